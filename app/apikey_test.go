@@ -0,0 +1,53 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+// apiKey creates an API key with scopes as the caller authenticated by header and returns the key.
+func (tc *testClient) apiKey(header http.Header, scopes ...string) string {
+	tc.t.Helper()
+	var created struct {
+		Key string `json:"key"`
+	}
+	tc.expect(http.StatusOK, http.MethodPost, "/api-keys", header, map[string]any{"name": "terminal", "scopes": scopes}, &created)
+	return created.Key
+}
+
+func TestApiKeysCannotGrantMoreThanTheirCreator(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	key := apiKeyHeader(tc.apiKey(bearer(admin.Token), "api_keys:manage"))
+
+	tc.expect(http.StatusForbidden, http.MethodGet, "/users", key, nil, nil)
+	tc.expect(http.StatusForbidden, http.MethodPost, "/api-keys", key, map[string]any{"name": "escalated", "scopes": []string{"users:manage"}}, nil)
+	tc.expect(http.StatusForbidden, http.MethodPost, "/api-keys", key, map[string]any{"name": "escalated", "scopes": []string{"api_keys:manage", "maintain"}}, nil)
+	tc.expect(http.StatusOK, http.MethodPost, "/api-keys", key, map[string]any{"name": "another", "scopes": []string{"api_keys:manage"}}, nil)
+
+	// A user is limited by the permissions of their role in the same way.
+	manager := tc.staff(admin, "manager@example.com", "MANAGER")
+	tc.expect(http.StatusForbidden, http.MethodPost, "/api-keys", bearer(manager.Token), map[string]any{"name": "mine", "scopes": []string{"menu:read"}}, nil)
+}
+
+func TestApiKeysStopWorkingWithTheirCreator(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	other := tc.staff(admin, "other@example.com", "ADMIN")
+	key := apiKeyHeader(tc.apiKey(bearer(other.Token), "api_keys:manage", "menu:read"))
+	// A key created by a key answers to the user at the start of the chain.
+	keyOfKey := apiKeyHeader(tc.apiKey(key, "menu:read"))
+	tc.expect(http.StatusOK, http.MethodGet, "/foods", key, nil, nil)
+	tc.expect(http.StatusOK, http.MethodGet, "/foods", keyOfKey, nil, nil)
+
+	tc.expect(http.StatusOK, http.MethodPost, "/users/"+other.User_id+"/deactivate", bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/foods", key, nil, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/foods", keyOfKey, nil, nil)
+
+	tc.expect(http.StatusOK, http.MethodPost, "/users/"+other.User_id+"/reactivate", bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusOK, http.MethodGet, "/foods", keyOfKey, nil, nil)
+
+	tc.expect(http.StatusOK, http.MethodDelete, "/users/"+other.User_id, bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/foods", key, nil, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/foods", keyOfKey, nil, nil)
+}
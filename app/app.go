@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restaurant-management-system/blobstore"
+	"restaurant-management-system/config"
+	controller "restaurant-management-system/controllers"
+	"restaurant-management-system/database"
+	"restaurant-management-system/events"
+	"restaurant-management-system/helpers"
+	"restaurant-management-system/mailer"
+	middleware "restaurant-management-system/middleware"
+	"restaurant-management-system/migrations"
+	routes "restaurant-management-system/routes"
+	"restaurant-management-system/store"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// App owns everything the server builds at startup, in the order it is built: the Mongo client, the store, the
+// event bus, the controller and the router. Nothing is connected at import time any more; New does all of it explicitly and Close
+// undoes it.
+type App struct {
+	Config config.Config
+	Client *mongo.Client // nil when running on the in-memory store
+	Store  *store.Store
+	// Events is where subscribers register for the domain events the handlers publish.
+	Events *events.Bus
+	Router *gin.Engine
+
+	server *http.Server
+}
+
+func New(ctx context.Context, cfg config.Config) (*App, error) {
+	a := &App{Config: cfg, Events: events.NewBus()}
+	helpers.Configure(cfg)
+	var publisher events.Publisher = a.Events
+
+	var db *mongo.Database
+	// STORE=memory runs the whole API without a MongoDB server, which is handy for demos and tests. Everything is
+	// lost when the process exits.
+	if cfg.Store == "memory" {
+		a.Store = store.NewMemoryStore()
+	} else {
+		client, err := database.DBInstance(ctx, cfg.MongoURI)
+		if err != nil {
+			return nil, err
+		}
+		a.Client = client
+		db = database.OpenDatabase(client, cfg.DatabaseName)
+		if cfg.MigrateOnStartup {
+			if err := migrations.Run(ctx, db); err != nil {
+				a.Close(context.Background())
+				return nil, err
+			}
+		}
+		a.Store = store.NewMongoStore(db)
+
+		if cfg.Events == "changestream" {
+			collection := db.Collection(store.EventCollection)
+			if err := events.Watch(ctx, collection, a.Events); err != nil {
+				a.Close(context.Background())
+				return nil, err
+			}
+			publisher = events.NewMongoPublisher(collection)
+		}
+	}
+
+	if err := helpers.StartSigningKeys(ctx, a.Store.SigningKeys); err != nil {
+		a.Close(context.Background())
+		return nil, err
+	}
+
+	m, err := mailer.New(cfg)
+	if err != nil {
+		a.Close(context.Background())
+		return nil, err
+	}
+	blobs, err := blobstore.New(cfg, db)
+	if err != nil {
+		a.Close(context.Background())
+		return nil, err
+	}
+	a.Router = newRouter(controller.NewController(a.Store, cfg, publisher, m, blobs))
+	// Validate has checked every entry, so this cannot fail on a loaded configuration.
+	if err := a.Router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		a.Close(context.Background())
+		return nil, err
+	}
+	a.server = &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: a.Router,
+	}
+	return a, nil
+}
+
+func newRouter(ctl *controller.Controller) *gin.Engine {
+	// t creates a new instance of the Gin engine (router) without any default middleware.
+	router := gin.New()
+	// gin.Logger() is a built-in middleware provided by the Gin framework. It logs details about each HTTP request the router receives and the corresponding response. This helps in debugging and monitoring the behavior of your application
+	// gin.Logger() logs important information like HTTP methods, paths, response status codes, client IP addresses, and request processing time.
+	router.Use(gin.Logger())
+	// The probes stay outside authentication so the orchestrator can call them, and so do the public keys.
+	routes.HealthRoutes(router, ctl)
+	routes.WellKnownRoutes(router, ctl)
+	routes.UserRoutes(router, ctl)
+	// used to attach custom authentication middleware to your Gin router. Middleware in Gin acts like a filter that processes every request before it reaches your route handlers. This particular middleware is for authentication, ensuring that only users who are authenticated (logged in or have valid credentials) can access certain routes.
+	router.Use(middleware.Authentication(ctl.Store))
+
+	routes.FoodRoutes(router, ctl)
+	routes.MenuRoutes(router, ctl)
+	routes.TableRoutes(router, ctl)
+	routes.OrderRoutes(router, ctl)
+	routes.OrderItemRoutes(router, ctl)
+	routes.InvoiceRoutes(router, ctl)
+	routes.AdminRoutes(router, ctl)
+	routes.AuditRoutes(router, ctl)
+	routes.ApiKeyRoutes(router, ctl)
+	routes.LoginAttemptRoutes(router, ctl)
+	return router
+}
+
+// Run serves HTTP until ctx is cancelled (main cancels it on SIGINT or SIGTERM). It then stops accepting
+// connections, waits up to ShutdownTimeout for in-flight requests to finish and finally disconnects from MongoDB, so
+// an order that was being written when the deploy started still reaches the database.
+func (a *App) Run(ctx context.Context) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", a.server.Addr)
+		serverErr <- a.server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		a.Close(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.ShutdownTimeout.Duration)
+	defer cancel()
+
+	shutdownErr := a.server.Shutdown(shutdownCtx)
+	if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		shutdownErr = errors.Join(shutdownErr, err)
+	}
+	if err := a.Close(shutdownCtx); err != nil {
+		shutdownErr = errors.Join(shutdownErr, err)
+	}
+	return shutdownErr
+}
+
+// Close releases the Mongo client. It is safe to call on an App built on the in-memory store.
+func (a *App) Close(ctx context.Context) error {
+	if a.Client == nil {
+		return nil
+	}
+	if err := a.Client.Disconnect(ctx); err != nil {
+		return fmt.Errorf("disconnecting from mongodb: %w", err)
+	}
+	a.Client = nil
+	return nil
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestAuthenticationIsRequired(t *testing.T) {
+	tc := newTestClient(t)
+	tc.admin()
+
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/foods", nil, nil, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/foods", bearer("not-a-token"), nil, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/foods", apiKeyHeader("not-a-key"), nil, nil)
+}
+
+func TestRolesAuthorizeRequests(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	if status := tc.signUp("customer@example.com", "CUSTOMER", nil); status != http.StatusOK {
+		t.Fatalf("signing up a customer answered %d", status)
+	}
+	customer := tc.login("customer@example.com", testPassword)
+	waiter := tc.staff(admin, "waiter@example.com", "WAITER")
+	menuId := tc.menu(admin.Token)
+
+	tc.expect(http.StatusOK, http.MethodGet, "/foods", bearer(customer.Token), nil, nil)
+	tc.expect(http.StatusForbidden, http.MethodPost, "/menus", bearer(customer.Token), map[string]any{"name": "Dinner"}, nil)
+	tc.expect(http.StatusForbidden, http.MethodGet, "/users", bearer(customer.Token), nil, nil)
+	tc.expect(http.StatusForbidden, http.MethodGet, "/tables", bearer(customer.Token), nil, nil)
+
+	tc.expect(http.StatusOK, http.MethodGet, "/tables", bearer(waiter.Token), nil, nil)
+	tc.expect(http.StatusForbidden, http.MethodPost, "/foods", bearer(waiter.Token), map[string]any{"name": "Soup", "price": 4, "menu_id": menuId}, nil)
+	tc.expect(http.StatusForbidden, http.MethodPost, "/users", bearer(waiter.Token), tc.newUser("chef@example.com", "CHEF"), nil)
+
+	tc.expect(http.StatusOK, http.MethodGet, "/users", bearer(admin.Token), nil, nil)
+}
+
+func TestSignUpCreatesOnlyTheFirstStaffAccount(t *testing.T) {
+	tc := newTestClient(t)
+	if status := tc.signUp("waiter@example.com", "WAITER", nil); status != http.StatusForbidden {
+		t.Fatalf("the first account as a waiter answered %d, want %d", status, http.StatusForbidden)
+	}
+	admin := tc.admin()
+
+	// Not even an admin token makes the public sign up create staff, that is what POST /users is for.
+	if status := tc.signUp("second@example.com", "ADMIN", bearer(admin.Token)); status != http.StatusForbidden {
+		t.Fatalf("signing up a second admin answered %d, want %d", status, http.StatusForbidden)
+	}
+	if status := tc.signUp("customer@example.com", "CUSTOMER", nil); status != http.StatusOK {
+		t.Fatalf("signing up a customer answered %d, want %d", status, http.StatusOK)
+	}
+	tc.staff(admin, "waiter@example.com", "WAITER")
+}
+
+func TestOnlyOneOfConcurrentSignUpsBecomesAdmin(t *testing.T) {
+	tc := newTestClient(t)
+	users := make([]map[string]any, 8)
+	for i := range users {
+		users[i] = tc.newUser(fmt.Sprintf("admin%d@example.com", i), "ADMIN")
+	}
+
+	statuses := make([]int, len(users))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, user := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			statuses[i] = tc.do(http.MethodPost, "/users/signup", nil, user, nil)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	admins := 0
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			admins++
+		} else if status != http.StatusForbidden {
+			t.Fatalf("a concurrent sign up answered %d", status)
+		}
+	}
+	if admins != 1 {
+		t.Fatalf("%d of %d concurrent sign ups became admin, want 1", admins, len(users))
+	}
+}
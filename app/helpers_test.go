@@ -0,0 +1,234 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"restaurant-management-system/config"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The tests drive the API the way a client does, through the router of an App built on the in-memory store, so
+// they cover the middleware and the routes as well as the handlers.
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	os.Exit(m.Run())
+}
+
+// testClient sends requests to the router of a fresh App.
+type testClient struct {
+	t      *testing.T
+	router http.Handler
+	phones int
+	tables int
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+	return newTestClientWith(t, func(cfg *config.Config) {})
+}
+
+// newTestClientWith is newTestClient with the test configuration changed by configure first.
+func newTestClientWith(t *testing.T, configure func(cfg *config.Config)) *testClient {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Store = "memory"
+	cfg.SecretKey = "test-secret"
+	cfg.BcryptCost = bcrypt.MinCost
+	cfg.Mailer = "memory"
+	cfg.BlobStore = "memory"
+	configure(&cfg)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid test configuration: %v", err)
+	}
+	a, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("building the app: %v", err)
+	}
+	t.Cleanup(func() { a.Close(context.Background()) })
+	return &testClient{t: t, router: a.Router}
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
+
+func apiKeyHeader(key string) http.Header {
+	return http.Header{"X-Api-Key": {key}}
+}
+
+// do sends body as JSON with header and decodes the JSON answer into out, unless out is nil. It returns the status.
+func (tc *testClient) do(method string, path string, header http.Header, body any, out any) int {
+	tc.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			tc.t.Fatalf("encoding the body of %s %s: %v", method, path, err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	recorder := httptest.NewRecorder()
+	tc.router.ServeHTTP(recorder, request)
+	if out != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+			tc.t.Fatalf("%s %s answered %d with %q: %v", method, path, recorder.Code, recorder.Body.String(), err)
+		}
+	}
+	return recorder.Code
+}
+
+// expect is do for a request that has to answer status.
+func (tc *testClient) expect(status int, method string, path string, header http.Header, body any, out any) {
+	tc.t.Helper()
+	var raw json.RawMessage
+	code := tc.do(method, path, header, body, &raw)
+	if code != status {
+		tc.t.Fatalf("%s %s answered %d, want %d: %s", method, path, code, status, raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			tc.t.Fatalf("decoding the answer of %s %s: %v", method, path, err)
+		}
+	}
+}
+
+const testPassword = "secret1"
+
+// signUp asks the public sign up for an account with role and returns the status it answered.
+func (tc *testClient) signUp(email string, role string, header http.Header) int {
+	tc.t.Helper()
+	return tc.do(http.MethodPost, "/users/signup", header, tc.newUser(email, role), nil)
+}
+
+func (tc *testClient) newUser(email string, role string) map[string]any {
+	tc.phones++
+	return map[string]any{
+		"first_name": "Test",
+		"last_name":  "User",
+		"Password":   testPassword,
+		"email":      email,
+		"phone":      fmt.Sprintf("555-%04d", tc.phones),
+		"user_type":  role,
+	}
+}
+
+type loginResponse struct {
+	User_id       string `json:"user_id"`
+	Token         string `json:"token"`
+	Refresh_token string `json:"refresh_token"`
+}
+
+func (tc *testClient) login(email string, password string) loginResponse {
+	tc.t.Helper()
+	var login loginResponse
+	tc.expect(http.StatusOK, http.MethodPost, "/users/login", nil, map[string]string{"email": email, "Password": password}, &login)
+	return login
+}
+
+// admin signs up the first account, which is an admin, and logs it in.
+func (tc *testClient) admin() loginResponse {
+	tc.t.Helper()
+	if status := tc.signUp("admin@example.com", "ADMIN", nil); status != http.StatusOK {
+		tc.t.Fatalf("signing up the first admin answered %d", status)
+	}
+	return tc.login("admin@example.com", testPassword)
+}
+
+// staff creates an account with role as the admin and logs it in.
+func (tc *testClient) staff(admin loginResponse, email string, role string) loginResponse {
+	tc.t.Helper()
+	tc.expect(http.StatusCreated, http.MethodPost, "/users", bearer(admin.Token), tc.newUser(email, role), nil)
+	return tc.login(email, testPassword)
+}
+
+// pageResponse is the envelope of every listing.
+type pageResponse[T any] struct {
+	Items       []T     `json:"items"`
+	Next_cursor *string `json:"next_cursor"`
+	Total_count int     `json:"total_count"`
+}
+
+// menu creates a menu that is current for a month and returns its menu_id.
+func (tc *testClient) menu(token string) string {
+	tc.t.Helper()
+	var menu struct {
+		Menu_id string `json:"menu_id"`
+	}
+	tc.expect(http.StatusOK, http.MethodPost, "/menus", bearer(token), map[string]any{
+		"name":       "Lunch",
+		"category":   "main",
+		"start_date": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		"end_date":   time.Now().AddDate(0, 1, 0).UTC().Format(time.RFC3339),
+	}, &menu)
+	return menu.Menu_id
+}
+
+// table creates a table with number and returns its table_id.
+func (tc *testClient) table(token string, number int) string {
+	tc.t.Helper()
+	var table struct {
+		Table_id string `json:"table_id"`
+	}
+	tc.expect(http.StatusOK, http.MethodPost, "/tables", bearer(token), map[string]any{"table_number": number, "number_of_guests": 2}, &table)
+	tc.tables = max(tc.tables, number)
+	return table.Table_id
+}
+
+// order opens an order at a new table and returns its order_id.
+func (tc *testClient) order(token string) string {
+	tc.t.Helper()
+	var order struct {
+		Order_id string `json:"order_id"`
+	}
+	tc.expect(http.StatusOK, http.MethodPost, "/orders", bearer(token), map[string]any{
+		"table_id":   tc.table(token, tc.tables+1),
+		"order_date": time.Now().UTC().Format(time.RFC3339),
+	}, &order)
+	return order.Order_id
+}
+
+type orderItemResponse struct {
+	Order_item_id string   `json:"order_item_id"`
+	Order_id      string   `json:"order_id"`
+	Quantity      string   `json:"quantity"`
+	Unit_price    *float64 `json:"unit_price"`
+	Modifiers     []struct {
+		Group string   `json:"group"`
+		Name  string   `json:"name"`
+		Price *float64 `json:"price"`
+	} `json:"modifiers"`
+}
+
+// food creates a food from fields on a new menu and returns its food_id.
+func (tc *testClient) food(token string, fields map[string]any) string {
+	tc.t.Helper()
+	fields["menu_id"] = tc.menu(token)
+	var food struct {
+		Food_id string `json:"food_id"`
+	}
+	tc.expect(http.StatusOK, http.MethodPost, "/foods", bearer(token), fields, &food)
+	return food.Food_id
+}
+
+// orderItems is the body that orders items at the table tableId.
+func orderItems(tableId string, items ...map[string]any) map[string]any {
+	return map[string]any{"Table_id": tableId, "Order_items": items}
+}
@@ -0,0 +1,14 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInvoicesNeedAnExistingOrder(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+
+	tc.expect(http.StatusNotFound, http.MethodPost, "/invoices", bearer(admin.Token), map[string]any{"order_id": "missing", "payment_method": "CARD", "payment_status": "PENDING"}, nil)
+	tc.expect(http.StatusOK, http.MethodPost, "/invoices", bearer(admin.Token), map[string]any{"order_id": tc.order(admin.Token), "payment_method": "CARD", "payment_status": "PENDING"}, nil)
+}
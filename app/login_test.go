@@ -0,0 +1,68 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"restaurant-management-system/config"
+)
+
+func TestLoginDoesNotTellWhichEmailsExist(t *testing.T) {
+	tc := newTestClient(t)
+	tc.admin()
+
+	var wrongPassword, unknownEmail map[string]string
+	tc.expect(http.StatusUnauthorized, http.MethodPost, "/users/login", nil, map[string]string{"email": "admin@example.com", "Password": "wrong-password"}, &wrongPassword)
+	tc.expect(http.StatusUnauthorized, http.MethodPost, "/users/login", nil, map[string]string{"email": "nobody@example.com", "Password": "wrong-password"}, &unknownEmail)
+	if wrongPassword["error"] != unknownEmail["error"] {
+		t.Fatalf("a wrong password answers %q but an unknown email %q", wrongPassword["error"], unknownEmail["error"])
+	}
+}
+
+func TestFailedLoginsLockTheAccount(t *testing.T) {
+	tc := newTestClient(t)
+	tc.admin()
+	wrong := map[string]string{"email": "admin@example.com", "Password": "wrong-password"}
+
+	for range config.Default().LoginAccountAttempts + 1 {
+		tc.expect(http.StatusUnauthorized, http.MethodPost, "/users/login", nil, wrong, nil)
+	}
+	// Even the right password waits for the lock to end.
+	tc.expect(http.StatusTooManyRequests, http.MethodPost, "/users/login", nil, map[string]string{"email": "admin@example.com", "Password": testPassword}, nil)
+}
+
+// guessFromEverywhere fails a login for a new email each time, claiming in X-Forwarded-For to come from a new
+// address each time, until the IP lockout answers. It returns the number of failures that took, or 0 when the
+// lockout never answered.
+func guessFromEverywhere(tc *testClient, tries int) int {
+	tc.t.Helper()
+	for try := 1; try <= tries; try++ {
+		header := http.Header{"X-Forwarded-For": {fmt.Sprintf("203.0.113.%d", try)}}
+		body := map[string]string{"email": fmt.Sprintf("guess%d@example.com", try), "Password": "wrong-password"}
+		switch status := tc.do(http.MethodPost, "/users/login", header, body, nil); status {
+		case http.StatusTooManyRequests:
+			return try - 1
+		case http.StatusUnauthorized:
+		default:
+			tc.t.Fatalf("a wrong guess answered %d", status)
+		}
+	}
+	return 0
+}
+
+func TestForwardedForDoesNotDodgeTheIPLockout(t *testing.T) {
+	tc := newTestClientWith(t, func(cfg *config.Config) { cfg.LoginIPAttempts = 3 })
+	if failures := guessFromEverywhere(tc, 10); failures != 4 {
+		t.Fatalf("the client was locked out after %d failures, want 4 whatever X-Forwarded-For claims", failures)
+	}
+
+	// Behind a trusted proxy the header names the client, and every address is counted on its own.
+	tc = newTestClientWith(t, func(cfg *config.Config) {
+		cfg.LoginIPAttempts = 3
+		cfg.TrustedProxies = []string{"192.0.2.0/24"}
+	})
+	if failures := guessFromEverywhere(tc, 10); failures != 0 {
+		t.Fatalf("clients behind a trusted proxy were locked out together after %d failures", failures)
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLogoutRevokesTheToken(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	other := tc.login("admin@example.com", testPassword)
+
+	tc.expect(http.StatusOK, http.MethodPost, "/users/logout", bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/users/me", bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusOK, http.MethodGet, "/users/me", bearer(other.Token), nil, nil)
+}
+
+func TestLoginRightAfterPasswordChange(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+
+	tc.expect(http.StatusOK, http.MethodPost, "/users/me/password", bearer(admin.Token), map[string]string{
+		"current_password": testPassword,
+		"new_password":     "secret2",
+	}, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/users/me", bearer(admin.Token), nil, nil)
+
+	// The login usually falls into the same second as the revocation of the old sessions.
+	login := tc.login("admin@example.com", "secret2")
+	tc.expect(http.StatusOK, http.MethodGet, "/users/me", bearer(login.Token), nil, nil)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestModifiersAddToTheOrderAndInvoice(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	tableId := tc.table(admin.Token, 1)
+	burger := tc.food(admin.Token, map[string]any{"name": "Burger", "price": 8, "modifier_groups": []map[string]any{
+		{"name": "side", "min": 1, "max": 1, "modifiers": []map[string]any{
+			{"name": "fries", "price": 0},
+			{"name": "salad", "price": 1.25},
+		}},
+		{"name": "extras", "min": 0, "max": 5, "modifiers": []map[string]any{
+			{"name": "extra cheese", "price": 1.5},
+			{"name": "no onions", "price": 0},
+		}},
+	}})
+
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/orderItems", bearer(admin.Token), orderItems(tableId, map[string]any{"food_id": burger, "quantity": "M"}), nil)
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/orderItems", bearer(admin.Token), orderItems(tableId, map[string]any{"food_id": burger, "quantity": "M", "modifiers": []map[string]any{
+		{"group": "side", "name": "fries"},
+		{"group": "side", "name": "salad"},
+	}}), nil)
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/orderItems", bearer(admin.Token), orderItems(tableId, map[string]any{"food_id": burger, "quantity": "M", "modifiers": []map[string]any{
+		{"group": "side", "name": "fries"},
+		{"group": "extras", "name": "bacon"},
+	}}), nil)
+
+	var items []orderItemResponse
+	tc.expect(http.StatusOK, http.MethodPost, "/orderItems", bearer(admin.Token), orderItems(tableId,
+		map[string]any{"food_id": burger, "quantity": "M", "modifiers": []map[string]any{
+			{"group": "side", "name": "salad", "price": -100},
+			{"group": "extras", "name": "extra cheese"},
+			{"group": "extras", "name": "no onions"},
+		}},
+		map[string]any{"food_id": burger, "quantity": "S", "modifiers": []map[string]any{{"group": "Side", "name": "Fries"}}},
+	), &items)
+	if len(items[0].Modifiers) != 3 || *items[0].Modifiers[0].Price != 1.25 {
+		t.Fatalf("the modifiers of the first burger are %+v, want salad at 1.25 and two extras", items[0].Modifiers)
+	}
+	orderId := items[0].Order_id
+	tc.expect(http.StatusBadRequest, http.MethodPatch, "/orderItems/"+items[1].Order_item_id, bearer(admin.Token), map[string]any{"modifiers": []map[string]any{{"group": "side", "name": "rice"}}}, nil)
+
+	var summaries []struct {
+		Order_items []struct {
+			Amount float64 `json:"amount"`
+		} `json:"order_items"`
+		Payment_due float64 `json:"payment_due"`
+	}
+	tc.expect(http.StatusOK, http.MethodGet, "/orderItems-order/"+orderId, bearer(admin.Token), nil, &summaries)
+	if len(summaries) != 1 || len(summaries[0].Order_items) != 2 {
+		t.Fatalf("the summary of the order is %+v, want one summary of 2 items", summaries)
+	}
+	if summaries[0].Order_items[0].Amount != 10.75 || summaries[0].Payment_due != 18.75 {
+		t.Fatalf("the order comes to %v with a first item of %v, want 18.75 and 10.75", summaries[0].Payment_due, summaries[0].Order_items[0].Amount)
+	}
+
+	var invoice struct {
+		Invoice_id  string  `json:"invoice_id"`
+		Payment_due float64 `json:"payment_due"`
+	}
+	tc.expect(http.StatusOK, http.MethodPost, "/invoices", bearer(admin.Token), map[string]any{"order_id": orderId, "payment_method": "CARD", "payment_status": "PENDING"}, &invoice)
+	tc.expect(http.StatusOK, http.MethodGet, "/invoices/"+invoice.Invoice_id, bearer(admin.Token), nil, &invoice)
+	if invoice.Payment_due != 18.75 {
+		t.Fatalf("the invoice is due %v, want 18.75", invoice.Payment_due)
+	}
+}
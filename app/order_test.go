@@ -0,0 +1,30 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRestoringAnOrderLeavesItemsDeletedBeforeIt(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	burger := tc.food(admin.Token, map[string]any{"name": "Burger", "price": 8})
+
+	var items []orderItemResponse
+	tc.expect(http.StatusOK, http.MethodPost, "/orderItems", bearer(admin.Token), orderItems(tc.table(admin.Token, 1),
+		map[string]any{"food_id": burger, "quantity": "S"},
+		map[string]any{"food_id": burger, "quantity": "M"},
+	), &items)
+	orderId := items[0].Order_id
+
+	tc.expect(http.StatusOK, http.MethodDelete, "/orderItems/"+items[0].Order_item_id, bearer(admin.Token), nil, nil)
+	// Deletions are stamped to the millisecond, the two below must not share one.
+	time.Sleep(2 * time.Millisecond)
+	tc.expect(http.StatusOK, http.MethodDelete, "/orders/"+orderId, bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusNotFound, http.MethodGet, "/orderItems/"+items[1].Order_item_id, bearer(admin.Token), nil, nil)
+
+	tc.expect(http.StatusOK, http.MethodPost, "/orders/"+orderId+"/restore", bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusOK, http.MethodGet, "/orderItems/"+items[1].Order_item_id, bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusNotFound, http.MethodGet, "/orderItems/"+items[0].Order_item_id, bearer(admin.Token), nil, nil)
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestListingsPageWithCursors(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	created := map[string]bool{}
+	for number := 1; number <= 7; number++ {
+		created[tc.table(admin.Token, number)] = true
+	}
+
+	seen := map[string]bool{}
+	path := "/tables?limit=3"
+	for pages := 1; ; pages++ {
+		var page pageResponse[struct {
+			Table_id string `json:"table_id"`
+		}]
+		tc.expect(http.StatusOK, http.MethodGet, path, bearer(admin.Token), nil, &page)
+		if page.Total_count != len(created) {
+			t.Fatalf("page %d counts %d tables, want %d", pages, page.Total_count, len(created))
+		}
+		for _, table := range page.Items {
+			if seen[table.Table_id] || !created[table.Table_id] {
+				t.Fatalf("page %d lists table %s twice or one that was not created", pages, table.Table_id)
+			}
+			seen[table.Table_id] = true
+		}
+		if page.Next_cursor == nil {
+			if pages != 3 {
+				t.Fatalf("7 tables came in %d pages of 3", pages)
+			}
+			break
+		}
+		if len(page.Items) != 3 {
+			t.Fatalf("page %d has %d tables and a next cursor", pages, len(page.Items))
+		}
+		path = "/tables?limit=3&cursor=" + url.QueryEscape(*page.Next_cursor)
+	}
+	if len(seen) != len(created) {
+		t.Fatalf("the pages listed %d of %d tables", len(seen), len(created))
+	}
+}
+
+func TestListingsRefuseBadPages(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	tc.table(admin.Token, 1)
+	tc.table(admin.Token, 2)
+
+	tc.expect(http.StatusBadRequest, http.MethodGet, "/tables?limit=0", bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusBadRequest, http.MethodGet, "/tables?limit=101", bearer(admin.Token), nil, nil)
+	tc.expect(http.StatusBadRequest, http.MethodGet, "/tables?cursor=garbage", bearer(admin.Token), nil, nil)
+
+	// A cursor belongs to the order of its listing, the audit log is newest first.
+	var page pageResponse[map[string]any]
+	tc.expect(http.StatusOK, http.MethodGet, "/tables?limit=1", bearer(admin.Token), nil, &page)
+	if page.Next_cursor == nil {
+		t.Fatalf("the first of two tables has no next cursor")
+	}
+	tc.expect(http.StatusBadRequest, http.MethodGet, "/audit?cursor="+url.QueryEscape(*page.Next_cursor), bearer(admin.Token), nil, nil)
+}
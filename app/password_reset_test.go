@@ -0,0 +1,15 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUnknownTokensAreRefused(t *testing.T) {
+	tc := newTestClient(t)
+	tc.admin()
+
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/users/reset-password", nil, map[string]string{"token": "not-a-token", "password": "secret2"}, nil)
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/users/verify-email", nil, map[string]string{"token": "not-a-token"}, nil)
+	tc.login("admin@example.com", testPassword)
+}
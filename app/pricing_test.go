@@ -0,0 +1,70 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOrderItemsArePricedByTheServer(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	tableId := tc.table(admin.Token, 1)
+	burger := tc.food(admin.Token, map[string]any{"name": "Burger", "price": 9.999})
+	wine := tc.food(admin.Token, map[string]any{"name": "Wine", "variants": []map[string]any{
+		{"name": "glass", "price": 5.5},
+		{"name": "bottle", "price": 24},
+	}})
+
+	var items []orderItemResponse
+	tc.expect(http.StatusOK, http.MethodPost, "/orderItems", bearer(admin.Token), orderItems(tableId,
+		map[string]any{"food_id": burger, "quantity": "L", "unit_price": 0.01},
+		map[string]any{"food_id": wine, "quantity": "Bottle"},
+	), &items)
+	if len(items) != 2 {
+		t.Fatalf("ordering 2 items created %d", len(items))
+	}
+	if *items[0].Unit_price != 10 {
+		t.Fatalf("the burger was priced %v, want the food price 10 whatever the client sent", *items[0].Unit_price)
+	}
+	if items[1].Quantity != "bottle" || *items[1].Unit_price != 24 {
+		t.Fatalf("the wine was ordered as %s at %v, want bottle at 24", items[1].Quantity, *items[1].Unit_price)
+	}
+
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/orderItems", bearer(admin.Token), orderItems(tableId, map[string]any{"food_id": wine, "quantity": "M"}), nil)
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/orderItems", bearer(admin.Token), orderItems(tableId, map[string]any{"food_id": burger, "quantity": "XL"}), nil)
+	tc.expect(http.StatusBadRequest, http.MethodPatch, "/orderItems/"+items[1].Order_item_id, bearer(admin.Token), map[string]any{"quantity": "L"}, nil)
+	tc.expect(http.StatusBadRequest, http.MethodPatch, "/orderItems/"+items[1].Order_item_id, bearer(admin.Token), map[string]any{"food_id": "missing"}, nil)
+
+	var updated orderItemResponse
+	tc.expect(http.StatusOK, http.MethodPatch, "/orderItems/"+items[1].Order_item_id, bearer(admin.Token), map[string]any{"quantity": "glass", "unit_price": 0}, &updated)
+	if *updated.Unit_price != 5.5 {
+		t.Fatalf("changing the wine to a glass priced it %v, want 5.5", *updated.Unit_price)
+	}
+}
+
+func TestFoodPricesAreChecked(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	menuId := tc.menu(admin.Token)
+
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/foods", bearer(admin.Token), map[string]any{"name": "Free", "price": -1, "menu_id": menuId}, nil)
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/foods", bearer(admin.Token), map[string]any{"name": "Unpriced", "menu_id": menuId}, nil)
+	tc.expect(http.StatusBadRequest, http.MethodPost, "/foods", bearer(admin.Token), map[string]any{"name": "Twice", "menu_id": menuId, "variants": []map[string]any{
+		{"name": "glass", "price": 5},
+		{"name": "Glass", "price": 6},
+	}}, nil)
+
+	var wine struct {
+		Food_id string  `json:"food_id"`
+		Price   float64 `json:"price"`
+	}
+	tc.expect(http.StatusOK, http.MethodPost, "/foods", bearer(admin.Token), map[string]any{"name": "Wine", "menu_id": menuId, "variants": []map[string]any{
+		{"name": "glass", "price": 5.5},
+		{"name": "bottle", "price": 24},
+	}}, &wine)
+	if wine.Price != 5.5 {
+		t.Fatalf("a food with variants is priced %v, want its lowest variant price 5.5", wine.Price)
+	}
+	tc.expect(http.StatusBadRequest, http.MethodPatch, "/foods/"+wine.Food_id, bearer(admin.Token), map[string]any{"price": 3}, nil)
+	tc.expect(http.StatusBadRequest, http.MethodPatch, "/foods/"+wine.Food_id, bearer(admin.Token), map[string]any{"variants": []map[string]any{}, "price": -3}, nil)
+}
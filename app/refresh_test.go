@@ -0,0 +1,39 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLoginAndRefresh(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	tc.expect(http.StatusOK, http.MethodGet, "/users/me", bearer(admin.Token), nil, nil)
+
+	var refreshed loginResponse
+	tc.expect(http.StatusOK, http.MethodPost, "/users/refresh", nil, map[string]string{"refresh_token": admin.Refresh_token}, &refreshed)
+	if refreshed.Token == "" || refreshed.Refresh_token == admin.Refresh_token {
+		t.Fatalf("refresh did not rotate the refresh token")
+	}
+	tc.expect(http.StatusOK, http.MethodGet, "/users/me", bearer(refreshed.Token), nil, nil)
+
+	// A refresh token authenticates nothing but a refresh.
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/users/me", bearer(refreshed.Refresh_token), nil, nil)
+}
+
+func TestReusedRefreshTokenRevokesSessions(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+
+	var refreshed loginResponse
+	tc.expect(http.StatusOK, http.MethodPost, "/users/refresh", nil, map[string]string{"refresh_token": admin.Refresh_token}, &refreshed)
+	tc.expect(http.StatusUnauthorized, http.MethodPost, "/users/refresh", nil, map[string]string{"refresh_token": admin.Refresh_token}, nil)
+
+	// Whoever reused the copy may hold access tokens issued from it, so none of them is accepted any more, and
+	// neither is the refresh token of the owner.
+	tc.expect(http.StatusUnauthorized, http.MethodGet, "/users/me", bearer(refreshed.Token), nil, nil)
+	tc.expect(http.StatusUnauthorized, http.MethodPost, "/users/refresh", nil, map[string]string{"refresh_token": refreshed.Refresh_token}, nil)
+
+	// A new login works again.
+	tc.expect(http.StatusOK, http.MethodGet, "/users/me", bearer(tc.login("admin@example.com", testPassword).Token), nil, nil)
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTheLastAdminStays(t *testing.T) {
+	tc := newTestClient(t)
+	admin := tc.admin()
+	tc.expect(http.StatusForbidden, http.MethodDelete, "/users/"+admin.User_id, bearer(admin.Token), nil, nil)
+
+	// A key may manage users, but not take away the only admin there is.
+	key := apiKeyHeader(tc.apiKey(bearer(admin.Token), "users:manage"))
+	tc.expect(http.StatusConflict, http.MethodDelete, "/users/"+admin.User_id, key, nil, nil)
+	tc.expect(http.StatusConflict, http.MethodPost, "/users/"+admin.User_id+"/deactivate", key, nil, nil)
+	tc.expect(http.StatusConflict, http.MethodPatch, "/users/"+admin.User_id+"/role", key, map[string]string{"user_type": "MANAGER"}, nil)
+
+	// With another admin around, either can go.
+	other := tc.staff(admin, "other@example.com", "ADMIN")
+	tc.expect(http.StatusOK, http.MethodDelete, "/users/"+admin.User_id, bearer(other.Token), nil, nil)
+	tc.expect(http.StatusForbidden, http.MethodDelete, "/users/"+other.User_id, bearer(other.Token), nil, nil)
+}
@@ -0,0 +1,40 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"restaurant-management-system/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNotFound is returned by Get for a name that holds no blob.
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps the files the API serves, like uploaded food images, under slash separated names the server picks.
+// Writing a name again replaces its blob.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	// Delete removes the blob of name. A name that holds nothing is not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// New builds the blob store cfg.BlobStore names: "gridfs" keeps blobs in db, "local" in files under cfg.ImageDir
+// and "memory" in process, which is only useful to tests and demos. db is nil when the server runs on the
+// in-memory store.
+func New(cfg config.Config, db *mongo.Database) (Store, error) {
+	switch cfg.BlobStore {
+	case "gridfs":
+		if db == nil {
+			return nil, errors.New("the gridfs blob store needs a MongoDB database")
+		}
+		return NewGridFSStore(db), nil
+	case "local":
+		return NewLocalStore(cfg.ImageDir), nil
+	case "memory":
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown blob store %q", cfg.BlobStore)
+}
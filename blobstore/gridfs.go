@@ -0,0 +1,98 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BucketName is the GridFS bucket the blobs are kept in, in the collections blobs.files and blobs.chunks.
+const BucketName = "blobs"
+
+// GridFSStore keeps blobs in GridFS, so they live and are backed up with the rest of the database and every server
+// process sees the same ones.
+type GridFSStore struct {
+	db *mongo.Database
+}
+
+func NewGridFSStore(db *mongo.Database) *GridFSStore {
+	return &GridFSStore{db: db}
+}
+
+// bucket opens the bucket for one call. The upload and download streams of a bucket take a deadline instead of a
+// context, so a bucket is not shared between calls with different deadlines.
+func (s *GridFSStore) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(s.db, options.GridFSBucket().SetName(BucketName))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := bucket.SetWriteDeadline(deadline); err != nil {
+			return nil, err
+		}
+		if err := bucket.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	return bucket, nil
+}
+
+// Put uploads a new revision of name and then removes the older ones.
+func (s *GridFSStore) Put(ctx context.Context, name string, data []byte) error {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := bucket.UploadFromStream(name, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return s.delete(ctx, bucket, bson.M{"filename": name, "_id": bson.M{"$ne": id}})
+}
+
+// Get downloads the latest revision of name.
+func (s *GridFSStore) Get(ctx context.Context, name string) ([]byte, error) {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var data bytes.Buffer
+	if _, err := bucket.DownloadToStreamByName(name, &data); errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+func (s *GridFSStore) Delete(ctx context.Context, name string) error {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	return s.delete(ctx, bucket, bson.M{"filename": name})
+}
+
+func (s *GridFSStore) delete(ctx context.Context, bucket *gridfs.Bucket, filter bson.M) error {
+	cursor, err := bucket.FindContext(ctx, filter)
+	if err != nil {
+		return err
+	}
+	var files []struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &files); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := bucket.DeleteContext(ctx, file.ID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"restaurant-management-system/config"
+	"restaurant-management-system/database"
+	"restaurant-management-system/integrity"
+	"restaurant-management-system/migrations"
+	"restaurant-management-system/store"
+	"strings"
+	"text/tabwriter"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The subcommands take the same flags as the server, so they read the database settings from the same config file
+// and environment.
+
+// splitAction takes the optional action that follows a subcommand, like "status" in "migrate status".
+func splitAction(args []string, defaultAction string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return defaultAction, args
+}
+
+// connect loads the configuration from args and connects to its Mongo database.
+func connect(ctx context.Context, args []string) (*mongo.Client, *mongo.Database) {
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Store != "mongo" {
+		log.Fatal("this command only applies to the mongo store")
+	}
+
+	client, err := database.DBInstance(ctx, cfg.MongoURI)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return client, database.OpenDatabase(client, cfg.DatabaseName)
+}
+
+// runMigrate implements "restaurant-management-system migrate [up|status] [flags]".
+func runMigrate(args []string) {
+	action, args := splitAction(args, "up")
+
+	ctx := context.Background()
+	client, db := connect(ctx, args)
+	defer client.Disconnect(ctx)
+
+	switch action {
+	case "up":
+		if err := migrations.Run(ctx, db); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("database is up to date")
+	case "status":
+		statuses, err := migrations.GetStatus(ctx, db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tAPPLIED AT\tDESCRIPTION")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied != nil {
+				appliedAt = status.Applied.Applied_at.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Migration.Version, appliedAt, status.Migration.Description)
+		}
+		w.Flush()
+	default:
+		log.Fatalf("unknown migrate action %q, expected up or status", action)
+	}
+}
+
+// runIntegrity implements "restaurant-management-system integrity [check|repair] [--apply] [flags]". repair is a
+// dry run unless --apply is given. The report is printed as JSON, and the documents repair deletes are marked as
+// deleted by "integrity".
+func runIntegrity(args []string) {
+	action, args := splitAction(args, "check")
+
+	apply := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "-apply" || arg == "--apply" {
+			apply = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	ctx := context.Background()
+	client, db := connect(ctx, rest)
+	defer client.Disconnect(ctx)
+	s := store.NewMongoStore(db)
+
+	var report integrity.Report
+	var err error
+	switch action {
+	case "check":
+		report, err = integrity.Check(ctx, s)
+	case "repair":
+		report, err = integrity.Repair(ctx, s, apply, "integrity")
+	default:
+		log.Fatalf("unknown integrity action %q, expected check or repair", action)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
@@ -0,0 +1,504 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"restaurant-management-system/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config holds every setting the server needs at startup. Values are layered: the defaults below, then the JSON
+// config file, then environment variables, then command line flags. A later layer only overrides the settings it
+// actually sets.
+type Config struct {
+	Port            string   `json:"port"`
+	Store           string   `json:"store"` // "mongo" or "memory"
+	MongoURI        string   `json:"mongo_uri"`
+	DatabaseName    string   `json:"database_name"`
+	SecretKey       string   `json:"secret_key"`
+	AccessTokenTTL  Duration `json:"access_token_ttl"`
+	RefreshTokenTTL Duration `json:"refresh_token_ttl"`
+	RequestTimeout  Duration `json:"request_timeout"`
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	BcryptCost      int      `json:"bcrypt_cost"`
+	// MigrateOnStartup applies pending schema migrations before the server starts listening. Turn it off when
+	// migrations are run separately with the migrate subcommand.
+	MigrateOnStartup bool `json:"migrate_on_startup"`
+	// Events selects how domain events reach their subscribers: "memory" delivers them inside the process that
+	// published them, "changestream" passes them through MongoDB so subscribers in every process receive them.
+	Events string `json:"events"`
+	// JWTAlgorithm signs tokens with HS256 and SecretKey, or with RS256 or EdDSA and key pairs the server generates
+	// and rotates every JWTKeyRotation. The public keys are served at /.well-known/jwks.json, so other services can
+	// verify tokens without the secret; SecretKey then only encrypts the stored private keys.
+	JWTAlgorithm   string   `json:"jwt_algorithm"`
+	JWTKeyRotation Duration `json:"jwt_key_rotation"`
+	// Mailer selects how the password reset and verification emails are sent: "smtp", "file" to write them to
+	// MailDir, or "memory" to keep them in the process.
+	Mailer       string `json:"mailer"`
+	MailFrom     string `json:"mail_from"`
+	MailDir      string `json:"mail_dir"`
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password"`
+	// BlobStore selects where uploaded food images and their thumbnails are kept: "gridfs" in the MongoDB database,
+	// "local" in files under ImageDir, or "memory" in the process. MaxImageBytes is the largest upload accepted.
+	BlobStore     string `json:"blob_store"`
+	ImageDir      string `json:"image_dir"`
+	MaxImageBytes int64  `json:"max_image_bytes"`
+	// PasswordResetTTL and EmailVerificationTTL are how long the token sent by email can be used.
+	PasswordResetTTL     Duration `json:"password_reset_ttl"`
+	EmailVerificationTTL Duration `json:"email_verification_ttl"`
+	// RequireVerifiedEmail refuses to log in users who have not verified their email address yet.
+	RequireVerifiedEmail bool `json:"require_verified_email"`
+	// LoginAccountAttempts and LoginIPAttempts are how many failed logins an account or a client IP gets before
+	// each further failure locks it for LoginBackoff, doubling every time up to LoginLockout. Failures are forgotten
+	// once the account or IP has not failed for LoginLockout. An IP gets more attempts than an account because
+	// many clients can share one address.
+	LoginAccountAttempts int      `json:"login_account_attempts"`
+	LoginIPAttempts      int      `json:"login_ip_attempts"`
+	LoginBackoff         Duration `json:"login_backoff"`
+	LoginLockout         Duration `json:"login_lockout"`
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies in front of the server. Only a request
+	// coming from one of them may name its client in X-Forwarded-For; the client IP of any other request is the
+	// address it came from. With none, which is the default, the header is always ignored, so a client cannot dodge
+	// the lockout of its IP by making one up.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// MfaRequiredRoles are the roles that cannot log in without a TOTP code. A user of such a role who has not
+	// enrolled yet is taken through enrollment at the next login. MfaIssuer is the name authenticator apps show.
+	MfaRequiredRoles []string `json:"mfa_required_roles"`
+	MfaIssuer        string   `json:"mfa_issuer"`
+}
+
+// Duration is a time.Duration that reads "24h" style strings from JSON.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"24h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Default returns the settings used when nothing else is configured. There is deliberately no default SecretKey.
+func Default() Config {
+	return Config{
+		Port:            "8000",
+		Store:           "mongo",
+		MongoURI:        "mongodb://localhost:27017",
+		DatabaseName:    "restaurant",
+		AccessTokenTTL:  Duration{24 * time.Hour},
+		RefreshTokenTTL: Duration{168 * time.Hour},
+		RequestTimeout:  Duration{100 * time.Second},
+		ShutdownTimeout: Duration{30 * time.Second},
+		BcryptCost:      14,
+
+		MigrateOnStartup: true,
+		Events:           "memory",
+
+		JWTAlgorithm:   "HS256",
+		JWTKeyRotation: Duration{30 * 24 * time.Hour},
+
+		Mailer:           "file",
+		MailFrom:         "no-reply@localhost",
+		MailDir:          "mail",
+		SMTPPort:         587,
+		PasswordResetTTL: Duration{time.Hour},
+
+		BlobStore:     "local",
+		ImageDir:      "images",
+		MaxImageBytes: 5 << 20,
+
+		EmailVerificationTTL: Duration{48 * time.Hour},
+
+		LoginAccountAttempts: 5,
+		LoginIPAttempts:      20,
+		LoginBackoff:         Duration{time.Second},
+		LoginLockout:         Duration{15 * time.Minute},
+		TrustedProxies:       []string{},
+
+		MfaRequiredRoles: []string{},
+		MfaIssuer:        "Restaurant",
+	}
+}
+
+// Load builds the configuration from the config file, the environment and args (usually os.Args[1:]) and validates
+// the result. The config file is taken from the -config flag or the CONFIG_FILE environment variable.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("restaurant-management-system", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a JSON config file")
+	port := fs.String("port", "", "HTTP port to listen on")
+	storeKind := fs.String("store", "", "storage backend: mongo or memory")
+	mongoURI := fs.String("mongo-uri", "", "MongoDB connection string")
+	databaseName := fs.String("database", "", "MongoDB database name")
+	secretKey := fs.String("secret-key", "", "JWT signing key (prefer the SECRET_KEY environment variable)")
+	jwtAlgorithm := fs.String("jwt-algorithm", "", "token signing algorithm: HS256, RS256 or EdDSA")
+	jwtKeyRotation := fs.Duration("jwt-key-rotation", 0, "how long an RS256 or EdDSA key signs before the next one takes over")
+	accessTokenTTL := fs.Duration("access-token-ttl", 0, "lifetime of access tokens")
+	refreshTokenTTL := fs.Duration("refresh-token-ttl", 0, "lifetime of refresh tokens")
+	requestTimeout := fs.Duration("request-timeout", 0, "timeout for the database work of a single request")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 0, "how long to wait for in-flight requests on shutdown")
+	bcryptCost := fs.Int("bcrypt-cost", 0, "bcrypt cost used to hash passwords")
+	migrateOnStartup := fs.Bool("migrate-on-startup", false, "apply pending schema migrations at startup")
+	eventsKind := fs.String("events", "", "domain event source: memory or changestream")
+	mailerKind := fs.String("mailer", "", "how emails are sent: smtp, file or memory")
+	mailDir := fs.String("mail-dir", "", "directory the file mailer writes emails to")
+	blobStore := fs.String("blob-store", "", "where food images are stored: gridfs, local or memory")
+	imageDir := fs.String("image-dir", "", "directory the local blob store keeps food images in")
+	trustedProxies := fs.String("trusted-proxies", "", "comma separated addresses or CIDR ranges of the reverse proxies allowed to send X-Forwarded-For")
+	mfaRequiredRoles := fs.String("mfa-required-roles", "", "comma separated roles that must use two-factor authentication")
+	requireVerifiedEmail := fs.Bool("require-verified-email", false, "refuse logins with an unverified email address")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return cfg, err
+	}
+
+	// Only the flags that were given on the command line override the earlier layers.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = *port
+		case "store":
+			cfg.Store = *storeKind
+		case "mongo-uri":
+			cfg.MongoURI = *mongoURI
+		case "database":
+			cfg.DatabaseName = *databaseName
+		case "secret-key":
+			cfg.SecretKey = *secretKey
+		case "jwt-algorithm":
+			cfg.JWTAlgorithm = *jwtAlgorithm
+		case "jwt-key-rotation":
+			cfg.JWTKeyRotation.Duration = *jwtKeyRotation
+		case "access-token-ttl":
+			cfg.AccessTokenTTL.Duration = *accessTokenTTL
+		case "refresh-token-ttl":
+			cfg.RefreshTokenTTL.Duration = *refreshTokenTTL
+		case "request-timeout":
+			cfg.RequestTimeout.Duration = *requestTimeout
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout.Duration = *shutdownTimeout
+		case "bcrypt-cost":
+			cfg.BcryptCost = *bcryptCost
+		case "migrate-on-startup":
+			cfg.MigrateOnStartup = *migrateOnStartup
+		case "events":
+			cfg.Events = *eventsKind
+		case "mailer":
+			cfg.Mailer = *mailerKind
+		case "mail-dir":
+			cfg.MailDir = *mailDir
+		case "blob-store":
+			cfg.BlobStore = *blobStore
+		case "image-dir":
+			cfg.ImageDir = *imageDir
+		case "trusted-proxies":
+			cfg.TrustedProxies = splitList(*trustedProxies)
+		case "mfa-required-roles":
+			cfg.MfaRequiredRoles = splitList(*mfaRequiredRoles)
+		case "require-verified-email":
+			cfg.RequireVerifiedEmail = *requireVerifiedEmail
+		}
+	})
+
+	return cfg, cfg.Validate()
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func loadEnv(cfg *Config) error {
+	setString := func(name string, target *string) {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+	setDuration := func(name string, target *Duration) error {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			target.Duration = parsed
+		}
+		return nil
+	}
+
+	setString("PORT", &cfg.Port)
+	setString("STORE", &cfg.Store)
+	setString("MONGODB_URI", &cfg.MongoURI)
+	setString("MONGODB_DATABASE", &cfg.DatabaseName)
+	setString("SECRET_KEY", &cfg.SecretKey)
+	setString("JWT_ALGORITHM", &cfg.JWTAlgorithm)
+	setString("EVENTS", &cfg.Events)
+	setString("MAILER", &cfg.Mailer)
+	setString("MAIL_FROM", &cfg.MailFrom)
+	setString("MAIL_DIR", &cfg.MailDir)
+	setString("SMTP_HOST", &cfg.SMTPHost)
+	setString("SMTP_USERNAME", &cfg.SMTPUsername)
+	setString("SMTP_PASSWORD", &cfg.SMTPPassword)
+	setString("BLOB_STORE", &cfg.BlobStore)
+	setString("IMAGE_DIR", &cfg.ImageDir)
+	setString("MFA_ISSUER", &cfg.MfaIssuer)
+	if value, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		cfg.TrustedProxies = splitList(value)
+	}
+	if value, ok := os.LookupEnv("MFA_REQUIRED_ROLES"); ok {
+		cfg.MfaRequiredRoles = splitList(value)
+	}
+	if err := setDuration("JWT_KEY_ROTATION", &cfg.JWTKeyRotation); err != nil {
+		return err
+	}
+	if err := setDuration("ACCESS_TOKEN_TTL", &cfg.AccessTokenTTL); err != nil {
+		return err
+	}
+	if err := setDuration("REFRESH_TOKEN_TTL", &cfg.RefreshTokenTTL); err != nil {
+		return err
+	}
+	if err := setDuration("REQUEST_TIMEOUT", &cfg.RequestTimeout); err != nil {
+		return err
+	}
+	if err := setDuration("SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout); err != nil {
+		return err
+	}
+	if err := setDuration("PASSWORD_RESET_TTL", &cfg.PasswordResetTTL); err != nil {
+		return err
+	}
+	if err := setDuration("EMAIL_VERIFICATION_TTL", &cfg.EmailVerificationTTL); err != nil {
+		return err
+	}
+	if err := setDuration("LOGIN_BACKOFF", &cfg.LoginBackoff); err != nil {
+		return err
+	}
+	if err := setDuration("LOGIN_LOCKOUT", &cfg.LoginLockout); err != nil {
+		return err
+	}
+	for name, target := range map[string]*int{"LOGIN_ACCOUNT_ATTEMPTS": &cfg.LoginAccountAttempts, "LOGIN_IP_ATTEMPTS": &cfg.LoginIPAttempts} {
+		if value, ok := os.LookupEnv(name); ok {
+			attempts, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*target = attempts
+		}
+	}
+	if value, ok := os.LookupEnv("SMTP_PORT"); ok {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("SMTP_PORT: %w", err)
+		}
+		cfg.SMTPPort = port
+	}
+	if value, ok := os.LookupEnv("MAX_IMAGE_BYTES"); ok {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("MAX_IMAGE_BYTES: %w", err)
+		}
+		cfg.MaxImageBytes = size
+	}
+	if value, ok := os.LookupEnv("BCRYPT_COST"); ok {
+		cost, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("BCRYPT_COST: %w", err)
+		}
+		cfg.BcryptCost = cost
+	}
+	if value, ok := os.LookupEnv("MIGRATE_ON_STARTUP"); ok {
+		migrate, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("MIGRATE_ON_STARTUP: %w", err)
+		}
+		cfg.MigrateOnStartup = migrate
+	}
+	if value, ok := os.LookupEnv("REQUIRE_VERIFIED_EMAIL"); ok {
+		require, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("REQUIRE_VERIFIED_EMAIL: %w", err)
+		}
+		cfg.RequireVerifiedEmail = require
+	}
+	return nil
+}
+
+// splitList reads a comma separated list, dropping blanks, so that "" is the empty list.
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Validate reports every invalid setting at once, so a broken deployment can be fixed in one go.
+func (c Config) Validate() error {
+	var problems []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Errorf("port %q is not a valid TCP port", c.Port))
+	}
+	switch c.Store {
+	case "mongo":
+		if c.MongoURI == "" {
+			problems = append(problems, errors.New("mongo_uri is required when store is mongo"))
+		}
+		if c.DatabaseName == "" {
+			problems = append(problems, errors.New("database_name is required when store is mongo"))
+		}
+	case "memory":
+	default:
+		problems = append(problems, fmt.Errorf("store %q must be mongo or memory", c.Store))
+	}
+	switch c.Events {
+	case "memory":
+	case "changestream":
+		if c.Store != "mongo" {
+			problems = append(problems, errors.New("events changestream needs store mongo"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("events %q must be memory or changestream", c.Events))
+	}
+	switch c.Mailer {
+	case "smtp":
+		if c.SMTPHost == "" {
+			problems = append(problems, errors.New("smtp_host is required when mailer is smtp"))
+		}
+		if c.SMTPPort < 1 || c.SMTPPort > 65535 {
+			problems = append(problems, fmt.Errorf("smtp_port %d is not a valid TCP port", c.SMTPPort))
+		}
+	case "file":
+		if c.MailDir == "" {
+			problems = append(problems, errors.New("mail_dir is required when mailer is file"))
+		}
+	case "memory":
+	default:
+		problems = append(problems, fmt.Errorf("mailer %q must be smtp, file or memory", c.Mailer))
+	}
+	switch c.BlobStore {
+	case "gridfs":
+		if c.Store != "mongo" {
+			problems = append(problems, errors.New("blob_store gridfs needs store mongo"))
+		}
+	case "local":
+		if c.ImageDir == "" {
+			problems = append(problems, errors.New("image_dir is required when blob_store is local"))
+		}
+	case "memory":
+	default:
+		problems = append(problems, fmt.Errorf("blob_store %q must be gridfs, local or memory", c.BlobStore))
+	}
+	if c.MaxImageBytes < 1 {
+		problems = append(problems, errors.New("max_image_bytes must be positive"))
+	}
+	if c.MailFrom == "" {
+		problems = append(problems, errors.New("mail_from is required"))
+	}
+	if c.PasswordResetTTL.Duration <= 0 {
+		problems = append(problems, errors.New("password_reset_ttl must be positive"))
+	}
+	if c.EmailVerificationTTL.Duration <= 0 {
+		problems = append(problems, errors.New("email_verification_ttl must be positive"))
+	}
+	if c.LoginAccountAttempts < 1 {
+		problems = append(problems, errors.New("login_account_attempts must be at least 1"))
+	}
+	if c.LoginIPAttempts < 1 {
+		problems = append(problems, errors.New("login_ip_attempts must be at least 1"))
+	}
+	if c.LoginBackoff.Duration <= 0 {
+		problems = append(problems, errors.New("login_backoff must be positive"))
+	}
+	if c.LoginLockout.Duration < c.LoginBackoff.Duration {
+		problems = append(problems, errors.New("login_lockout must not be shorter than login_backoff"))
+	}
+	// The failed login counts expire from the database after a day of quiet.
+	if c.LoginLockout.Duration > 24*time.Hour {
+		problems = append(problems, errors.New("login_lockout must not be longer than 24h"))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			problems = append(problems, fmt.Errorf("trusted_proxies: %q is neither an IP address nor a CIDR range", proxy))
+		}
+	}
+	for _, role := range c.MfaRequiredRoles {
+		if !slices.Contains(models.Roles, role) {
+			problems = append(problems, fmt.Errorf("mfa_required_roles: %q is not a role", role))
+		}
+	}
+	if c.MfaIssuer == "" {
+		problems = append(problems, errors.New("mfa_issuer is required"))
+	}
+	if c.SecretKey == "" {
+		problems = append(problems, errors.New("secret_key is required: refusing to sign tokens with an empty key"))
+	}
+	switch c.JWTAlgorithm {
+	case "HS256", "RS256", "EdDSA":
+	default:
+		problems = append(problems, fmt.Errorf("jwt_algorithm %q must be HS256, RS256 or EdDSA", c.JWTAlgorithm))
+	}
+	// A new key is published an hour before it starts signing, see helpers.StartSigningKeys.
+	if c.JWTKeyRotation.Duration < 2*time.Hour {
+		problems = append(problems, errors.New("jwt_key_rotation must be at least 2h"))
+	}
+	if c.AccessTokenTTL.Duration <= 0 {
+		problems = append(problems, errors.New("access_token_ttl must be positive"))
+	}
+	if c.RefreshTokenTTL.Duration <= c.AccessTokenTTL.Duration {
+		problems = append(problems, errors.New("refresh_token_ttl must be longer than access_token_ttl"))
+	}
+	if c.RequestTimeout.Duration <= 0 {
+		problems = append(problems, errors.New("request_timeout must be positive"))
+	}
+	if c.ShutdownTimeout.Duration <= 0 {
+		problems = append(problems, errors.New("shutdown_timeout must be positive"))
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		problems = append(problems, fmt.Errorf("bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(problems...))
+	}
+	return nil
+}
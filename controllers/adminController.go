@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"restaurant-management-system/integrity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckIntegrity reports every reference between collections that points at a missing document.
+func (ctl *Controller) CheckIntegrity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+
+		report, err := integrity.Check(ctx, ctl.Store)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// RepairIntegrity is a dry run unless the request says ?apply=true.
+func (ctl *Controller) RepairIntegrity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+
+		apply := c.Query("apply") == "true"
+		report, err := integrity.Repair(ctx, ctl.Store, apply, c.GetString("uid"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	helper "restaurant-management-system/helpers"
+	"restaurant-management-system/middleware"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (ctl *Controller) GetApiKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		page, ok := pageRequest(c)
+		if !ok {
+			return
+		}
+		apiKeys, err := ctl.Store.ApiKeys.List(ctx, includeDeleted(c), page)
+		if !listed(c, err, "error occured while listing the API keys") {
+			return
+		}
+		c.JSON(http.StatusOK, pageView(apiKeys, viewerOf(c).apiKey))
+	}
+}
+
+// CreateApiKey issues a key limited to the requested scopes, which its creator must have. The response is the only place the key ever appears;
+// whoever loses it has to create a new one.
+func (ctl *Controller) CreateApiKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var apiKey models.ApiKey
+		var validate = validator.New()
+
+		if err := c.BindJSON(&apiKey); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(apiKey); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, scope := range apiKey.Scopes {
+			if !middleware.IsPermission(scope) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unknown scope " + scope})
+				return
+			}
+			// A key never gets more than its creator has: the scopes of the API key that creates it, or the
+			// permissions of the role of the user who does.
+			if !middleware.Allowed(c, middleware.Permission(scope)) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "you cannot grant the scope " + scope + ", you do not have it yourself"})
+				return
+			}
+		}
+		if apiKey.Expires_at != nil && !apiKey.Expires_at.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+
+		apiKey.ID = primitive.NewObjectID()
+		apiKey.Api_key_id = apiKey.ID.Hex()
+		key, hash, err := helper.GenerateApiKey(apiKey.Api_key_id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while generating the API key"})
+			return
+		}
+		apiKey.Key_hash = hash
+		apiKey.Created_by = c.GetString("uid")
+		apiKey.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		apiKey.Deleted_at = nil
+		apiKey.Deleted_by = nil
+		apiKey.Version = 1
+
+		insertErr := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			if err := tx.ApiKeys.Create(ctx, apiKey); err != nil {
+				return err
+			}
+			return record(ctx, c, tx, models.AuditCreate, models.AuditApiKey, apiKey.Api_key_id, nil, apiKey)
+		})
+		if insertErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "API key was not created"})
+			return
+		}
+
+		setETag(c, apiKey.Version)
+		c.JSON(http.StatusOK, gin.H{"key": key, "api_key": viewerOf(c).apiKey(apiKey)})
+	}
+}
+
+// RevokeApiKey soft deletes a key; requests using it are refused from then on. Unlike other deletions it cannot
+// be undone, a revoked key may have leaked.
+func (ctl *Controller) RevokeApiKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		apiKeyId := c.Param("api_key_id")
+
+		d := deletion(c)
+		var result models.ApiKey
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditDelete, models.AuditApiKey, apiKeyId, tx.ApiKeys.FindByIDWithDeleted, func() error {
+				return tx.ApiKeys.Delete(ctx, apiKeyId, d)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the API key"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).apiKey(result))
+	}
+}
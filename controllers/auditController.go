@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Every handler that changes a document writes the change and its audit entry in one transaction, so there is no
+// change without an entry and no entry for a change that was rolled back.
+
+// redactedFields never have their values written to the audit log. A change to them is still recorded.
+var redactedFields = map[string]bool{
+	"password": true, "token": true, "refresh_token": true, "token_family": true, "key_hash": true,
+	"mfa_secret": true, "mfa_pending_secret": true, "mfa_recovery_codes": true,
+}
+
+// audited runs write inside the transaction tx and records what it did to the document find returns for id. The
+// document is read before and after the write; a document that did not exist before, like a new one, is recorded
+// with no previous values. It returns the document as it is after the write.
+func audited[T any](ctx context.Context, c *gin.Context, tx *store.Store, action string, resource string, id string, find func(ctx context.Context, id string) (T, error), write func() error) (T, error) {
+	var before interface{}
+	previous, err := find(ctx, id)
+	if err == nil {
+		before = previous
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return previous, err
+	}
+
+	if err := write(); err != nil {
+		return previous, err
+	}
+
+	after, err := find(ctx, id)
+	if err != nil {
+		return after, err
+	}
+	return after, record(ctx, c, tx, action, resource, id, before, after)
+}
+
+// record writes the audit entry for a change to one document, made by the user of the request. s is the
+// transaction the change was made in. before is nil for a create. A write that changed nothing, like restoring a
+// document that was not deleted, leaves no entry.
+func record(ctx context.Context, c *gin.Context, s *store.Store, action string, resource string, id string, before interface{}, after interface{}) error {
+	changes, err := diff(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	entry := models.AuditEntry{
+		ID:          primitive.NewObjectID(),
+		Actor_id:    c.GetString("uid"),
+		Actor_email: c.GetString("email"),
+		Action:      action,
+		Resource:    resource,
+		Resource_id: id,
+		Changes:     changes,
+	}
+	entry.Audit_id = entry.ID.Hex()
+	entry.At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return s.Audit.Create(ctx, entry)
+}
+
+// diff compares two versions of a document field by field, using their bson names.
+func diff(before interface{}, after interface{}) (map[string]models.AuditChange, error) {
+	b, err := auditDocument(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := auditDocument(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]models.AuditChange{}
+	for _, doc := range []bson.M{b, a} {
+		for field := range doc {
+			if field == "_id" || reflect.DeepEqual(b[field], a[field]) {
+				continue
+			}
+			change := models.AuditChange{Before: b[field], After: a[field]}
+			if redactedFields[field] {
+				change = models.AuditChange{Before: "[redacted]", After: "[redacted]"}
+			}
+			changes[field] = change
+		}
+	}
+	return changes, nil
+}
+
+func auditDocument(v interface{}) (bson.M, error) {
+	doc := bson.M{}
+	if v == nil {
+		return doc, nil
+	}
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	err = bson.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// GetAuditLog lists audit entries, newest first. The query can be narrowed with resource, resource_id, actor (a
+// user_id) and a from/to time range in RFC 3339, and is paged like every listing.
+func (ctl *Controller) GetAuditLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+
+		filter := store.AuditFilter{
+			Resource:    c.Query("resource"),
+			Resource_id: c.Query("resource_id"),
+			Actor_id:    c.Query("actor"),
+		}
+		for param, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+			if c.Query(param) == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, c.Query(param))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time, like 2024-10-01T00:00:00Z"})
+				return
+			}
+			*bound = t
+		}
+
+		page, ok := pageRequest(c)
+		if !ok {
+			return
+		}
+		entries, err := ctl.Store.Audit.List(ctx, filter, page)
+		if !listed(c, err, "error occured while fetching the audit log") {
+			return
+		}
+		c.JSON(http.StatusOK, pageView(entries, asIs))
+	}
+}
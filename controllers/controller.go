@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"restaurant-management-system/blobstore"
+	"restaurant-management-system/config"
+	"restaurant-management-system/events"
+	"restaurant-management-system/mailer"
+	"restaurant-management-system/store"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Controller holds the dependencies shared by every handler. main builds one Controller at startup and the route
+// files register its handlers, so no handler reaches for a package level *mongo.Collection any more.
+type Controller struct {
+	Store  *store.Store
+	Config config.Config
+	Events events.Publisher
+	Mailer mailer.Mailer
+	Blobs  blobstore.Store
+}
+
+func NewController(s *store.Store, cfg config.Config, publisher events.Publisher, m mailer.Mailer, blobs blobstore.Store) *Controller {
+	return &Controller{Store: s, Config: cfg, Events: publisher, Mailer: m, Blobs: blobs}
+}
+
+// publish announces a change the handler has committed. The change stands even when publishing fails, so the
+// error is only logged.
+func (ctl *Controller) publish(ctx context.Context, c *gin.Context, e events.Event) {
+	if err := ctl.Events.Publish(ctx, c.GetString("uid"), e); err != nil {
+		log.Printf("publishing %s failed: %v", e.EventType(), err)
+	}
+}
+
+// includeDeleted reports whether the request asked for soft deleted records with ?include_deleted=true.
+func includeDeleted(c *gin.Context) bool {
+	include, _ := strconv.ParseBool(c.Query("include_deleted"))
+	return include
+}
+
+// Listings return defaultPageLimit items unless ?limit= asks for another number, up to maxPageLimit.
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageRequest reads the page a listing is asked for: ?cursor= continues after the page that returned it as
+// next_cursor, and ?limit= is the size of the page. A bad limit is answered with 400 and false is returned.
+func pageRequest(c *gin.Context) (store.Page, bool) {
+	page := store.Page{Cursor: c.Query("cursor"), Limit: defaultPageLimit}
+	if c.Query("limit") != "" {
+		limit, err := strconv.Atoi(c.Query("limit"))
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number from 1 to " + strconv.Itoa(maxPageLimit)})
+			return page, false
+		}
+		page.Limit = limit
+	}
+	return page, true
+}
+
+// listed answers the error of a listing, if there is one, and reports whether there was none. A cursor that does
+// not belong to the listing is the fault of the client.
+func listed(c *gin.Context, err error, msg string) bool {
+	if errors.Is(err, store.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the cursor is invalid for this listing, start over without one"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return false
+	}
+	return true
+}
+
+// deletion is the stamp for a soft delete made by the user of the request. It keeps the milliseconds MongoDB stores,
+// because restoring an order brings back the items whose stamp equals the order's, and an item deleted on its own a
+// moment earlier must not count as deleted with the order.
+func deletion(c *gin.Context) store.Deletion {
+	return store.Deletion{At: time.Now().UTC().Truncate(time.Millisecond), By: c.GetString("uid")}
+}
+
+// conflict is returned from a transaction when a guard rail refuses the change, which rolls the transaction back.
+// The handler sends its message with a 409.
+type conflict string
+
+func (e conflict) Error() string {
+	return string(e)
+}
+
+// invalid is returned from a transaction when the request does not hold up against what the transaction read, like
+// an order item for a food that does not exist. The handler sends its message with a 400.
+type invalid string
+
+func (e invalid) Error() string {
+	return string(e)
+}
+
+// setETag sends the version of the returned document as its ETag. A client passes it back in If-Match to make sure
+// its PATCH applies to the version it read.
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// ifMatch returns the version a PATCH expects from the If-Match header. Without the header, or with "*", it returns
+// 0 and the update applies to whatever version is current.
+func ifMatch(c *gin.Context) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+	if err != nil || version < 1 {
+		return 0, errors.New("If-Match must be an ETag returned by this API")
+	}
+	return version, nil
+}
@@ -2,159 +2,132 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"math"
 	"net/http"
-	"restaurant-management-system/database"
+	"restaurant-management-system/events"
 	"restaurant-management-system/models"
+	"restaurant-management-system/store"
 
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10" // Add this import
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "food")
-
-func GetFoods() gin.HandlerFunc {
+// GetFoods lists the food catalog. The query can be narrowed with search (words in the name or description),
+// menu_id, min_price and max_price, tags (comma separated, a food needs all of them) and available, and sorted with
+// sort=name, price or created_at, descending with a leading minus like sort=-price. A search without a sort returns
+// the best matches first.
+func (ctl *Controller) GetFoods() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel() // Ensure cancel is called before returning
 
-		// c.Query("recordPerPage") extracts the value of the recordPerPage query parameter from the URL (e.g., in http://example.com?page=2&recordPerPage=10, the value of recordPerPage would be "10").
-
-		// strconv is part of Go's standard library. It contains functions for string conversions, including converting strings to integers.
-
-		// strconv.Atoi(...) attempts to convert the string returned by c.Query("recordPerPage") into an integer.
-
-		// The default value is chosen to ensure the application has a reasonable, valid value to work with for pagination purposes. In this case, 10 means that the system will show 10 records per page if the user input is invalid or missing.
-
-		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
-		if err != nil || recordPerPage < 1 {
-			recordPerPage = 10
-		}
-
-		page, err1 := strconv.Atoi(c.Query("page"))
-		if err1 != nil || page < 1 {
-			page = 1
-		}
-
-		// For page 1, startIndex is 0 (starting from the first record).
-		//For page 2, startIndex is 10 (starting from the 11th record).
-		//For page 3, startIndex is 20 (starting from the 21st record).
-
-		startIndex := (page - 1) * recordPerPage // Remove the conflicting reassign
-
-		// "$match": This is an aggregation operator used in MongoDB to filter documents.
-		// It selects documents that match the specified condition.
-		// Value: bson.D{{}} - This indicates that no specific matching criteria are set, allowing all documents to be included.
-		matchStage := bson.D{{Key: "$match", Value: bson.D{{}}}}
-		// "$group": This is an aggregation operator that groups documents together based on a specified key
-		groupStage := bson.D{
-			{Key: "$group", Value: bson.D{
-				// Setting _id to nil means that theres no specific grouping criterion. Instead, it groups all documents into a single group.
-				// total_count:-This is the name of the new field in the output document that will store the count of the documents
-				//For each document processed, MongoDB sees 1 and adds it to a total count.
-				//If there are three documents in the group, total_count will be 3.
-				// The $push operator adds items to an array. In this case, it pushes the entire document being processed into the array.
-				// So when you push $$ROOT, you are pushing the complete document, not just specific fields.
-
-				//{
-				//"_id": null,
-				//"total_count": 3,
-				//"data": [
-				//{ "name": "Pizza", "type": "fast food" },
-				//{ "name": "Sushi", "type": "Japanese" },
-				//{ "name": "Burger", "type": "fast food" }
-				//]
-				//}
-
-				{Key: "_id", Value: nil},
-				{Key: "total_count", Value: bson.D{{Key: "$sum", Value: 1}}},
-				{Key: "data", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}},
-			}},
-		}
-
-		// Index is page in this case
-		//Suppose $data contains the following array of food items:
-		//[
-		//{"name": "Pizza"},
-		//{"name": "Burger"},
-		//{"name": "Sushi"},
-		//{"name": "Pasta"},
-		//{"name": "Salad"},
-		//{"name": "Tacos"},
-		//{"name": "Ice Cream"},
-		//{"name": "Cake"}
-		//]
-
-		// If startIndex is 4 and recordPerPage is 3, the $slice operation would yield:
-		//[
-		//{"name": "Salad"},
-		//{"name": "Tacos"},
-		//{"name": "Ice Cream"}
-		//]
-
-		// This stage is crucial for returning a clean and relevant set of results for the client, ensuring they get only what they need to display on their UI.
-		projectStage := bson.D{
-			{Key: "$project", Value: bson.D{
-				{Key: "_id", Value: 0},
-				{Key: "total_count", Value: 1},
-				{Key: "food_items", Value: bson.D{{Key: "$slice", Value: bson.A{"$data", startIndex, recordPerPage}}}},
-			}},
-		}
-
-		// example
-		//Filter: Retrieve only the items in the "Fruits" category.
-		//Group: Calculate the total quantity of fruits.
-		//Project: Return the total quantity and the names of the fruit
-
-		result, err := foodCollection.Aggregate(ctx, mongo.Pipeline{matchStage, groupStage, projectStage})
+		filter, err := foodFilter(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the food items"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		// bson.M is used to hold the data returned from MongoDB queries in Go.in here it is stored that in allFoods
 
-		// allFoods = []bson.M{
-		//{"_id": nil, "total_count": 5, "data": [{"name": "Apple", "category": "Fruits", "price": 0.5}, ...]},
-		//}
+		page, ok := pageRequest(c)
+		if !ok {
+			return
+		}
+		foodItems, err := ctl.Store.Foods.List(ctx, filter, page)
+		if !listed(c, err, "error occurred while fetching the food items") {
+			return
+		}
+		c.JSON(http.StatusOK, pageView(foodItems, viewerOf(c).food))
+	}
+}
 
-		var allFoods []bson.M
+// foodFilter reads the filters and the sort order of GetFoods from the query.
+func foodFilter(c *gin.Context) (store.FoodFilter, error) {
+	filter := store.FoodFilter{
+		Search:          strings.TrimSpace(c.Query("search")),
+		Menu_id:         c.Query("menu_id"),
+		Include_deleted: includeDeleted(c),
+	}
+	for param, bound := range map[string]**float64{"min_price": &filter.Min_price, "max_price": &filter.Max_price} {
+		if c.Query(param) == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(c.Query(param), 64)
+		if err != nil || price < 0 {
+			return filter, fmt.Errorf("%s must be a price, like 12.50", param)
+		}
+		*bound = &price
+	}
+	if filter.Min_price != nil && filter.Max_price != nil && *filter.Min_price > *filter.Max_price {
+		return filter, errors.New("min_price cannot be above max_price")
+	}
+	if tags := c.Query("tags"); tags != "" {
+		filter.Tags = normalizeTags(strings.Split(tags, ","))
+	}
+	if c.Query("available") != "" {
+		available, err := strconv.ParseBool(c.Query("available"))
+		if err != nil {
+			return filter, errors.New("available must be true or false")
+		}
+		filter.Available = &available
+	}
+	filter.Sort, filter.Descending = strings.CutPrefix(c.Query("sort"), "-")
+	switch filter.Sort {
+	case "", "name", "price", "created_at":
+	default:
+		return filter, errors.New("sort must be name, price or created_at, with a leading - to sort descending")
+	}
+	return filter, nil
+}
 
-		// This function call retrieves all the documents from the MongoDB cursor (result) and decodes them into the allFoods slice.
-		if err = result.All(ctx, &allFoods); err != nil {
-			log.Fatal(err)
+// normalizeTags lower cases and trims tags and drops empty and repeated ones, so that a filter on Vegan finds the
+// foods tagged vegan.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
 		}
-		c.JSON(http.StatusOK, allFoods)
 	}
+	return normalized
 }
 
-func GetFood() gin.HandlerFunc {
+func (ctl *Controller) GetFood() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		foodId := c.Param("food_id")
-		var food models.Food
 
-		err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food)
+		findFood := ctl.Store.Foods.FindByID
+		if includeDeleted(c) {
+			findFood = ctl.Store.Foods.FindByIDWithDeleted
+		}
+		food, err := findFood(ctx, foodId)
 		defer cancel()
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+			return
 		}
-		c.JSON(http.StatusOK, food)
+		setETag(c, food.Version)
+		c.JSON(http.StatusOK, viewerOf(c).food(food))
 	}
 }
 
-func CreateFood() gin.HandlerFunc {
+func (ctl *Controller) CreateFood() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-		var menu models.Menu
 		var food models.Food
 		// The validator package allows you to define rules for each field in your structs. For example, you can specify that a certain field must be a valid email address, must not be empty, must be a specific length, etc. This is done using struct tags.
 		// Check your model files
@@ -180,9 +153,8 @@ func CreateFood() gin.HandlerFunc {
 
 		// The data is temporarily stored in the menu struct for the duration of the CreateFood function execution. It allows you to use the information from the database (like validating that the menu exists) without having to keep the data in MongoDB at that moment.
 
-		// Therefore, there will be no storage of the menu data in the menuCollection during the execution of this function.
-		err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.Menu_id}).Decode(&menu)
-		defer cancel()
+		// Therefore, there will be no storage of the menu data in the menu collection during the execution of this function.
+		_, err := ctl.Store.Menus.FindByID(ctx, *food.Menu_id)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "menu was not found"})
 			return
@@ -190,20 +162,48 @@ func CreateFood() gin.HandlerFunc {
 		food.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.ID = primitive.NewObjectID()
+		food.Version = 1
 		// this line converts the newly generated ObjectID (which is used as the primary key for the food item in MongoDB) into a hexadecimal string representation.
 		food.Food_id = food.ID.Hex()
-		var num = toFixed(*food.Price, 2) // Dereference food.Price
-		food.Price = &num                 // Assign the result back as a pointer
+		if len(food.Variants) > 0 {
+			if err := priceVariants(&food); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		} else if food.Price == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "a food needs a price, or variants with a price each"})
+			return
+		} else if *food.Price < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the price must be at least 0"})
+			return
+		} else {
+			var num = toFixed(*food.Price, 2) // Dereference food.Price
+			food.Price = &num                 // Assign the result back as a pointer
+		}
+		if err := checkModifierGroups(food.Modifier_groups); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		food.Tags = normalizeTags(food.Tags)
+		if food.Available == nil {
+			available := true
+			food.Available = &available
+		}
 
 		//  In Go, when you use the MongoDB driver to insert a document into a collection, you don't need to manually marshal (serialize) your struct into a BSON format before insertion. The MongoDB Go driver handles this for you automatically
 
-		result, insertErr := foodCollection.InsertOne(ctx, food)
+		insertErr := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			if err := tx.Foods.Create(ctx, food); err != nil {
+				return err
+			}
+			return record(ctx, c, tx, models.AuditCreate, models.AuditFood, food.Food_id, nil, food)
+		})
 		if insertErr != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": insertErr.Error()})
 			return
 		}
-		defer cancel()
-		c.JSON(http.StatusOK, result)
+		setETag(c, food.Version)
+		c.JSON(http.StatusOK, viewerOf(c).food(food))
 
 	}
 }
@@ -219,11 +219,120 @@ func toFixed(num float64, precision int) float64 {
 
 }
 
-func UpdateFood() gin.HandlerFunc {
+// A food is sold either in the StandardSizes, all at its price, or in the variants it lists, each at a price of its
+// own. The price of a food with variants is the lowest of them, which is what the catalog shows and sorts on.
+
+// errPricedByVariants refuses an update that sets the price of a food that has variants, without replacing them.
+var errPricedByVariants = errors.New("the price of a food with variants is taken from them, change their prices instead")
+
+// priceVariants checks the variants of food, rounds their prices and sets the price of the food from them.
+func priceVariants(food *models.Food) error {
+	seen := map[string]bool{}
+	var lowest *float64
+	for i := range food.Variants {
+		variant := &food.Variants[i]
+		variant.Name = strings.TrimSpace(variant.Name)
+		if variant.Name == "" || len(variant.Name) > 30 {
+			return fmt.Errorf("the name of a variant must be between 1 and 30 characters")
+		}
+		if seen[strings.ToLower(variant.Name)] {
+			return fmt.Errorf("the variant %s is listed twice", variant.Name)
+		}
+		seen[strings.ToLower(variant.Name)] = true
+		if variant.Price == nil || *variant.Price < 0 {
+			return fmt.Errorf("the variant %s needs a price of at least 0", variant.Name)
+		}
+		price := toFixed(*variant.Price, 2)
+		variant.Price = &price
+		if lowest == nil || price < *lowest {
+			lowest = variant.Price
+		}
+	}
+	food.Price = lowest
+	return nil
+}
+
+// variantPrice is the variant of food that variant names, spelled the way the food spells it, and its price. Names
+// are matched regardless of case, as they are told apart when the variants are set. It returns false when the food
+// is not sold that way.
+func variantPrice(food models.Food, variant string) (string, float64, bool) {
+	if len(food.Variants) == 0 {
+		size := slices.IndexFunc(models.StandardSizes, func(size string) bool {
+			return strings.EqualFold(size, variant)
+		})
+		if food.Price == nil || size < 0 {
+			return "", 0, false
+		}
+		return models.StandardSizes[size], *food.Price, true
+	}
+	for _, offered := range food.Variants {
+		if strings.EqualFold(offered.Name, variant) && offered.Price != nil {
+			return offered.Name, *offered.Price, true
+		}
+	}
+	return "", 0, false
+}
+
+// checkModifierGroups checks the modifier groups of a food and rounds the prices of their modifiers.
+func checkModifierGroups(groups []models.ModifierGroup) error {
+	seen := map[string]bool{}
+	for i := range groups {
+		group := &groups[i]
+		group.Name = strings.TrimSpace(group.Name)
+		if group.Name == "" || len(group.Name) > 50 {
+			return fmt.Errorf("the name of a modifier group must be between 1 and 50 characters")
+		}
+		if seen[strings.ToLower(group.Name)] {
+			return fmt.Errorf("the modifier group %s is listed twice", group.Name)
+		}
+		seen[strings.ToLower(group.Name)] = true
+		if len(group.Modifiers) == 0 {
+			return fmt.Errorf("the modifier group %s needs modifiers", group.Name)
+		}
+		if group.Max < 1 || group.Min < 0 || group.Min > group.Max || group.Min > len(group.Modifiers) {
+			return fmt.Errorf("the modifier group %s needs a max of at least 1 and a min between 0 and max, and no more than its modifiers", group.Name)
+		}
+		names := map[string]bool{}
+		for j := range group.Modifiers {
+			modifier := &group.Modifiers[j]
+			modifier.Name = strings.TrimSpace(modifier.Name)
+			if modifier.Name == "" || len(modifier.Name) > 50 {
+				return fmt.Errorf("the name of a modifier in %s must be between 1 and 50 characters", group.Name)
+			}
+			if names[strings.ToLower(modifier.Name)] {
+				return fmt.Errorf("the modifier %s is listed twice in %s", modifier.Name, group.Name)
+			}
+			names[strings.ToLower(modifier.Name)] = true
+			if modifier.Price == nil || *modifier.Price < 0 {
+				return fmt.Errorf("the modifier %s in %s needs a price of at least 0", modifier.Name, group.Name)
+			}
+			price := toFixed(*modifier.Price, 2)
+			modifier.Price = &price
+		}
+	}
+	return nil
+}
+
+func sameVariant(a models.FoodVariant, b models.FoodVariant) bool {
+	return a.Name == b.Name && a.Price != nil && b.Price != nil && *a.Price == *b.Price
+}
+
+// variantNames lists the variants food is sold in, for error messages.
+func variantNames(food models.Food) []string {
+	if len(food.Variants) == 0 {
+		return models.StandardSizes
+	}
+	names := make([]string, len(food.Variants))
+	for i, variant := range food.Variants {
+		names[i] = variant.Name
+	}
+	return names
+}
+
+func (ctl *Controller) UpdateFood() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-		var menu models.Menu
 		var food models.Food
 
 		foodId := c.Param("food_id")
@@ -235,6 +344,12 @@ func UpdateFood() gin.HandlerFunc {
 		// Think of primitive.D like a list where each item in the list is a pair of a key and a value. This is similar to how a dictionary or a map works in other programming languages
 
 		// Each item in updateObj will represent a field in the food item that you want to change. For example, if you want to change the food's name, you'd add a key-value pair where the key is "name" and the value is the new name.
+		version, err := ifMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		var updateObj primitive.D
 
 		// By checking if food.Name is not nil, you ensure that you only update the name field if a new value has actually been provided.
@@ -251,60 +366,186 @@ func UpdateFood() gin.HandlerFunc {
 			updateObj = append(updateObj, bson.E{Key: "name", Value: food.Name})
 		}
 
+		// Variants replace the ones the food had, and an empty list goes back to the standard sizes at the price.
+		if len(food.Variants) > 0 {
+			if food.Price != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "the price of a food with variants is taken from them, send only the variants"})
+				return
+			}
+			if err := priceVariants(&food); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "variants", Value: food.Variants})
+		} else if food.Variants != nil {
+			updateObj = append(updateObj, bson.E{Key: "variants", Value: []models.FoodVariant{}})
+		}
+
 		if food.Price != nil {
+			if *food.Price < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "the price must be at least 0"})
+				return
+			}
+			var num = toFixed(*food.Price, 2)
+			food.Price = &num
 			updateObj = append(updateObj, bson.E{Key: "price", Value: food.Price})
 		}
 
+		// Modifier groups replace the ones the food had. Items already ordered keep the modifiers they were ordered with.
+		if food.Modifier_groups != nil {
+			if err := checkModifierGroups(food.Modifier_groups); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "modifier_groups", Value: food.Modifier_groups})
+		}
+
 		if food.Food_image != nil {
 			updateObj = append(updateObj, bson.E{Key: "food_image", Value: food.Food_image})
 		}
 
-		if food.Menu_id != nil {
-			err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.Menu_id}).Decode(&menu)
+		if food.Description != nil {
+			updateObj = append(updateObj, bson.E{Key: "description", Value: food.Description})
+		}
+
+		if food.Tags != nil {
+			updateObj = append(updateObj, bson.E{Key: "tags", Value: normalizeTags(food.Tags)})
+		}
+
+		if food.Available != nil {
+			updateObj = append(updateObj, bson.E{Key: "available", Value: food.Available})
+		}
 
-			defer cancel()
+		if food.Menu_id != nil {
+			_, err := ctl.Store.Menus.FindByID(ctx, *food.Menu_id)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "menu was not found"})
 				return
 			}
 
-			updateObj = append(updateObj, bson.E{Key: "menu", Value: food.Menu_id})
+			updateObj = append(updateObj, bson.E{Key: "menu_id", Value: food.Menu_id})
 		}
 		food.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: food.Updated_at})
-		// In the context of MongoDB operations, "upsert" is a combination of "update" and "insert"
+		// The update never creates a food: patching a food_id that does not exist is a 404. With an If-Match header
+		// it only applies when nobody changed the food since the client read it.
+
+		var previous, result models.Food
+		err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			if previous, err = tx.Foods.FindByID(ctx, foodId); err != nil {
+				return err
+			}
+			if food.Variants == nil && len(previous.Variants) > 0 && food.Price != nil {
+				return errPricedByVariants
+			}
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditFood, foodId, tx.Foods.FindByIDWithDeleted, func() error {
+				return tx.Foods.Update(ctx, foodId, updateObj, version)
+			})
+			return err
+		})
+		if errors.Is(err, errPricedByVariants) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrConflict) {
+			current, _ := ctl.Store.Foods.FindByID(ctx, foodId)
+			setETag(c, current.Version)
+			c.JSON(http.StatusConflict, gin.H{"error": "the food item was changed by someone else, apply your change to the current version and retry", "current": viewerOf(c).food(current)})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Food item update failed"})
+			return
+		}
 
-		// When upsert is set to true, it means that if the specified document to be updated does not exist, a new document should be created with the specified values.
+		if previous.Price == nil || result.Price == nil || *previous.Price != *result.Price || !slices.EqualFunc(previous.Variants, result.Variants, sameVariant) {
+			ctl.publish(ctx, c, events.FoodPriceChangedEvent{
+				Food_id:         foodId,
+				Name:            result.Name,
+				Before:          previous.Price,
+				After:           result.Price,
+				Before_variants: previous.Variants,
+				After_variants:  result.Variants,
+			})
+		}
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).food(result))
+	}
+}
 
-		// For example, if you attempt to update a food item with food_id "12345" and that item does not exist, a new document will be created with the food_id set to "12345" and the fields specified in updateObj
+// DeleteFood soft deletes a food. It can still be read with ?include_deleted=true and brought back by RestoreFood.
+func (ctl *Controller) DeleteFood() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		foodId := c.Param("food_id")
 
-		upsert := true
-		// Suppose you want to update a food item with a specific food_id (for example, "12345").
+		var result models.Food
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditDelete, models.AuditFood, foodId, tx.Foods.FindByIDWithDeleted, func() error {
+				return tx.Foods.Delete(ctx, foodId, deletion(c))
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while deleting the food item"})
+			return
+		}
 
-		//If foodId holds the value "12345",  such a document exists, the updates specified in updateObj will be applied to it
-		filter := bson.M{"food_id": foodId}
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).food(result))
+	}
+}
 
-		// options.UpdateOptions?
+// RestoreFood brings back a deleted food. Its menu has to be restored first, otherwise the food would point at a
+// menu that is gone.
+func (ctl *Controller) RestoreFood() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		foodId := c.Param("food_id")
 
-		//This is a struct provided by the MongoDB Go driver that contains options you can set when you want to update a document in the database.
-		// Think of it as a way to customize how your update operation will behave.
-		// The Upsert field in UpdateOptions is a boolean (true or false) that tells MongoDB whether to create a new document if it doesnt find one to update.
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		var result models.Food
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			food, err := tx.Foods.FindByIDWithDeleted(ctx, foodId)
+			if err != nil {
+				return err
+			}
+			if food.Menu_id != nil {
+				if _, err := tx.Menus.FindByID(ctx, *food.Menu_id); errors.Is(err, store.ErrNotFound) {
+					return conflict("the menu of this food is deleted, restore the menu first")
+				} else if err != nil {
+					return err
+				}
+			}
+			result, err = audited(ctx, c, tx, models.AuditRestore, models.AuditFood, foodId, tx.Foods.FindByIDWithDeleted, func() error {
+				return tx.Foods.Restore(ctx, foodId)
+			})
+			return err
+		})
+		var refused conflict
+		if errors.As(err, &refused) {
+			c.JSON(http.StatusConflict, gin.H{"error": refused.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
 		}
-		//  bson.D is commonly used for creating documents to be inserted or updated in the database
-		result, err := foodCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Food item update failed"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while restoring the food item"})
+			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).food(result))
 	}
 }
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"restaurant-management-system/blobstore"
+	helper "restaurant-management-system/helpers"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Food images are uploaded through the API instead of being linked from anywhere on the web. Every upload gets an
+// image_id of its own and its blobs are written before the food points at them, so a failed upload leaves the food
+// as it was and a client never sees an image that is half replaced. The blobs of the replaced image are removed
+// once the food points at the new one.
+
+// UploadFoodImage takes the image of a food from the multipart field image, stores it with its thumbnails and
+// points the food at it.
+func (ctl *Controller) UploadFoodImage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		foodId := c.Param("food_id")
+
+		version, err := ifMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// The rest of the multipart body is small, the limit leaves it room next to the image.
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, ctl.Config.MaxImageBytes+64<<10)
+		file, _, err := c.Request.FormFile("image")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("the image must be at most %d bytes", ctl.Config.MaxImageBytes)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "send the image as the multipart/form-data field image"})
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, ctl.Config.MaxImageBytes+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "error occured while reading the image"})
+			return
+		}
+		if int64(len(data)) > ctl.Config.MaxImageBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("the image must be at most %d bytes", ctl.Config.MaxImageBytes)})
+			return
+		}
+
+		variants, err := helper.ResizeImage(data)
+		if err != nil {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, err := ctl.Store.Foods.FindByID(ctx, foodId); errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+			return
+		}
+
+		uploadedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		image := models.FoodImage{Image_id: primitive.NewObjectID().Hex(), Uploaded_at: uploadedAt, Uploaded_by: c.GetString("uid")}
+		for _, size := range append([]string{helper.ImageOriginal}, imageSizeNames()...) {
+			variant, ok := variants[size]
+			if !ok {
+				continue
+			}
+			if err := ctl.Blobs.Put(ctx, foodImageBlob(foodId, image.Image_id, size), variant.Data); err != nil {
+				ctl.deleteFoodImage(ctx, foodId, &image)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while storing the image"})
+				return
+			}
+			image.Sizes = append(image.Sizes, models.FoodImageSize{
+				Size:         size,
+				Content_type: variant.Content_type,
+				Width:        variant.Width,
+				Height:       variant.Height,
+				Bytes:        len(variant.Data),
+			})
+		}
+
+		var previous, result models.Food
+		err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			if previous, err = tx.Foods.FindByID(ctx, foodId); err != nil {
+				return err
+			}
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditFood, foodId, tx.Foods.FindByIDWithDeleted, func() error {
+				return tx.Foods.Update(ctx, foodId, primitive.D{
+					{Key: "image", Value: image},
+					{Key: "food_image", Value: foodImageUrl(foodId, image.Image_id, helper.ImageOriginal)},
+					{Key: "updated_at", Value: uploadedAt},
+				}, version)
+			})
+			return err
+		})
+		if err != nil {
+			ctl.deleteFoodImage(ctx, foodId, &image)
+		}
+		if errors.Is(err, store.ErrConflict) {
+			current, _ := ctl.Store.Foods.FindByID(ctx, foodId)
+			setETag(c, current.Version)
+			c.JSON(http.StatusConflict, gin.H{"error": "the food item was changed by someone else, apply your change to the current version and retry", "current": viewerOf(c).food(current)})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the food item"})
+			return
+		}
+		ctl.deleteFoodImage(ctx, foodId, previous.Image)
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).food(result))
+	}
+}
+
+// GetFoodImage serves the uploaded image of a food in the size asked for with ?size=, the original by default. A
+// size that is larger than the original was not made and is answered with the original.
+func (ctl *Controller) GetFoodImage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		foodId := c.Param("food_id")
+
+		size := c.DefaultQuery("size", helper.ImageOriginal)
+		if _, ok := helper.ImageSizes[size]; !ok && size != helper.ImageOriginal {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size must be %s or one of %v", helper.ImageOriginal, imageSizeNames())})
+			return
+		}
+
+		food, err := ctl.Store.Foods.FindByID(ctx, foodId)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "food item was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+			return
+		}
+		if food.Image == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no image was uploaded for this food"})
+			return
+		}
+		var served *models.FoodImageSize
+		for i := range food.Image.Sizes {
+			if food.Image.Sizes[i].Size == size || (served == nil && food.Image.Sizes[i].Size == helper.ImageOriginal) {
+				served = &food.Image.Sizes[i]
+			}
+		}
+		if served == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no image was uploaded for this food"})
+			return
+		}
+
+		// The URLs the food hands out carry the image_id in v, so what they point at never changes and can be cached
+		// for good. Without it, a client has to revalidate, because the next upload replaces the image.
+		etag := `"` + food.Image.Image_id + "-" + served.Size + `"`
+		if c.Query("v") == food.Image.Image_id {
+			c.Header("Cache-Control", "private, max-age=31536000, immutable")
+		} else {
+			c.Header("Cache-Control", "private, no-cache")
+		}
+		c.Header("ETag", etag)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+
+		data, err := ctl.Blobs.Get(ctx, foodImageBlob(foodId, food.Image.Image_id, served.Size))
+		if errors.Is(err, blobstore.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "the image of this food is missing from the blob store"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the image"})
+			return
+		}
+		c.Data(http.StatusOK, served.Content_type, data)
+	}
+}
+
+// deleteFoodImage removes the blobs of image, which nothing points at any more. A blob left behind only takes
+// space, so failures are logged and not answered.
+func (ctl *Controller) deleteFoodImage(ctx context.Context, foodId string, image *models.FoodImage) {
+	if image == nil {
+		return
+	}
+	for _, size := range image.Sizes {
+		if err := ctl.Blobs.Delete(ctx, foodImageBlob(foodId, image.Image_id, size.Size)); err != nil {
+			log.Printf("deleting the %s image %s of food %s failed: %v", size.Size, image.Image_id, foodId, err)
+		}
+	}
+}
+
+func foodImageBlob(foodId string, imageId string, size string) string {
+	return "foods/" + foodId + "/" + imageId + "/" + size
+}
+
+func foodImageUrl(foodId string, imageId string, size string) string {
+	query := url.Values{"v": {imageId}}
+	if size != helper.ImageOriginal {
+		query.Set("size", size)
+	}
+	return "/foods/" + url.PathEscape(foodId) + "/image?" + query.Encode()
+}
+
+// imageSizeNames lists the thumbnail sizes, smallest first.
+func imageSizeNames() []string {
+	names := make([]string, 0, len(helper.ImageSizes))
+	for name := range helper.ImageSizes {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return helper.ImageSizes[a] - helper.ImageSizes[b]
+	})
+	return names
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz tells the orchestrator the process is alive. It never touches the database, so a slow MongoDB does not get
+// the pod restarted.
+func (ctl *Controller) Healthz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// Readyz tells the load balancer whether this instance can serve traffic, which needs a reachable database.
+func (ctl *Controller) Readyz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		if err := ctl.Store.Ping(ctx); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
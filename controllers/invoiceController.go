@@ -2,17 +2,17 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"restaurant-management-system/database"
+	"restaurant-management-system/events"
 	"restaurant-management-system/models"
+	"restaurant-management-system/store"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // the interface{} type is a special type that can represent any type of value. It essentially means "any type" or "no specific type." It is Go's way of providing flexibility, allowing a variable to hold values of any data type, such as strings, integers, structs, or even slices and maps.
@@ -35,50 +35,60 @@ import (
 // }
 
 type InvoiceViewFormat struct {
-	Invoice_id       string      `json:"invoice_id"`
-	Order_id         string      `json:"order_id"`
-	Payment_method   string      `json:"payment_method"`
-	Payment_status   *string     `json:"payment_status"`
-	Payment_due      interface{} `json:"payment_due"`
-	Table_number     interface{} `json:"table_number"`
-	Payment_due_date time.Time   `json:"payment_due_date"`
-	Order_details    interface{} `json:"order_details"`
+	Invoice_id       string                `json:"invoice_id"`
+	Order_id         string                `json:"order_id"`
+	Payment_method   string                `json:"payment_method"`
+	Payment_status   *string               `json:"payment_status"`
+	Payment_due      float64               `json:"payment_due"`
+	Table_number     *int                  `json:"table_number"`
+	Payment_due_date time.Time             `json:"payment_due_date"`
+	Order_details    []OrderLineViewFormat `json:"order_details"`
+	Version          int                   `json:"version"`
 }
 
-var invoiceCollection *mongo.Collection = database.OpenCollection(database.Client, "invoice")
-
-func GetInvoices() gin.HandlerFunc {
+func (ctl *Controller) GetInvoices() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-		result, err := invoiceCollection.Find(context.TODO(), bson.M{})
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		page, ok := pageRequest(c)
+		if !ok {
+			return
 		}
-		var allinvoices []bson.M
-		if err = result.All(ctx, &allinvoices); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		allinvoices, err := ctl.Store.Invoices.List(ctx, includeDeleted(c), page)
+		if !listed(c, err, "error occured while listing the invoices") {
+			return
 		}
-		c.JSON(http.StatusOK, allinvoices)
+		c.JSON(http.StatusOK, pageView(allinvoices, viewerOf(c).invoice))
 	}
 }
 
-func GetInvoice() gin.HandlerFunc {
+func (ctl *Controller) GetInvoice() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		invoiceId := c.Param("invoice_id")
-		var invoice models.Invoice
 
-		err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		findInvoice := ctl.Store.Invoices.FindByID
+		if includeDeleted(c) {
+			findInvoice = ctl.Store.Invoices.FindByIDWithDeleted
+		}
+		invoice, err := findInvoice(ctx, invoiceId)
 		defer cancel()
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
 		var invoiceView InvoiceViewFormat
+		var allOrderDetails []OrderDetailsViewFormat
 		//  The purpose of this function is likely to retrieve a list of items associated with a specific order
-		allOrderItems, err := ItemsByOrder(invoice.Order_id)
+		allOrderItems, err := ctl.ItemsByOrder(invoice.Order_id)
+		if err == nil {
+			allOrderDetails, err = orderDetails(allOrderItems)
+		}
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -102,17 +112,24 @@ func GetInvoice() gin.HandlerFunc {
 
 		// Payment_status is a field in the invoice struct that is defined as a pointer (*string). This means it can hold either nil (indicating no value) or a reference to a string value (e.g., "PAID" or "PENDING")
 		invoiceView.Payment_status = invoice.Payment_status
-		invoiceView.Payment_due = allOrderItems[0]["payment_due"]
-		invoiceView.Table_number = allOrderItems[0]["table_number"]
-		invoiceView.Order_details = allOrderItems[0]["order_items"]
+		invoiceView.Payment_due_date = invoice.Payment_due_date
+		// An order without items has no summary document.
+		invoiceView.Order_details = []OrderLineViewFormat{}
+		if len(allOrderDetails) > 0 {
+			invoiceView.Payment_due = allOrderDetails[0].Payment_due
+			invoiceView.Table_number = allOrderDetails[0].Table_number
+			invoiceView.Order_details = allOrderDetails[0].Order_items
+		}
+		invoiceView.Version = invoice.Version
 
+		setETag(c, invoice.Version)
 		c.JSON(http.StatusOK, invoiceView)
 	}
 }
 
-func CreateInvoice() gin.HandlerFunc {
+func (ctl *Controller) CreateInvoice() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var invoice models.Invoice
 
@@ -121,16 +138,6 @@ func CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		var order models.Order
-
-		err := orderCollection.FindOne(ctx, bson.M{"order_id": invoice.Order_id}).Decode(&order)
-		defer cancel()
-		if err != nil {
-
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order Was not found"})
-			return
-		}
-
 		status := "PENDING"
 		if invoice.Payment_method == nil {
 			invoice.Payment_status = &status
@@ -145,6 +152,7 @@ func CreateInvoice() gin.HandlerFunc {
 		// The result is a new date that is 1 day after the current date.
 		invoice.Payment_due_date, _ = time.Parse(time.RFC3339, time.Now().AddDate(0, 0, 1).Format(time.RFC3339))
 		invoice.ID = primitive.NewObjectID()
+		invoice.Version = 1
 		invoice.Invoice_id = invoice.ID.Hex()
 		var validate = validator.New()
 
@@ -154,7 +162,21 @@ func CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		result, insertErr := invoiceCollection.InsertOne(ctx, invoice)
+		// The order is read in the same transaction that creates the invoice, so the invoice cannot be written for an
+		// order that disappears in between.
+		insertErr := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			if _, err := tx.Orders.FindByID(ctx, invoice.Order_id); err != nil {
+				return err
+			}
+			if err := tx.Invoices.Create(ctx, invoice); err != nil {
+				return err
+			}
+			return record(ctx, c, tx, models.AuditCreate, models.AuditInvoice, invoice.Invoice_id, nil, invoice)
+		})
+		if errors.Is(insertErr, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
 		if insertErr != nil {
 			// msg := fmt.Sprintf("Invoice item was not created")
 			msg := "Invoice item was not created"
@@ -162,18 +184,29 @@ func CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		ctl.publish(ctx, c, events.InvoiceCreatedEvent{Invoice: invoice})
+		if paid(invoice) {
+			ctl.publish(ctx, c, events.InvoicePaidEvent{Invoice: invoice})
+		}
+		setETag(c, invoice.Version)
+		c.JSON(http.StatusOK, viewerOf(c).invoice(invoice))
 
 	}
 }
 
-func UpdateInvoice() gin.HandlerFunc {
+func (ctl *Controller) UpdateInvoice() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 
 		var invoice models.Invoice
 		invoiceId := c.Param("invoice_id")
+		version, err := ifMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		var updateObj primitive.D
 
 		if err := c.BindJSON(&invoice); err != nil {
@@ -181,8 +214,6 @@ func UpdateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		filter := bson.M{"invoice_id": invoiceId}
-
 		if invoice.Payment_method != nil {
 			updateObj = append(updateObj, bson.E{Key: "payment_method", Value: invoice.Payment_method})
 		}
@@ -193,12 +224,6 @@ func UpdateInvoice() gin.HandlerFunc {
 		invoice.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: invoice.Updated_at})
 
-		upsert := true
-
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
-
 		//Memory Reference: By using &, you're not copying the string value "PENDING" into Payment_status. Instead, you're storing a reference to it.
 		//Dynamic Changes: If you change the value of status later, Payment_status will still point to it and reflect the change.
 
@@ -207,22 +232,95 @@ func UpdateInvoice() gin.HandlerFunc {
 			invoice.Payment_status = &status
 		}
 
-		result, err := invoiceCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
-
+		var previous, result models.Invoice
+		err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			if previous, err = tx.Invoices.FindByID(ctx, invoiceId); err != nil {
+				return err
+			}
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditInvoice, invoiceId, tx.Invoices.FindByIDWithDeleted, func() error {
+				return tx.Invoices.Update(ctx, invoiceId, updateObj, version)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrConflict) {
+			current, _ := ctl.Store.Invoices.FindByID(ctx, invoiceId)
+			setETag(c, current.Version)
+			c.JSON(http.StatusConflict, gin.H{"error": "the invoice was changed by someone else, apply your change to the current version and retry", "current": viewerOf(c).invoice(current)})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		if paid(result) && !paid(previous) {
+			ctl.publish(ctx, c, events.InvoicePaidEvent{Invoice: result})
+		}
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).invoice(result))
+	}
+}
+
+// DeleteInvoice soft deletes an invoice. Its order counts as unpaid again.
+func (ctl *Controller) DeleteInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		invoiceId := c.Param("invoice_id")
+
+		var result models.Invoice
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditDelete, models.AuditInvoice, invoiceId, tx.Invoices.FindByIDWithDeleted, func() error {
+				return tx.Invoices.Delete(ctx, invoiceId, deletion(c))
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while deleting the invoice"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).invoice(result))
+	}
+}
+
+// RestoreInvoice brings back a deleted invoice.
+func (ctl *Controller) RestoreInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
+		invoiceId := c.Param("invoice_id")
 
-		c.JSON(http.StatusOK, result)
+		var result models.Invoice
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditRestore, models.AuditInvoice, invoiceId, tx.Invoices.FindByIDWithDeleted, func() error {
+				return tx.Invoices.Restore(ctx, invoiceId)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invoice was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while restoring the invoice"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).invoice(result))
 	}
 }
+
+func paid(invoice models.Invoice) bool {
+	return invoice.Payment_status != nil && *invoice.Payment_status == "PAID"
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"net/http"
+	helper "restaurant-management-system/helpers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS serves the public keys of the tokens, so other services can verify them without the secret key. Clients
+// may cache it for 15 minutes; a new key is published an hour before it signs anything.
+func (ctl *Controller) JWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=900")
+		c.JSON(http.StatusOK, gin.H{"keys": helper.JWKS()})
+	}
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Failed logins are counted per account and per client IP. Past its free attempts each further failure locks the
+// account or IP for a while, twice as long as the failure before, so guessing a password gets slower and slower
+// while a user who mistyped a few times is not held up. An account is counted by the email sent, whether or not it
+// belongs to a user, so the lockout does not tell which emails have accounts. Neither does the answer: an unknown
+// email is refused with the same 401 as a wrong password, after comparing the password to a dummy hash, which takes
+// as long as comparing it to the hash of a user.
+
+// dummyPassword is the hash the password of a login for an unknown email is compared to. It is made once, at the
+// bcrypt cost of the configuration.
+var dummyPassword struct {
+	once sync.Once
+	hash string
+}
+
+func (ctl *Controller) dummyPasswordHash() string {
+	dummyPassword.once.Do(func() {
+		dummyPassword.hash = HashPassword(primitive.NewObjectID().Hex(), ctl.Config.BcryptCost)
+	})
+	return dummyPassword.hash
+}
+
+// loginLimit is one throttle a login is counted against and the number of failures it allows.
+type loginLimit struct {
+	key      string
+	attempts int
+}
+
+func (ctl *Controller) loginLimits(email string, ip string) []loginLimit {
+	return []loginLimit{
+		{key: accountThrottleKey(email), attempts: ctl.Config.LoginAccountAttempts},
+		{key: "ip:" + ip, attempts: ctl.Config.LoginIPAttempts},
+	}
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+// loginLockedFor returns how much longer any of limits refuses logins, or zero.
+func (ctl *Controller) loginLockedFor(ctx context.Context, limits []loginLimit) (time.Duration, error) {
+	var longest time.Duration
+	now := time.Now()
+	for _, limit := range limits {
+		throttle, err := ctl.Store.LoginThrottles.Find(ctx, limit.key)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		longest = max(longest, ctl.lockRemaining(throttle, limit.attempts, now))
+	}
+	return longest, nil
+}
+
+// lockRemaining is what is left at now of the lock that the last failure of throttle started. The first failure
+// past attempts locks for LoginBackoff and every one after it doubles that, up to LoginLockout.
+func (ctl *Controller) lockRemaining(throttle models.LoginThrottle, attempts int, now time.Time) time.Duration {
+	excess := throttle.Failures - attempts
+	if excess <= 0 {
+		return 0
+	}
+	lock := ctl.Config.LoginLockout.Duration
+	// Doubling more than 31 times would overflow for any backoff that is still below the lockout.
+	if excess <= 31 {
+		if backoff := ctl.Config.LoginBackoff.Duration << (excess - 1); backoff > 0 && backoff < lock {
+			lock = backoff
+		}
+	}
+	return max(throttle.Last_failure.Add(lock).Sub(now), 0)
+}
+
+// loginFailed counts a failed login against every one of limits. Each count is atomic on its own; they are not made
+// in one transaction, where a lost race to create a count would abort the transaction instead of being retried.
+func (ctl *Controller) loginFailed(ctx context.Context, limits []loginLimit) error {
+	now := time.Now()
+	for _, limit := range limits {
+		if _, err := ctl.Store.LoginThrottles.Fail(ctx, limit.key, now, ctl.Config.LoginLockout.Duration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordLoginAttempt adds the attempt to the record security reviews read. A login is not refused because its
+// record could not be written; the error is logged.
+func (ctl *Controller) recordLoginAttempt(ctx context.Context, c *gin.Context, email string, user *models.User, outcome string) {
+	attempt := models.LoginAttempt{
+		ID:         primitive.NewObjectID(),
+		Email:      strings.ToLower(email),
+		Ip:         c.ClientIP(),
+		User_agent: c.Request.UserAgent(),
+		Outcome:    outcome,
+		At:         time.Now(),
+	}
+	attempt.Login_attempt_id = attempt.ID.Hex()
+	if user != nil {
+		attempt.User_id = &user.User_id
+	}
+	if err := ctl.Store.LoginAttempts.Create(ctx, attempt); err != nil {
+		log.Printf("recording the login attempt of %s failed: %v", attempt.Email, err)
+	}
+}
+
+// tooManyLogins answers a login refused by a lock with 429 and the seconds until it may be tried again.
+func tooManyLogins(c *gin.Context, lockedFor time.Duration) {
+	seconds := strconv.Itoa(int(math.Ceil(lockedFor.Seconds())))
+	c.Header("Retry-After", seconds)
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed logins, try again in " + seconds + " seconds"})
+}
+
+// UnlockUser forgets the failed logins of a user's account, so the user can log in again right away. Failures
+// counted against the IP addresses they came from stay.
+func (ctl *Controller) UnlockUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		userId := c.Param("user_id")
+
+		user, err := ctl.Store.Users.FindByID(ctx, userId)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the user"})
+			return
+		}
+
+		if err := ctl.Store.LoginThrottles.Reset(ctx, accountThrottleKey(*user.Email)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while unlocking the user"})
+			return
+		}
+		log.Printf("user %s unlocked the logins of user %s", c.GetString("uid"), userId)
+		c.JSON(http.StatusOK, gin.H{"message": "the user can log in again"})
+	}
+}
+
+// UnlockIP forgets the failed logins counted against a client IP address.
+func (ctl *Controller) UnlockIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Ip string `json:"ip" binding:"required,ip"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := ctl.Store.LoginThrottles.Reset(ctx, "ip:"+request.Ip); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while unlocking the address"})
+			return
+		}
+		log.Printf("user %s unlocked the logins from %s", c.GetString("uid"), request.Ip)
+		c.JSON(http.StatusOK, gin.H{"message": "logins from " + request.Ip + " are allowed again"})
+	}
+}
+
+// GetLoginAttempts lists login attempts, newest first. The query can be narrowed with email, user_id, ip, outcome
+// and a from/to time range in RFC 3339, and is paged like every listing.
+func (ctl *Controller) GetLoginAttempts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+
+		filter := store.LoginAttemptFilter{
+			Email:   strings.ToLower(c.Query("email")),
+			User_id: c.Query("user_id"),
+			Ip:      c.Query("ip"),
+			Outcome: c.Query("outcome"),
+		}
+		for param, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+			if c.Query(param) == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, c.Query(param))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time, like 2024-10-01T00:00:00Z"})
+				return
+			}
+			*bound = t
+		}
+
+		page, ok := pageRequest(c)
+		if !ok {
+			return
+		}
+		attempts, err := ctl.Store.LoginAttempts.List(ctx, filter, page)
+		if !listed(c, err, "error occured while fetching the login attempts") {
+			return
+		}
+		c.JSON(http.StatusOK, pageView(attempts, asIs))
+	}
+}
@@ -2,56 +2,61 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
-	"restaurant-management-system/database"
 	"restaurant-management-system/models"
+	"restaurant-management-system/store"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var menuCollection *mongo.Collection = database.OpenCollection(database.Client, "menu")
-
-func GetMenus() gin.HandlerFunc {
+func (ctl *Controller) GetMenus() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := menuCollection.Find(context.TODO(), bson.M{})
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listening the menu it"})
+		page, ok := pageRequest(c)
+		if !ok {
+			return
 		}
-		var allMenus []bson.M
-		if err = result.All(ctx, &allMenus); err != nil {
-			log.Fatal(err)
+		allMenus, err := ctl.Store.Menus.List(ctx, includeDeleted(c), page)
+		if !listed(c, err, "error occured while listening the menu it") {
+			return
 		}
-		c.JSON(http.StatusOK, allMenus)
+		c.JSON(http.StatusOK, pageView(allMenus, viewerOf(c).menu))
 	}
 }
 
-func GetMenu() gin.HandlerFunc {
+func (ctl *Controller) GetMenu() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		menuId := c.Param("menu_id")
-		var menu models.Menu
 
-		err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
+		findMenu := ctl.Store.Menus.FindByID
+		if includeDeleted(c) {
+			findMenu = ctl.Store.Menus.FindByIDWithDeleted
+		}
+		menu, err := findMenu(ctx, menuId)
 		defer cancel()
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the menu"})
+			return
 		}
-		c.JSON(http.StatusOK, menu)
+		setETag(c, menu.Version)
+		c.JSON(http.StatusOK, viewerOf(c).menu(menu))
 	}
 }
 
-func CreateMenu() gin.HandlerFunc {
+func (ctl *Controller) CreateMenu() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var menu models.Menu
 		var validate = validator.New()
@@ -71,24 +76,27 @@ func CreateMenu() gin.HandlerFunc {
 		menu.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		menu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		menu.ID = primitive.NewObjectID()
+		menu.Version = 1
 		// this line converts the newly generated ObjectID (which is used as the primary key for the food item in MongoDB) into a hexadecimal string representation.
 		menu.Menu_id = menu.ID.Hex()
 		//menu.Category = menu.Category
 
-		result, err := menuCollection.InsertOne(ctx, menu)
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			if err := tx.Menus.Create(ctx, menu); err != nil {
+				return err
+			}
+			return record(ctx, c, tx, models.AuditCreate, models.AuditMenu, menu.Menu_id, nil, menu)
+		})
 
 		if err != nil {
 
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "food item was not created"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "menu was not created"})
 
 			return
 		}
 
-		defer cancel()
-
-		c.JSON(http.StatusOK, result)
-
-		defer cancel()
+		setETag(c, menu.Version)
+		c.JSON(http.StatusOK, viewerOf(c).menu(menu))
 
 	}
 }
@@ -101,9 +109,9 @@ func inTimeSpan(start, end time.Time) bool {
 	return start.After(time.Now()) && end.After(start)
 }
 
-func UpdateMenu() gin.HandlerFunc {
+func (ctl *Controller) UpdateMenu() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		var menu models.Menu
 		defer cancel()
 
@@ -114,7 +122,11 @@ func UpdateMenu() gin.HandlerFunc {
 
 		menuId := c.Param("menu_id")
 
-		filter := bson.M{"menu_id": menuId}
+		version, err := ifMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		var updateObj primitive.D
 
@@ -139,34 +151,107 @@ func UpdateMenu() gin.HandlerFunc {
 			}
 
 			if menu.Category != "" {
-				updateObj = append(updateObj, bson.E{Key: "name", Value: menu.Category})
+				updateObj = append(updateObj, bson.E{Key: "category", Value: menu.Category})
 			}
 
 			menu.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			updateObj = append(updateObj, bson.E{Key: "updated_at", Value: menu.Updated_at})
 
-			upsert := true
-
-			opt := options.UpdateOptions{
-				Upsert: &upsert,
+			var result models.Menu
+			err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+				result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditMenu, menuId, tx.Menus.FindByIDWithDeleted, func() error {
+					return tx.Menus.Update(ctx, menuId, updateObj, version)
+				})
+				return err
+			})
+			if errors.Is(err, store.ErrConflict) {
+				current, _ := ctl.Store.Menus.FindByID(ctx, menuId)
+				setETag(c, current.Version)
+				c.JSON(http.StatusConflict, gin.H{"error": "the menu was changed by someone else, apply your change to the current version and retry", "current": viewerOf(c).menu(current)})
+				return
+			}
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+				return
 			}
-
-			result, err := menuCollection.UpdateOne(
-				ctx, filter, bson.D{
-					{Key: "$set", Value: updateObj},
-				},
-				&opt,
-			)
-
 			if err != nil {
 				msg := "Menu Updated Failed"
 				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+				return
+			}
+
+			setETag(c, result.Version)
+			c.JSON(http.StatusOK, viewerOf(c).menu(result))
+		}
 
+	}
+}
+
+// DeleteMenu soft deletes a menu. A menu that still has foods is refused with a 409: delete or move the foods first.
+func (ctl *Controller) DeleteMenu() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		menuId := c.Param("menu_id")
+
+		d := deletion(c)
+		var result models.Menu
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			foods, err := tx.Foods.CountByMenu(ctx, menuId)
+			if err != nil {
+				return err
+			}
+			if foods > 0 {
+				return conflict("the menu still has foods, delete them or move them to another menu first")
 			}
+			result, err = audited(ctx, c, tx, models.AuditDelete, models.AuditMenu, menuId, tx.Menus.FindByIDWithDeleted, func() error {
+				return tx.Menus.Delete(ctx, menuId, d)
+			})
+			return err
+		})
+		var refused conflict
+		if errors.As(err, &refused) {
+			c.JSON(http.StatusConflict, gin.H{"error": refused.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while deleting the menu"})
+			return
+		}
 
-			defer cancel()
-			c.JSON(http.StatusOK, result)
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).menu(result))
+	}
+}
+
+// RestoreMenu brings back a deleted menu.
+func (ctl *Controller) RestoreMenu() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		menuId := c.Param("menu_id")
+
+		var result models.Menu
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditRestore, models.AuditMenu, menuId, tx.Menus.FindByIDWithDeleted, func() error {
+				return tx.Menus.Restore(ctx, menuId)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "menu was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while restoring the menu"})
+			return
 		}
 
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).menu(result))
 	}
 }
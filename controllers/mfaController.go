@@ -0,0 +1,443 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	helper "restaurant-management-system/helpers"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Two-factor authentication asks for a code from a TOTP authenticator app after the password. Enrolling stores a
+// pending secret, which only becomes the secret of the user once a code from it was confirmed, so a user cannot lock
+// themselves out with an app that was set up wrong. Each confirmed enrollment comes with recovery codes, which work
+// once each in place of a code when the app is lost.
+//
+// Login answers a user with two-factor authentication with an MFA challenge instead of tokens. The challenge is a
+// short lived token that only /users/login/mfa accepts, together with a code. A user whose role requires two-factor
+// authentication but who has not enrolled gets a challenge too, and enrolls with it before the first login completes.
+
+const recoveryCodeCount = 10
+
+var errMfaCodeInvalid = errors.New("the code is incorrect or was already used")
+
+// mfaRequired reports whether the role of user may not log in without two-factor authentication.
+func (ctl *Controller) mfaRequired(user models.User) bool {
+	return user.User_type != nil && slices.Contains(ctl.Config.MfaRequiredRoles, *user.User_type)
+}
+
+// LoginMfa is the second step of a login with two-factor authentication. It takes the challenge from Login and a
+// code from the authenticator app, or a recovery code, and answers like Login. For a user who enrolled with the
+// challenge, the code confirms the enrollment and the answer also carries the recovery codes. Wrong codes count as
+// failed logins.
+func (ctl *Controller) LoginMfa() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Mfa_token string `json:"mfa_token" binding:"required"`
+			Code      string `json:"code" binding:"required"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		claims, user, ok := ctl.mfaChallenge(ctx, c, request.Mfa_token)
+		if !ok {
+			return
+		}
+
+		limits := ctl.loginLimits(*user.Email, c.ClientIP())
+		lockedFor, err := ctl.loginLockedFor(ctx, limits)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the failed logins"})
+			return
+		}
+		if lockedFor > 0 {
+			ctl.recordLoginAttempt(ctx, c, *user.Email, &user, models.LoginLocked)
+			tooManyLogins(c, lockedFor)
+			return
+		}
+
+		var updateObj primitive.D
+		var recoveryCodes []string
+		if user.Mfa_enabled {
+			updateObj, err = checkMfaCode(user, request.Code, true)
+		} else if user.Mfa_pending_secret != nil {
+			updateObj, recoveryCodes, err = confirmMfa(user, request.Code)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "two-factor authentication is required for your role, enroll at /users/login/mfa/enroll first"})
+			return
+		}
+		if errors.Is(err, errMfaCodeInvalid) {
+			ctl.recordLoginAttempt(ctx, c, *user.Email, &user, models.LoginWrongMfaCode)
+			if err := ctl.loginFailed(ctx, limits); err != nil {
+				log.Printf("counting the failed login of %s failed: %v", *user.Email, err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the code"})
+			return
+		}
+
+		// The version makes sure the same code or recovery code cannot complete two logins, and the challenge is
+		// revoked so it cannot be used again either.
+		err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			_, err := audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, user.User_id, tx.Users.FindByIDWithDeleted, func() error {
+				return tx.Users.Update(ctx, user.User_id, updateObj, user.Version)
+			})
+			if err != nil {
+				return err
+			}
+			revocation := newRevocation(time.Unix(claims.ExpiresAt, 0))
+			revocation.Jti = &claims.Id
+			return tx.Revocations.Create(ctx, revocation)
+		})
+		if errors.Is(err, store.ErrConflict) || errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errMfaCodeInvalid.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while storing the code"})
+			return
+		}
+
+		ctl.completeLogin(ctx, c, user, limits, recoveryCodes)
+	}
+}
+
+// LoginMfaEnroll starts the enrollment of a user whose role requires two-factor authentication, with the challenge
+// Login handed out. The code from the app then goes to /users/login/mfa to confirm it and complete the login.
+func (ctl *Controller) LoginMfaEnroll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Mfa_token string `json:"mfa_token" binding:"required"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		_, user, ok := ctl.mfaChallenge(ctx, c, request.Mfa_token)
+		if !ok {
+			return
+		}
+		ctl.enrollMfa(ctx, c, user)
+	}
+}
+
+// EnrollMfa starts the enrollment of the logged in user. It answers with the secret, the otpauth:// URI and a QR
+// code of it for the authenticator app. Enrolling again before confirming replaces the pending secret.
+func (ctl *Controller) EnrollMfa() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+
+		user, ok := ctl.loggedInUser(ctx, c)
+		if !ok {
+			return
+		}
+		ctl.enrollMfa(ctx, c, user)
+	}
+}
+
+// ConfirmMfa turns two-factor authentication on for the logged in user with a code from the app it was enrolled
+// in. It answers with the recovery codes, which are not shown again.
+func (ctl *Controller) ConfirmMfa() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Code string `json:"code" binding:"required"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := ctl.loggedInUser(ctx, c)
+		if !ok {
+			return
+		}
+		if user.Mfa_enabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is already on"})
+			return
+		}
+		if user.Mfa_pending_secret == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "there is no enrollment to confirm, start one at /users/mfa/enroll"})
+			return
+		}
+
+		updateObj, recoveryCodes, err := confirmMfa(user, request.Code)
+		if !ctl.updateMfa(ctx, c, user, updateObj, err) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+	}
+}
+
+// DisableMfa turns two-factor authentication off for the logged in user, who proves they still have it with a code
+// or a recovery code. It cannot be turned off while the role of the user requires it.
+func (ctl *Controller) DisableMfa() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Code string `json:"code" binding:"required"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := ctl.loggedInUser(ctx, c)
+		if !ok {
+			return
+		}
+		if ctl.mfaRequired(user) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "your role requires two-factor authentication"})
+			return
+		}
+		if !user.Mfa_enabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is not on"})
+			return
+		}
+
+		_, err := checkMfaCode(user, request.Code, true)
+		if !ctl.updateMfa(ctx, c, user, mfaCleared(), err) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication is off"})
+	}
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the logged in user, for example once most were used. It
+// takes a code from the app, not a recovery code.
+func (ctl *Controller) RegenerateRecoveryCodes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Code string `json:"code" binding:"required"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := ctl.loggedInUser(ctx, c)
+		if !ok {
+			return
+		}
+		if !user.Mfa_enabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is not on"})
+			return
+		}
+
+		updateObj, err := checkMfaCode(user, request.Code, false)
+		var recoveryCodes []string
+		if err == nil {
+			var hashes []string
+			recoveryCodes, hashes, err = helper.NewRecoveryCodes(recoveryCodeCount)
+			updateObj = append(updateObj, bson.E{Key: "mfa_recovery_codes", Value: hashes})
+		}
+		if !ctl.updateMfa(ctx, c, user, updateObj, err) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+	}
+}
+
+// ResetMfa turns two-factor authentication off for a user who lost both the app and the recovery codes. If their
+// role requires it, they enroll again at their next login.
+func (ctl *Controller) ResetMfa() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		userId := c.Param("user_id")
+
+		var result models.User
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, userId, tx.Users.FindByIDWithDeleted, func() error {
+				return tx.Users.Update(ctx, userId, mfaCleared(), 0)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while resetting two-factor authentication"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication of the user was reset"})
+	}
+}
+
+// mfaChallenge checks an MFA challenge from Login and returns it with its user. Otherwise it answers 401 and
+// returns false.
+func (ctl *Controller) mfaChallenge(ctx context.Context, c *gin.Context, token string) (*helper.SignedDetails, models.User, bool) {
+	claims, msg := helper.ValidateToken(token)
+	if msg != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+		return nil, models.User{}, false
+	}
+	if claims.Token_type != helper.MfaChallengeToken {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "this is not an MFA challenge, log in first"})
+		return nil, models.User{}, false
+	}
+	revoked, err := ctl.Store.Revocations.IsRevoked(ctx, claims.Id, claims.UId, claims.Issued())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the token"})
+		return nil, models.User{}, false
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "this MFA challenge was already used, log in again"})
+		return nil, models.User{}, false
+	}
+	user, err := ctl.Store.Users.FindByID(ctx, claims.UId)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "the user of this MFA challenge no longer exists"})
+		return nil, models.User{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the user"})
+		return nil, models.User{}, false
+	}
+	return claims, user, true
+}
+
+// enrollMfa stores a new pending secret for user and answers with what the authenticator app needs.
+func (ctl *Controller) enrollMfa(ctx context.Context, c *gin.Context, user models.User) {
+	if user.Mfa_enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "two-factor authentication is already on, turn it off before enrolling again"})
+		return
+	}
+	provisioning, err := helper.NewMfaSecret(ctl.Config.MfaIssuer, *user.Email)
+	var sealed []byte
+	if err == nil {
+		sealed, err = helper.SealSecret(helper.MfaSecretPurpose, []byte(provisioning.Secret))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while creating the secret"})
+		return
+	}
+
+	if !ctl.updateMfa(ctx, c, user, primitive.D{{Key: "mfa_pending_secret", Value: sealed}}, nil) {
+		return
+	}
+	c.JSON(http.StatusOK, provisioning)
+}
+
+// updateMfa stores updateObj on user when checkErr, the outcome of checking the code that allowed it, is nil. It
+// answers any error itself and returns false then.
+func (ctl *Controller) updateMfa(ctx context.Context, c *gin.Context, user models.User, updateObj primitive.D, checkErr error) bool {
+	if errors.Is(checkErr, errMfaCodeInvalid) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": checkErr.Error()})
+		return false
+	}
+	if checkErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the code"})
+		return false
+	}
+
+	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updatedAt})
+	var result models.User
+	err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+		result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, user.User_id, tx.Users.FindByIDWithDeleted, func() error {
+			return tx.Users.Update(ctx, user.User_id, updateObj, user.Version)
+		})
+		return err
+	})
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "the user was changed meanwhile, try again"})
+		return false
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating two-factor authentication"})
+		return false
+	}
+	setETag(c, result.Version)
+	return true
+}
+
+// checkMfaCode checks code against the confirmed secret of user and, with allowRecovery, against their unused
+// recovery codes. It returns the update that uses the code up, or errMfaCodeInvalid.
+func checkMfaCode(user models.User, code string, allowRecovery bool) (primitive.D, error) {
+	secret, err := helper.OpenSecret(helper.MfaSecretPurpose, user.Mfa_secret)
+	if err != nil {
+		return nil, err
+	}
+	if step, ok := helper.VerifyTotp(string(secret), code, user.Mfa_last_step, time.Now()); ok {
+		return primitive.D{{Key: "mfa_last_step", Value: step}}, nil
+	}
+	if allowRecovery {
+		hash := helper.HashRecoveryCode(code)
+		if i := slices.Index(user.Mfa_recovery_codes, hash); i >= 0 {
+			remaining := slices.Delete(slices.Clone(user.Mfa_recovery_codes), i, i+1)
+			return primitive.D{{Key: "mfa_recovery_codes", Value: remaining}}, nil
+		}
+	}
+	return nil, errMfaCodeInvalid
+}
+
+// confirmMfa checks code against the pending secret of user. It returns the update that makes the secret the
+// confirmed one, and the new recovery codes.
+func confirmMfa(user models.User, code string) (primitive.D, []string, error) {
+	secret, err := helper.OpenSecret(helper.MfaSecretPurpose, user.Mfa_pending_secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	step, ok := helper.VerifyTotp(string(secret), code, 0, time.Now())
+	if !ok {
+		return nil, nil, errMfaCodeInvalid
+	}
+	recoveryCodes, hashes, err := helper.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	return primitive.D{
+		{Key: "mfa_enabled", Value: true},
+		{Key: "mfa_secret", Value: user.Mfa_pending_secret},
+		{Key: "mfa_pending_secret", Value: nil},
+		{Key: "mfa_recovery_codes", Value: hashes},
+		{Key: "mfa_last_step", Value: step},
+	}, recoveryCodes, nil
+}
+
+// mfaCleared is the update that turns two-factor authentication off and forgets every secret of it.
+func mfaCleared() primitive.D {
+	return primitive.D{
+		{Key: "mfa_enabled", Value: false},
+		{Key: "mfa_secret", Value: nil},
+		{Key: "mfa_pending_secret", Value: nil},
+		{Key: "mfa_recovery_codes", Value: nil},
+		{Key: "mfa_last_step", Value: int64(0)},
+	}
+}
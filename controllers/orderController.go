@@ -2,64 +2,65 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
-	"restaurant-management-system/database"
+	"restaurant-management-system/events"
 	"restaurant-management-system/models"
+	"restaurant-management-system/store"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var orderCollection *mongo.Collection = database.OpenCollection(database.Client, "Order")
-
-func GetOrders() gin.HandlerFunc {
+func (ctl *Controller) GetOrders() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := orderCollection.Find(context.TODO(), bson.M{})
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listening the menu it"})
+		page, ok := pageRequest(c)
+		if !ok {
+			return
 		}
-
-		var allOrders []bson.M
-
-		if err = result.All(ctx, &allOrders); err != nil {
-			log.Fatal(err)
+		allOrders, err := ctl.Store.Orders.List(ctx, includeDeleted(c), page)
+		if !listed(c, err, "error occured while listening the orders") {
+			return
 		}
-
-		c.JSON(http.StatusOK, allOrders)
-
+		c.JSON(http.StatusOK, pageView(allOrders, viewerOf(c).order))
 	}
 }
 
-func GetOrder() gin.HandlerFunc {
+func (ctl *Controller) GetOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		orderId := c.Param("order_id")
-		var order models.Order
 
-		err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		findOrder := ctl.Store.Orders.FindByID
+		if includeDeleted(c) {
+			findOrder = ctl.Store.Orders.FindByIDWithDeleted
+		}
+		order, err := findOrder(ctx, orderId)
 		defer cancel()
 
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the menu"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order"})
+			return
 		}
-		c.JSON(http.StatusOK, order)
+		setETag(c, order.Version)
+		c.JSON(http.StatusOK, viewerOf(c).order(order))
 	}
 }
 
-func CreateOrder() gin.HandlerFunc {
+func (ctl *Controller) CreateOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var order models.Order
-		var table models.Table
 		var validate = validator.New()
 
 		if err := c.BindJSON(&order); err != nil {
@@ -74,8 +75,7 @@ func CreateOrder() gin.HandlerFunc {
 		}
 
 		if order.Table_ID != nil {
-			err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_ID}).Decode(&table)
-			defer cancel()
+			_, err := ctl.Store.Tables.FindByID(ctx, *order.Table_ID)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Table was not found"})
 				return
@@ -84,10 +84,16 @@ func CreateOrder() gin.HandlerFunc {
 		order.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		order.ID = primitive.NewObjectID()
+		order.Version = 1
 		// this line converts the newly generated ObjectID (which is used as the primary key for the food item in MongoDB) into a hexadecimal string representation.
 		order.Order_ID = order.ID.Hex()
 
-		result, err := menuCollection.InsertOne(ctx, order)
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			if err := tx.Orders.Create(ctx, order); err != nil {
+				return err
+			}
+			return record(ctx, c, tx, models.AuditCreate, models.AuditOrder, order.Order_ID, nil, order)
+		})
 
 		if err != nil {
 			msg := "order item was not created"
@@ -96,22 +102,25 @@ func CreateOrder() gin.HandlerFunc {
 			return
 		}
 
-		defer cancel()
-
-		c.JSON(http.StatusOK, result)
-
-		defer cancel()
+		ctl.publish(ctx, c, events.OrderCreatedEvent{Order: order, Order_items: []models.OrderItem{}})
+		setETag(c, order.Version)
+		c.JSON(http.StatusOK, viewerOf(c).order(order))
 	}
 }
 
 // only the Table_ID and UpdatedAt fields of the Order model are being updated. None of the fields from the Menu model (such as Name, Category, or Start_date) are involved in the update process within this function.
 
-func UpdateOrder() gin.HandlerFunc {
+func (ctl *Controller) UpdateOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var order models.Order
-		var table models.Table
+
+		version, err := ifMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		var updateObj primitive.D
 
@@ -121,45 +130,45 @@ func UpdateOrder() gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		filter := bson.M{"order_id": orderId}
-
 		if order.Table_ID != nil {
-			err := orderCollection.FindOne(ctx, bson.M{"table_id": order.Table_ID}).Decode(&table)
-
-			defer cancel()
+			_, err := ctl.Store.Tables.FindByID(ctx, *order.Table_ID)
 			if err != nil {
 				msg := "Table was not found"
 				c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 				return
 			}
 
-			updateObj = append(updateObj, bson.E{Key: "table", Value: order.Table_ID})
+			updateObj = append(updateObj, bson.E{Key: "table_id", Value: order.Table_ID})
 		}
 
 		order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: order.UpdatedAt})
 
-		upsert := true
-
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		var result models.Order
+		err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditOrder, orderId, tx.Orders.FindByIDWithDeleted, func() error {
+				return tx.Orders.Update(ctx, orderId, updateObj, version)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrConflict) {
+			current, _ := ctl.Store.Orders.FindByID(ctx, orderId)
+			setETag(c, current.Version)
+			c.JSON(http.StatusConflict, gin.H{"error": "the order was changed by someone else, apply your change to the current version and retry", "current": viewerOf(c).order(current)})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
 		}
-
-		result, err := orderCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
 		if err != nil {
 			msg := "Order update failed"
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
 		}
 
-		defer cancel()
-		c.JSON(http.StatusOK, result)
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).order(result))
 	}
 }
 
@@ -170,15 +179,139 @@ func UpdateOrder() gin.HandlerFunc {
 // After performing its task, the function will return a value of type string.
 // This string, as we'll see in the function body later, is the Order_ID, which serves as a unique identifier for the order.
 
-func OrderItemOrderCreator(order models.Order) string {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+// It runs inside the transaction of CreateOrderItem, so it writes through tx and reports its error instead of leaving
+// an order behind that its items never reach. The audit entry of the order is attributed to the user of c.
+func (ctl *Controller) OrderItemOrderCreator(ctx context.Context, c *gin.Context, tx *store.Store, order models.Order) (string, error) {
 	order.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.ID = primitive.NewObjectID()
+	order.Version = 1
 	order.Order_ID = order.ID.Hex()
 
-	orderCollection.InsertOne(ctx, order)
-	defer cancel()
+	if err := tx.Orders.Create(ctx, order); err != nil {
+		return "", err
+	}
+	return order.Order_ID, record(ctx, c, tx, models.AuditCreate, models.AuditOrder, order.Order_ID, nil, order)
+}
+
+// recordOrderItems records what a cascade from their order did to orderItems, which hold the items as they were
+// before it. It returns the items as they are after it.
+func recordOrderItems(ctx context.Context, c *gin.Context, tx *store.Store, action string, orderItems []models.OrderItem) ([]models.OrderItem, error) {
+	changed := []models.OrderItem{}
+	for _, before := range orderItems {
+		after, err := tx.OrderItems.FindByIDWithDeleted(ctx, before.Order_Item_Id)
+		if err != nil {
+			return nil, err
+		}
+		if err := record(ctx, c, tx, action, models.AuditOrderItem, before.Order_Item_Id, before, after); err != nil {
+			return nil, err
+		}
+		changed = append(changed, after)
+	}
+	return changed, nil
+}
+
+// DeleteOrder soft deletes an order together with its items, in one transaction and with one stamp.
+func (ctl *Controller) DeleteOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		orderId := c.Param("order_id")
 
-	return order.Order_ID
+		d := deletion(c)
+		var result models.Order
+		var deletedItems []models.OrderItem
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			orderItems, err := tx.OrderItems.ListByOrder(ctx, orderId, false)
+			if err != nil {
+				return err
+			}
+			result, err = audited(ctx, c, tx, models.AuditDelete, models.AuditOrder, orderId, tx.Orders.FindByIDWithDeleted, func() error {
+				return tx.Orders.Delete(ctx, orderId, d)
+			})
+			if err != nil {
+				return err
+			}
+			if err := tx.OrderItems.DeleteByOrder(ctx, orderId, d); err != nil {
+				return err
+			}
+			deletedItems, err = recordOrderItems(ctx, c, tx, models.AuditDelete, orderItems)
+			return err
+		})
+		var refused conflict
+		if errors.As(err, &refused) {
+			c.JSON(http.StatusConflict, gin.H{"error": refused.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while deleting the order"})
+			return
+		}
+
+		ctl.publish(ctx, c, events.OrderDeletedEvent{Order: result, Order_items: deletedItems})
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).order(result))
+	}
+}
+
+// RestoreOrder brings back a deleted order and the items that were deleted with it.
+func (ctl *Controller) RestoreOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		orderId := c.Param("order_id")
+
+		var result models.Order
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			order, err := tx.Orders.FindByIDWithDeleted(ctx, orderId)
+			if err != nil {
+				return err
+			}
+			result = order
+			if order.Deleted_at == nil {
+				return nil
+			}
+			deletedWith := []models.OrderItem{}
+			orderItems, err := tx.OrderItems.ListByOrder(ctx, orderId, true)
+			if err != nil {
+				return err
+			}
+			for _, orderItem := range orderItems {
+				if orderItem.Deleted_at != nil && orderItem.Deleted_at.Equal(*order.Deleted_at) {
+					deletedWith = append(deletedWith, orderItem)
+				}
+			}
+			result, err = audited(ctx, c, tx, models.AuditRestore, models.AuditOrder, orderId, tx.Orders.FindByIDWithDeleted, func() error {
+				return tx.Orders.Restore(ctx, orderId)
+			})
+			if err != nil {
+				return err
+			}
+			if err := tx.OrderItems.RestoreByOrder(ctx, orderId, *order.Deleted_at); err != nil {
+				return err
+			}
+			_, err = recordOrderItems(ctx, c, tx, models.AuditRestore, deletedWith)
+			return err
+		})
+		var refused conflict
+		if errors.As(err, &refused) {
+			c.JSON(http.StatusConflict, gin.H{"error": refused.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while restoring the order"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).order(result))
+	}
 }
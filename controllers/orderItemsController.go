@@ -2,18 +2,20 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
-	"restaurant-management-system/database"
+	"restaurant-management-system/events"
 	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // OrderItem Struct: This struct is designed to represent a single order item, including its attributes like quantity, unit price, food ID, and timestamps. It is focused on the details of one item.
@@ -64,240 +66,73 @@ type OrderItemPack struct {
 	Order_items []models.OrderItem
 }
 
-var orderItemCollection *mongo.Collection = database.OpenCollection(database.Client, "orderItem")
-
-func GetOrderItems() gin.HandlerFunc {
+func (ctl *Controller) GetOrderItems() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := orderItemCollection.Find(context.TODO(), bson.M{})
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order items"})
+		page, ok := pageRequest(c)
+		if !ok {
+			return
 		}
-
-		var allOrderItems []bson.M
-		if err = result.All(ctx, &allOrderItems); err != nil {
-			log.Fatal(err)
+		allOrderItems, err := ctl.Store.OrderItems.List(ctx, includeDeleted(c), page)
+		if !listed(c, err, "error occured while fetching the order items") {
+			return
 		}
-		c.JSON(http.StatusOK, allOrderItems)
+		c.JSON(http.StatusOK, pageView(allOrderItems, viewerOf(c).orderItem))
 	}
 }
 
-func GetOrderItemsByOrder() gin.HandlerFunc {
+func (ctl *Controller) GetOrderItemsByOrder() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		orderId := c.Param("order_id")
 
-		allOrderItems, err := ItemsByOrder(orderId)
+		allOrderItems, err := ctl.ItemsByOrder(orderId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items by order"})
+			return
+		}
+		details, err := orderDetails(allOrderItems)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing order items by order"})
+			return
 		}
-		c.JSON(http.StatusOK, allOrderItems)
+		c.JSON(http.StatusOK, details)
 
 	}
 }
 
-func GetOrderItem() gin.HandlerFunc {
+func (ctl *Controller) GetOrderItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		orderItemId := c.Param("order_item_id")
-		var orderItem models.OrderItem
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		orderItemId := c.Param("orderItem_id")
 
-		err := orderItemCollection.FindOne(ctx, bson.M{"orderItem_id": orderItemId}).Decode(&orderItem)
+		findOrderItem := ctl.Store.OrderItems.FindByID
+		if includeDeleted(c) {
+			findOrderItem = ctl.Store.OrderItems.FindByIDWithDeleted
+		}
+		orderItem, err := findOrderItem(ctx, orderItemId)
 		defer cancel()
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the order item"})
+			return
 		}
-		c.JSON(http.StatusOK, orderItem)
+		setETag(c, orderItem.Version)
+		c.JSON(http.StatusOK, viewerOf(c).orderItem(orderItem))
 	}
 }
 
-// this will hold the list of order items as documents retrieved from MongoDB.
-
-func ItemsByOrder(id string) (orderItems []primitive.M, err error) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	// $match: The $match is like a filter in a search. In this case, were saying, "Hey MongoDB, find documents (which are like rows in SQL databases) where the order_id equals the id that we passed into the function
-	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "order_id", Value: id}}}}
-	//Its purpose is to join two collections in MongoDB, much like how a SQL JOIN works.
-	// In this line, you're trying to get more details about the food associated with each item in the order. The order items are stored in one collection, and the food details are stored in another collection. This stage connects the two.
-	// from: "food" This tells MongoDB that the additional information you need is in the food collection
-	// localField: "food_id" This is the field in the current collection (order items) that will be used to match the documents. Here, its food_id, which links each order item to a specific food item.
-	// foreignField: "food_id"  This is the field in the other collection (food) that MongoDB will use to find matching documents. The food_id field in the food collection needs to match the food_id in the order item
-	// as: "food"  This is the name of the new field that will hold the matched data from the food collection. After the lookup, each order item will have a new field called food, which contains details about the food item (like its name, price, etc.).
-	// Before lookupStage:
-
-	// You just have:
-
-	// [
-	//   { "order_id": "123", "food_id": "001", "quantity": 2 },
-	// 	  { "order_id": "123", "food_id": "002", "quantity": 1 }
-	// ]
-
-	// This only tells you the food_id and quantity, but not much else.
-
-	// After lookupStage:
-
-	// [
-	// {
-	// "order_id": "123",
-	// "food_id": "001",
-	// "quantity": 2,
-	// "food": {
-	// 	"name": "Pizza",
-	// 	"price": 10.0,
-	// 	"food_image": "pizza.jpg"
-	// }
-	// },
-	// {
-	// "order_id": "123",
-	// "food_id": "002",
-	// "quantity": 1,
-	// "food": {
-	// "name": "Burger",
-	// "price": 5.0,
-	// "food_image": "burger.jpg"
-	// }
-	// }
-	// ]
-
-	lookupStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "food"}, {Key: "localField", Value: "food_id"}, {Key: "foreignField", Value: "food_id"}, {Key: "as", Value: "food"}}}}
-	// $unwind takes an array from a document and splits it into separate documents for each item in that array.
-	// It helps make it easier to work with data that has arrays.
-	//  path
-
-	// What It Means: The path specifies which field you want to unwind. In this case, its "$food".
-	// How It Works: By saying path: "$food", you are telling MongoDB that you want to take the food array from each order document and create separate documents for each food item.
-
-	// Before unwindStage:
-
-	// {
-	// "order_id": 1,
-	// "customer_name": "Alice",
-	// "food_ids": ["f1", "f2"],
-	// "food_items": [
-	// { "food_id": "f1", "name": "Pizza" },
-	// { "food_id": "f2", "name": "Burger" }
-	// ]
-	// }
-
-	// After unwindStage:
-
-	// {
-	// "order_id": 1,
-	// "customer_name": "Alice",
-	// "food_ids": ["f1", "f2"],
-	// "food_items": { "food_id": "f1", "name": "Pizza" }
-	// }
-
-	// {
-	// "order_id": 1,
-	// "customer_name": "Alice",
-	// "food_ids": ["f1", "f2"],
-	// "food_items": { "food_id": "f2", "name": "Burger" }
-	// }
-
-	// The primary purpose of the $unwind stage in MongoDB is to deconstruct an array field from the input documents. This means that if you have a document containing an array, $unwind will create a new document for each element in that array, effectively "flattening" it
-
-	// Simply ANTI-ARRAY :- Benefit: This allows for easier manipulation and querying of individual array elements in MongoDB.
-
-	//  preserveNullAndEmptyArrays: true ensures that documents with null values or empty arrays for the field being unwound are not excluded from the results. Instead, they will appear in the output, but the unwound field will have a null value.
-
-	unwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$food"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
-
-	// Seek Mock Data Example if confused
-	// Purpose of the as Key:
-
-	// The as key in the $lookup stage specifies the name of the new field that will be created in the resulting documents. In your case, this is the "order" field.
-	// The documents from the order collection that match the join condition will be included in this new field as an array.
-
-	// Why the Food Name Is Not Included:
-
-	// The $lookup only brings in data from the order collection based on the matching order_id.
-	// It does not include fields from the food collection in the result of the lookup because that part is handled separately in your pipeline.
-
-	// We are concerned with key as it's value
-	lookupOrderStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "order"}, {Key: "localField", Value: "order_id"}, {Key: "foreignField", Value: "order_id"}, {Key: "as", Value: "order"}}}}
-	unwindOrderStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$order"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
-
-	lookupTableStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "table"}, {Key: "localField", Value: "order.table_id"}, {Key: "foreignField", Value: "table_id"}, {Key: "as", Value: "table"}}}}
-	unwindTableStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$table"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
-
-	// The $project stage is crucial for shaping the final output of your aggregation pipeline. It allows you to control which fields are included, excluded, or renamed in the output documents, helping you create a cleaner and more relevant data structure for further processing or displaying in your application.
-
-	projectStage := bson.D{
-		{Key: "$project", Value: bson.D{
-			// This line excludes the id field from the output documents. Setting a field to 0 in the $project stage means it won't appear in the resulting documents.
-
-			// FIX THE STATIC VALUES THEY ARE NOT SAFE
-			{Key: "id", Value: 0},
-			{Key: "amount", Value: "$food_price"},
-			{Key: "total_count", Value: 1},
-			{Key: "food_name", Value: "$food.name"},
-			{Key: "food_image", Value: "$food.food_image"},
-			{Key: "table_number", Value: "$table.table_number"},
-			{Key: "table_id", Value: "$table.table_id"},
-			{Key: "order_id", Value: "$order.order_id"},
-			{Key: "price", Value: "$food.price"},
-			{Key: "quantity", Value: 1},
-		}}}
-
-	// The aggregation groups the documents based on both the order_id and table_number. This means that if multiple items belong to the same order (i.e., they have the same order_id) and were placed at the same table (i.e., they have the same table_number), they will be grouped together in a single result.
-
-	groupStage := bson.D{{Key: "$group", Value: bson.D{
-		{Key: "_id", Value: bson.D{
-			{Key: "order_id", Value: "$order_id"},
-			{Key: "table_number", Value: "$table_number"},
-		}},
-		{Key: "table_id", Value: bson.D{{Key: "$first", Value: "$table_id"}}},
-		{Key: "order_id", Value: bson.D{{Key: "$first", Value: "$order_id"}}},
-		{Key: "table_number", Value: bson.D{{Key: "$first", Value: "$table_number"}}},
-		{Key: "total_count", Value: bson.D{{Key: "$sum", Value: "$quantity"}}},
-		{Key: "order_items", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}},
-		{Key: "payment", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
-		{Key: "total_amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
-	}}}
-
-	//  In this code, the projectStage2 is applying a projection on the results that were produced by the previous groupStage.
-
-	// The earlier stage typically comes early in the pipeline, before the data is grouped. It helps to reduce the amount of data that is processed by focusing only on the fields you actually need.
-
-	// This second projection stage is used to restructure the grouped results and prepare the final output
-
-	projectStage2 := bson.D{
-		{Key: "$project", Value: bson.D{
-			{Key: "id", Value: 0},
-			{Key: "payment_due", Value: 1},
-			{Key: "table_number", Value: "$_id.table_number"},
-			{Key: "order_items", Value: 1},
-			{Key: "total_count", Value: 1},
-		}}}
-
-	//  the MongoDB aggregation pipeline is executed in sequence, with each stage processing the output of the previous one. This means the order of the stages is very important because each stage depends on the results from the stages before it.
-	cursor, err := orderItemCollection.Aggregate(ctx, mongo.Pipeline{
-		matchStage,
-		lookupStage,
-		unwindStage,
-		lookupOrderStage,
-		unwindOrderStage,
-		lookupTableStage,
-		unwindTableStage,
-		projectStage,
-		groupStage,
-		projectStage2,
-	})
+// ItemsByOrder returns the items of an order joined with their food and table details. The join itself lives in the
+// order item repository, see store.OrderItemRepository.ItemsByOrder.
+func (ctl *Controller) ItemsByOrder(id string) (orderItems []primitive.M, err error) {
+	var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 	defer cancel()
-	if err != nil {
-		panic(err)
-	}
-
-	if err = cursor.All(ctx, &orderItems); err != nil {
-		panic(err)
-	}
-
-	return orderItems, nil
 
+	return ctl.Store.OrderItems.ItemsByOrder(ctx, id)
 }
 
 // Order and OrderItem are two different types of documents in the database.
@@ -316,9 +151,9 @@ func ItemsByOrder(id string) (orderItems []primitive.M, err error) {
 // │   │   ├── Quantity: 2
 // │   │   ├── Unit_Price: 2.99
 
-func CreateOrderItem() gin.HandlerFunc {
+func (ctl *Controller) CreateOrderItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var order models.Order
 		var orderItemPack OrderItemPack
@@ -332,11 +167,8 @@ func CreateOrderItem() gin.HandlerFunc {
 		order.Order_Date, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
 		// orderItemsToBeInserted: This is the name of the variable being declared. It's intended to hold a collection of order items that will later be inserted into a database (MongoDB in this case).
-		orderItemsToBeInserted := []interface{}{}
+		orderItemsToBeInserted := []models.OrderItem{}
 		order.Table_ID = orderItemPack.Table_id
-		// The line order_id := OrderItemOrderCreator(order) creates a new order and retrieves its unique ID, which is crucial for associating the order items with the correct order.
-		// This step establishes the link between the Order and its OrderItems, allowing the application to maintain the integrity of data and relationships in the database
-		order_id := OrderItemOrderCreator(order)
 
 		// I take out a car from the box. I check if its okay. I put a sticker on it with my name. Then I put it in a special spot to keep it safe.
 
@@ -344,50 +176,140 @@ func CreateOrderItem() gin.HandlerFunc {
 		// The loop is essential because it allows you to handle multiple order items efficiently and cleanly.
 		// It saves time, reduces mistakes, and keeps the code neat and organized.
 		// Rememeber this it is for multiplicity of order items.if it was single item we dont need loop.
+		// Every item is validated before anything is written, so a bad item never leaves an order behind. The order id
+		// is only known once the order is created, so it is left out of the validation.
 		for _, orderItem := range orderItemPack.Order_items {
-			orderItem.Order_ID = order_id
-
-			validationErr := validate.Struct(orderItem)
+			validationErr := validate.StructExcept(orderItem, "Order_ID")
 			if validationErr != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 				return
 			}
 
 			orderItem.ID = primitive.NewObjectID()
+			orderItem.Version = 1
 			orderItem.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			orderItem.Order_Item_Id = orderItem.ID.Hex()
-			var num = toFixed(*orderItem.Unit_Price, 2)
-			orderItem.Unit_Price = &num
+
+			// The price comes from the food, never from the client: the quantity names the variant it is sold in.
+			food, err := ctl.Store.Foods.FindByID(ctx, *orderItem.Food_id)
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("food %s was not found", *orderItem.Food_id)})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the food item"})
+				return
+			}
+			variant, price, ok := variantPrice(food, *orderItem.Quantity)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not sold as %s, choose one of %v", *food.Name, *orderItem.Quantity, variantNames(food))})
+				return
+			}
+			orderItem.Quantity = &variant
+			orderItem.Unit_Price = &price
+			if orderItem.Modifiers, err = chooseModifiers(food, orderItem.Modifiers); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 			orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
 		}
 
-		insertedOrderItems, err := orderItemCollection.InsertMany(ctx, orderItemsToBeInserted)
+		// The order and its items are written in one transaction: if inserting the items fails, the order is rolled
+		// back with them.
+		var created models.Order
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			// The line order_id, err := ctl.OrderItemOrderCreator(ctx, c, tx, order) creates a new order and retrieves its unique ID, which is crucial for associating the order items with the correct order.
+			// This step establishes the link between the Order and its OrderItems, allowing the application to maintain the integrity of data and relationships in the database
+			order_id, err := ctl.OrderItemOrderCreator(ctx, c, tx, order)
+			if err != nil {
+				return err
+			}
+			for i := range orderItemsToBeInserted {
+				orderItemsToBeInserted[i].Order_ID = order_id
+			}
+			if err := tx.OrderItems.CreateMany(ctx, orderItemsToBeInserted); err != nil {
+				return err
+			}
+			for _, orderItem := range orderItemsToBeInserted {
+				if err := record(ctx, c, tx, models.AuditCreate, models.AuditOrderItem, orderItem.Order_Item_Id, nil, orderItem); err != nil {
+					return err
+				}
+			}
+			created, err = tx.Orders.FindByID(ctx, order_id)
+			return err
+		})
 		if err != nil {
-			log.Fatal(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order items were not created"})
+			return
 		}
-		defer cancel()
-		c.JSON(http.StatusOK, insertedOrderItems)
+		ctl.publish(ctx, c, events.OrderCreatedEvent{Order: created, Order_items: orderItemsToBeInserted})
+		c.JSON(http.StatusOK, viewAll(orderItemsToBeInserted, viewerOf(c).orderItem))
+	}
+}
+
+// chooseModifiers checks the modifiers chosen for an item of food against its modifier groups and returns them with
+// their prices. Every group gets between its min and max modifiers, none of them twice. Names are matched regardless
+// of case, like the variants.
+func chooseModifiers(food models.Food, chosen []models.OrderItemModifier) ([]models.OrderItemModifier, error) {
+	priced := []models.OrderItemModifier{}
+	counts := map[string]int{}
+	for _, choice := range chosen {
+		group := slices.IndexFunc(food.Modifier_groups, func(group models.ModifierGroup) bool {
+			return strings.EqualFold(group.Name, choice.Group)
+		})
+		if group < 0 {
+			return nil, fmt.Errorf("%s has no modifier group %s", *food.Name, choice.Group)
+		}
+		modifiers := food.Modifier_groups[group].Modifiers
+		modifier := slices.IndexFunc(modifiers, func(modifier models.Modifier) bool {
+			return strings.EqualFold(modifier.Name, choice.Name)
+		})
+		if modifier < 0 {
+			return nil, fmt.Errorf("%s is not in %s for %s", choice.Name, choice.Group, *food.Name)
+		}
+		// The modifier is stored the way the food spells it, so it is counted and compared as one.
+		choice = models.OrderItemModifier{Group: food.Modifier_groups[group].Name, Name: modifiers[modifier].Name, Price: modifiers[modifier].Price}
+		if slices.ContainsFunc(priced, func(other models.OrderItemModifier) bool {
+			return other.Group == choice.Group && other.Name == choice.Name
+		}) {
+			return nil, fmt.Errorf("%s is chosen twice from %s", choice.Name, choice.Group)
+		}
+		counts[choice.Group]++
+		priced = append(priced, choice)
+	}
+	for _, group := range food.Modifier_groups {
+		if counts[group.Name] >= group.Min && counts[group.Name] <= group.Max {
+			continue
+		}
+		if group.Min == group.Max {
+			return nil, fmt.Errorf("choose %d from %s for %s", group.Min, group.Name, *food.Name)
+		}
+		return nil, fmt.Errorf("choose %d to %d from %s for %s", group.Min, group.Max, group.Name, *food.Name)
 	}
+	return priced, nil
 }
 
-func UpdateOrderItem() gin.HandlerFunc {
+func (ctl *Controller) UpdateOrderItem() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var orderItem models.OrderItem
-		orderItemId := c.Param("order_item_id")
+		orderItemId := c.Param("orderItem_id")
 
-		filter := bson.M{"orderItem_id": orderItemId}
+		if err := c.BindJSON(&orderItem); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		version, err := ifMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		var updateObj primitive.D
 
-		if orderItem.Unit_Price != nil {
-			updateObj = append(updateObj, bson.E{Key: "unit_price", Value: orderItem.Unit_Price})
-		}
-		if orderItem.Quantity != nil {
-			updateObj = append(updateObj, bson.E{Key: "quantity", Value: orderItem.Quantity})
-		}
 		if orderItem.Food_id != nil {
 			updateObj = append(updateObj, bson.E{Key: "food_id", Value: orderItem.Food_id})
 		}
@@ -395,27 +317,140 @@ func UpdateOrderItem() gin.HandlerFunc {
 		orderItem.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: orderItem.UpdatedAt})
 
-		upsert := true
-
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		var result models.OrderItem
+		err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			// A new food, variant or choice of modifiers is priced again, in the same transaction that writes it. The
+			// modifiers the item has are checked against a new food, which may not offer them.
+			if orderItem.Quantity != nil || orderItem.Food_id != nil || orderItem.Modifiers != nil {
+				previous, err := tx.OrderItems.FindByID(ctx, orderItemId)
+				if err != nil {
+					return err
+				}
+				foodId, variant := previous.Food_id, previous.Quantity
+				if orderItem.Food_id != nil {
+					foodId = orderItem.Food_id
+				}
+				if orderItem.Quantity != nil {
+					variant = orderItem.Quantity
+				}
+				if foodId == nil || variant == nil {
+					return invalid("the order item has no food or quantity to price")
+				}
+				food, err := tx.Foods.FindByID(ctx, *foodId)
+				if errors.Is(err, store.ErrNotFound) {
+					return invalid(fmt.Sprintf("food %s was not found", *foodId))
+				} else if err != nil {
+					return err
+				}
+				name, price, ok := variantPrice(food, *variant)
+				if !ok {
+					return invalid(fmt.Sprintf("%s is not sold as %s, choose one of %v", *food.Name, *variant, variantNames(food)))
+				}
+				chosen := previous.Modifiers
+				if orderItem.Modifiers != nil {
+					chosen = orderItem.Modifiers
+				}
+				if chosen, err = chooseModifiers(food, chosen); err != nil {
+					return invalid(err.Error())
+				}
+				updateObj = append(updateObj, bson.E{Key: "quantity", Value: name}, bson.E{Key: "unit_price", Value: price}, bson.E{Key: "modifiers", Value: chosen})
+			}
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditOrderItem, orderItemId, tx.OrderItems.FindByIDWithDeleted, func() error {
+				return tx.OrderItems.Update(ctx, orderItemId, updateObj, version)
+			})
+			return err
+		})
+		var rejected invalid
+		if errors.As(err, &rejected) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": rejected.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrConflict) {
+			current, _ := ctl.Store.OrderItems.FindByID(ctx, orderItemId)
+			setETag(c, current.Version)
+			c.JSON(http.StatusConflict, gin.H{"error": "the order item was changed by someone else, apply your change to the current version and retry", "current": viewerOf(c).orderItem(current)})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the order item"})
+			return
 		}
 
-		result, err := orderItemCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).orderItem(result))
+	}
+}
 
+// DeleteOrderItem soft deletes a single item of an order.
+func (ctl *Controller) DeleteOrderItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		orderItemId := c.Param("orderItem_id")
+
+		var result models.OrderItem
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditDelete, models.AuditOrderItem, orderItemId, tx.OrderItems.FindByIDWithDeleted, func() error {
+				return tx.OrderItems.Delete(ctx, orderItemId, deletion(c))
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the order item"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while deleting the order item"})
 			return
 		}
 
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).orderItem(result))
+	}
+}
+
+// RestoreOrderItem brings back a deleted order item. The items of a deleted order come back with RestoreOrder.
+func (ctl *Controller) RestoreOrderItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-		c.JSON(http.StatusOK, result)
+		orderItemId := c.Param("orderItem_id")
+
+		var result models.OrderItem
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			orderItem, err := tx.OrderItems.FindByIDWithDeleted(ctx, orderItemId)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Orders.FindByID(ctx, orderItem.Order_ID); errors.Is(err, store.ErrNotFound) {
+				return conflict("the order of this item is deleted, restore the order instead")
+			} else if err != nil {
+				return err
+			}
+			result, err = audited(ctx, c, tx, models.AuditRestore, models.AuditOrderItem, orderItemId, tx.OrderItems.FindByIDWithDeleted, func() error {
+				return tx.OrderItems.Restore(ctx, orderItemId)
+			})
+			return err
+		})
+		var refused conflict
+		if errors.As(err, &refused) {
+			c.JSON(http.StatusConflict, gin.H{"error": refused.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while restoring the order item"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).orderItem(result))
 	}
 }
@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	helper "restaurant-management-system/helpers"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The profile routes act on the user who is logged in, whatever their role. The role, the deactivation and the
+// other fields an admin manages cannot be changed through them.
+
+// GetProfile returns the logged in user.
+func (ctl *Controller) GetProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+
+		user, ok := ctl.loggedInUser(ctx, c)
+		if !ok {
+			return
+		}
+		setETag(c, user.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(user))
+	}
+}
+
+// UpdateProfile changes the name, email or phone of the logged in user. A new email address needs the current
+// password, because whoever controls the address can reset the password, and it has to be verified again.
+func (ctl *Controller) UpdateProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			First_name       *string `json:"first_name" binding:"omitempty,min=2,max=100"`
+			Last_name        *string `json:"last_name" binding:"omitempty,min=2,max=100"`
+			Email            *string `json:"email" binding:"omitempty,email"`
+			Phone            *string `json:"phone" binding:"omitempty,min=1"`
+			Current_password *string `json:"current_password"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		version, err := ifMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := ctl.loggedInUser(ctx, c)
+		if !ok {
+			return
+		}
+
+		var updateObj primitive.D
+		if request.First_name != nil {
+			updateObj = append(updateObj, bson.E{Key: "first_name", Value: *request.First_name})
+		}
+		if request.Last_name != nil {
+			updateObj = append(updateObj, bson.E{Key: "last_name", Value: *request.Last_name})
+		}
+		if request.Phone != nil {
+			updateObj = append(updateObj, bson.E{Key: "phone", Value: *request.Phone})
+		}
+		emailChanged := request.Email != nil && (user.Email == nil || *request.Email != *user.Email)
+		if emailChanged {
+			if request.Current_password == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "current_password is required to change the email address"})
+				return
+			}
+			if !ctl.checkCurrentPassword(ctx, c, user, *request.Current_password) {
+				return
+			}
+			updateObj = append(updateObj, bson.E{Key: "email", Value: *request.Email})
+			updateObj = append(updateObj, bson.E{Key: "email_verified", Value: false})
+		}
+		if len(updateObj) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+			return
+		}
+		updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updatedAt})
+
+		var result models.User
+		var verificationToken string
+		var verificationExpiresAt time.Time
+		err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, user.User_id, tx.Users.FindByIDWithDeleted, func() error {
+				return tx.Users.Update(ctx, user.User_id, updateObj, version)
+			})
+			if err != nil || !emailChanged {
+				return err
+			}
+			verificationToken, verificationExpiresAt, err = ctl.issueUserToken(ctx, tx, result, models.PurposeEmailVerification)
+			return err
+		})
+		if errors.Is(err, store.ErrConflict) {
+			current, _ := ctl.Store.Users.FindByID(ctx, user.User_id)
+			setETag(c, current.Version)
+			c.JSON(http.StatusConflict, gin.H{"error": "your profile was changed meanwhile, apply your change to the current version and retry", "current": viewerOf(c).user(current)})
+			return
+		}
+		if errors.Is(err, store.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "this email or phone number already exists"})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while updating the profile"})
+			return
+		}
+		if emailChanged {
+			ctl.mailUserToken(ctx, *result.Email, models.PurposeEmailVerification, verificationToken, verificationExpiresAt)
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(result))
+	}
+}
+
+// ChangePassword sets a new password for the logged in user, who has to send the current one. Every session ends,
+// this one included, so a session someone else took over does not survive the change either.
+func (ctl *Controller) ChangePassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Current_password string `json:"current_password" binding:"required"`
+			New_password     string `json:"new_password" binding:"required,min=6"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := ctl.loggedInUser(ctx, c)
+		if !ok {
+			return
+		}
+		if !ctl.checkCurrentPassword(ctx, c, user, request.Current_password) {
+			return
+		}
+		password := HashPassword(request.New_password, ctl.Config.BcryptCost)
+
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			_, err := audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, user.User_id, tx.Users.FindByIDWithDeleted, func() error {
+				updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				updateObj := primitive.D{
+					{Key: "password", Value: password},
+					{Key: "updated_at", Value: updatedAt},
+				}
+				if err := tx.Users.Update(ctx, user.User_id, updateObj, 0); err != nil {
+					return err
+				}
+				if err := helper.RevokeTokens(ctx, tx.Users, user.User_id); err != nil {
+					return err
+				}
+				return revokeSessions(ctx, tx, user.User_id)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while changing the password"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "the password was changed, log in with the new one"})
+	}
+}
+
+// loggedInUser returns the user whose access token authenticated the request. An API key has no user of its own,
+// so it is answered with 400.
+func (ctl *Controller) loggedInUser(ctx context.Context, c *gin.Context) (models.User, bool) {
+	if _, ok := c.Get("claims"); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "this belongs to a login, an API key has no user of its own"})
+		return models.User{}, false
+	}
+	user, err := ctl.Store.Users.FindByID(ctx, c.GetString("uid"))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+		return user, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the user"})
+		return user, false
+	}
+	return user, true
+}
+
+// checkCurrentPassword makes a logged in user confirm a sensitive change with their password. Wrong passwords are
+// counted like failed logins, so a stolen session cannot be used to guess the password either. It answers a wrong
+// password or a lock itself and returns false then.
+func (ctl *Controller) checkCurrentPassword(ctx context.Context, c *gin.Context, user models.User, password string) bool {
+	limits := ctl.loginLimits(*user.Email, c.ClientIP())
+	lockedFor, err := ctl.loginLockedFor(ctx, limits)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the failed logins"})
+		return false
+	}
+	if lockedFor > 0 {
+		tooManyLogins(c, lockedFor)
+		return false
+	}
+	if ok, _ := VerifyPassword(password, *user.Password); !ok {
+		if err := ctl.loginFailed(ctx, limits); err != nil {
+			log.Printf("counting the failed password check of %s failed: %v", *user.Email, err)
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "the current password is incorrect"})
+		return false
+	}
+	return true
+}
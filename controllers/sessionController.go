@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	helper "restaurant-management-system/helpers"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Access tokens are checked against the revocations on every request, so ending a session takes effect at once
+// instead of when its token expires. A revoked session also loses its refresh token, otherwise it could simply be
+// refreshed into a new one.
+
+// Logout revokes the access token of the request and, when it belongs to the login whose refresh token is stored,
+// that refresh token too. Sessions of other logins of the same user stay valid.
+func (ctl *Controller) Logout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		value, ok := c.Get("claims")
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only a login can log out, an API key is revoked at /api-keys"})
+			return
+		}
+		claims := value.(*helper.SignedDetails)
+
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			if claims.Id != "" {
+				revocation := newRevocation(time.Unix(claims.ExpiresAt, 0))
+				revocation.Jti = &claims.Id
+				if err := tx.Revocations.Create(ctx, revocation); err != nil {
+					return err
+				}
+			}
+
+			user, err := tx.Users.FindByID(ctx, claims.UId)
+			if errors.Is(err, store.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if user.Token_family == nil || *user.Token_family != claims.Family {
+				return nil
+			}
+			return helper.RevokeTokens(ctx, tx.Users, user.User_id)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while logging out"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+	}
+}
+
+// RevokeSessions ends every session of a user: every access token issued so far is rejected and the stored
+// refresh token is dropped. The user can log in again right away.
+func (ctl *Controller) RevokeSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		userId := c.Param("user_id")
+
+		var result models.User
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, userId, tx.Users.FindByIDWithDeleted, func() error {
+				if err := helper.RevokeTokens(ctx, tx.Users, userId); err != nil {
+					return err
+				}
+				return revokeSessions(ctx, tx, userId)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the sessions"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, gin.H{"message": "every session of the user was revoked"})
+	}
+}
+
+// revokeSessions rejects every access token of the user issued until now, but not those issued afterwards, like the
+// one of a login right after a password change. The revocation can be forgotten once the last of those tokens has
+// expired.
+func revokeSessions(ctx context.Context, tx *store.Store, userId string) error {
+	// Mongo keeps dates to the millisecond, like the issue time of a token. The revocation covers the millisecond
+	// it is made in, so a token issued in it cannot escape; a login takes longer than that to issue its tokens.
+	now := time.Now().Truncate(time.Millisecond).Add(time.Millisecond)
+	revocation := newRevocation(now.Add(helper.AccessTokenTTL))
+	revocation.User_id = &userId
+	revocation.Before = &now
+	return tx.Revocations.Create(ctx, revocation)
+}
+
+func newRevocation(expiresAt time.Time) models.Revocation {
+	revocation := models.Revocation{ID: primitive.NewObjectID(), Expires_at: expiresAt}
+	revocation.Revocation_id = revocation.ID.Hex()
+	return revocation
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	helper "restaurant-management-system/helpers"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Staff administration lets an admin manage the accounts of others. Whatever changes what a user may do, a new role
+// or a deactivation, also ends their sessions: the role is carried in the access token, so a token issued before the
+// change would keep the old one until it expires. An admin cannot change their own role, deactivate or delete
+// themselves, and nobody can demote, deactivate or delete the last active admin, so the restaurant cannot be locked
+// out of its own administration by accident.
+
+// CreateStaff creates an account with any role, like a sign up by an admin. The new user verifies their email
+// address like everyone else.
+func (ctl *Controller) CreateStaff() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var user models.User
+		var validate = validator.New()
+
+		if err := c.BindJSON(&user); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(user); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, ok := ctl.createUser(ctx, c, user, false)
+		if !ok {
+			return
+		}
+
+		setETag(c, user.Version)
+		c.JSON(http.StatusCreated, viewerOf(c).user(user))
+	}
+}
+
+// ChangeRole gives a user another role. Their sessions end, so that their next login carries the new role.
+func (ctl *Controller) ChangeRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			User_type string `json:"user_type" binding:"required,oneof=ADMIN MANAGER CASHIER WAITER CHEF CUSTOMER"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userId := c.Param("user_id")
+		if userId == c.GetString("uid") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "you cannot change your own role"})
+			return
+		}
+
+		result, err := ctl.updateStaff(ctx, c, userId, func(ctx context.Context, tx *store.Store, user models.User) (primitive.D, error) {
+			if request.User_type != models.RoleAdmin {
+				if err := keepAnAdmin(ctx, tx, user); err != nil {
+					return nil, err
+				}
+			}
+			return primitive.D{{Key: "user_type", Value: request.User_type}}, nil
+		})
+		if !staffUpdated(c, err, "error occured while changing the role") {
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(result))
+	}
+}
+
+// DeactivateUser stops a user from logging in and ends their sessions, without deleting anything of theirs.
+func (ctl *Controller) DeactivateUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		userId := c.Param("user_id")
+		if userId == c.GetString("uid") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "you cannot deactivate yourself"})
+			return
+		}
+
+		now := time.Now()
+		by := c.GetString("uid")
+		result, err := ctl.updateStaff(ctx, c, userId, func(ctx context.Context, tx *store.Store, user models.User) (primitive.D, error) {
+			if user.Deactivated_at != nil {
+				return nil, conflict("the user is already deactivated")
+			}
+			if err := keepAnAdmin(ctx, tx, user); err != nil {
+				return nil, err
+			}
+			return primitive.D{
+				{Key: "deactivated_at", Value: now},
+				{Key: "deactivated_by", Value: by},
+			}, nil
+		})
+		if !staffUpdated(c, err, "error occured while deactivating the user") {
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(result))
+	}
+}
+
+// ReactivateUser lets a deactivated user log in again. The sessions that ended with the deactivation stay ended.
+func (ctl *Controller) ReactivateUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		userId := c.Param("user_id")
+
+		var result models.User
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, userId, tx.Users.FindByIDWithDeleted, func() error {
+				user, err := tx.Users.FindByID(ctx, userId)
+				if err != nil {
+					return err
+				}
+				if user.Deactivated_at == nil {
+					return conflict("the user is not deactivated")
+				}
+				updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				return tx.Users.Update(ctx, userId, primitive.D{
+					{Key: "deactivated_at", Value: nil},
+					{Key: "deactivated_by", Value: nil},
+					{Key: "updated_at", Value: updatedAt},
+				}, user.Version)
+			})
+			return err
+		})
+		if !staffUpdated(c, err, "error occured while reactivating the user") {
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(result))
+	}
+}
+
+// ForcePasswordReset makes a user choose a new password before they can log in again, for example when theirs may
+// have leaked. Their sessions end and they are emailed a reset token.
+func (ctl *Controller) ForcePasswordReset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		userId := c.Param("user_id")
+
+		var resetToken string
+		var resetExpiresAt time.Time
+		result, err := ctl.updateStaff(ctx, c, userId, func(ctx context.Context, tx *store.Store, user models.User) (update primitive.D, err error) {
+			resetToken, resetExpiresAt, err = ctl.issueUserToken(ctx, tx, user, models.PurposePasswordReset)
+			return primitive.D{{Key: "password_reset_required", Value: true}}, err
+		})
+		if !staffUpdated(c, err, "error occured while forcing the password reset") {
+			return
+		}
+		ctl.mailUserToken(ctx, *result.Email, models.PurposePasswordReset, resetToken, resetExpiresAt)
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(result))
+	}
+}
+
+// updateStaff applies the update that change returns for the user and ends every session of theirs, in one audited
+// transaction.
+func (ctl *Controller) updateStaff(ctx context.Context, c *gin.Context, userId string, change func(ctx context.Context, tx *store.Store, user models.User) (primitive.D, error)) (models.User, error) {
+	var result models.User
+	err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+		result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, userId, tx.Users.FindByIDWithDeleted, func() error {
+			user, err := tx.Users.FindByID(ctx, userId)
+			if err != nil {
+				return err
+			}
+			updateObj, err := change(ctx, tx, user)
+			if err != nil {
+				return err
+			}
+			updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updatedAt})
+			if err := tx.Users.Update(ctx, userId, updateObj, user.Version); err != nil {
+				return err
+			}
+			if err := helper.RevokeTokens(ctx, tx.Users, userId); err != nil {
+				return err
+			}
+			return revokeSessions(ctx, tx, userId)
+		})
+		return err
+	})
+	return result, err
+}
+
+// keepAnAdmin refuses to take user out of the administration when they are its last active admin.
+func keepAnAdmin(ctx context.Context, tx *store.Store, user models.User) error {
+	if user.User_type == nil || *user.User_type != models.RoleAdmin || user.Deactivated_at != nil {
+		return nil
+	}
+	admins, err := tx.Users.CountActiveByRole(ctx, models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return conflict("the user is the last active admin, make another user an admin first")
+	}
+	return nil
+}
+
+// staffUpdated answers the error of a staff administration transaction, if there is one, and reports whether there
+// was none.
+func staffUpdated(c *gin.Context, err error, msg string) bool {
+	var refused conflict
+	if errors.As(err, &refused) {
+		c.JSON(http.StatusConflict, gin.H{"error": refused.Error()})
+		return false
+	}
+	if errors.Is(err, store.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "the user was changed meanwhile, try again"})
+		return false
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return false
+	}
+	return true
+}
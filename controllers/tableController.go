@@ -2,56 +2,61 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
-	"restaurant-management-system/database"
 	"restaurant-management-system/models"
+	"restaurant-management-system/store"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var tableCollection *mongo.Collection = database.OpenCollection(database.Client, "table")
-
-func GetTables() gin.HandlerFunc {
+func (ctl *Controller) GetTables() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		result, err := tableCollection.Find(context.TODO(), bson.M{})
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listening the table"})
+		page, ok := pageRequest(c)
+		if !ok {
+			return
 		}
-		var allTables []bson.M
-		if err = result.All(ctx, &allTables); err != nil {
-			log.Fatal(err)
+		allTables, err := ctl.Store.Tables.List(ctx, includeDeleted(c), page)
+		if !listed(c, err, "error occured while listening the table") {
+			return
 		}
-		c.JSON(http.StatusOK, allTables)
+		c.JSON(http.StatusOK, pageView(allTables, viewerOf(c).table))
 	}
 }
 
-func GetTable() gin.HandlerFunc {
+func (ctl *Controller) GetTable() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		tableId := c.Param("table_id")
-		var table models.Table
 
-		err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+		findTable := ctl.Store.Tables.FindByID
+		if includeDeleted(c) {
+			findTable = ctl.Store.Tables.FindByIDWithDeleted
+		}
+		table, err := findTable(ctx, tableId)
 		defer cancel()
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the table"})
+			return
 		}
-		c.JSON(http.StatusOK, table)
+		setETag(c, table.Version)
+		c.JSON(http.StatusOK, viewerOf(c).table(table))
 	}
 }
 
-func CreateTable() gin.HandlerFunc {
+func (ctl *Controller) CreateTable() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var table models.Table
 		var validate = validator.New()
@@ -68,21 +73,31 @@ func CreateTable() gin.HandlerFunc {
 		table.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		table.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		table.ID = primitive.NewObjectID()
+		table.Version = 1
 		table.Table_ID = table.ID.Hex()
-		result, insertErr := tableCollection.InsertOne(ctx, table)
+		insertErr := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			if err := tx.Tables.Create(ctx, table); err != nil {
+				return err
+			}
+			return record(ctx, c, tx, models.AuditCreate, models.AuditTable, table.Table_ID, nil, table)
+		})
+		if errors.Is(insertErr, store.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "this table number already exists"})
+			return
+		}
 		if insertErr != nil {
 			msg := "Table item was not created"
 			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 			return
 		}
-		defer cancel()
-		c.JSON(http.StatusOK, result)
+		setETag(c, table.Version)
+		c.JSON(http.StatusOK, viewerOf(c).table(table))
 	}
 }
 
-func UpdateTable() gin.HandlerFunc {
+func (ctl *Controller) UpdateTable() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var table models.Table
 		tableId := c.Param("table_id")
@@ -91,6 +106,12 @@ func UpdateTable() gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		version, err := ifMatch(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		var updateObj primitive.D
 
 		if table.Number_of_guests != nil {
@@ -104,30 +125,130 @@ func UpdateTable() gin.HandlerFunc {
 		table.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: table.UpdatedAt})
 
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		var result models.Table
+		err = ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditTable, tableId, tx.Tables.FindByIDWithDeleted, func() error {
+				return tx.Tables.Update(ctx, tableId, updateObj, version)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrConflict) {
+			current, _ := ctl.Store.Tables.FindByID(ctx, tableId)
+			setETag(c, current.Version)
+			c.JSON(http.StatusConflict, gin.H{"error": "the table was changed by someone else, apply your change to the current version and retry", "current": viewerOf(c).table(current)})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+		if errors.Is(err, store.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{"error": "this table number already exists"})
+			return
 		}
+		if err != nil {
+			msg := "Failed to update the table item"
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).table(result))
 
-		filter := bson.M{"table_id": tableId}
+	}
+}
 
-		result, err := tableCollection.UpdateOne(
-			ctx,
-			filter,
-			bson.D{
-				{Key: "$set", Value: updateObj},
-			},
-			&opt,
-		)
+// DeleteTable soft deletes a table. A table with an open order is refused with a 409. The deleted table keeps its
+// number, so a new table cannot take it over.
+func (ctl *Controller) DeleteTable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		tableId := c.Param("table_id")
 
+		d := deletion(c)
+		var result models.Table
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			open, err := hasOpenOrder(ctx, tx, tableId)
+			if err != nil {
+				return err
+			}
+			if open {
+				return conflict("the table has an open order, pay or delete it first")
+			}
+			result, err = audited(ctx, c, tx, models.AuditDelete, models.AuditTable, tableId, tx.Tables.FindByIDWithDeleted, func() error {
+				return tx.Tables.Delete(ctx, tableId, d)
+			})
+			return err
+		})
+		var refused conflict
+		if errors.As(err, &refused) {
+			c.JSON(http.StatusConflict, gin.H{"error": refused.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
 		if err != nil {
-			msg := "Failed to update the table item"
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while deleting the table"})
 			return
 		}
 
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).table(result))
+	}
+}
+
+// RestoreTable brings back a deleted table.
+func (ctl *Controller) RestoreTable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
-		c.JSON(http.StatusOK, result)
+		tableId := c.Param("table_id")
 
+		var result models.Table
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditRestore, models.AuditTable, tableId, tx.Tables.FindByIDWithDeleted, func() error {
+				return tx.Tables.Restore(ctx, tableId)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "table was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while restoring the table"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).table(result))
+	}
+}
+
+// hasOpenOrder reports whether a table has an order that is neither deleted nor paid. An order is paid once one of
+// its invoices has the PAID status.
+func hasOpenOrder(ctx context.Context, s *store.Store, tableId string) (bool, error) {
+	orders, err := s.Orders.ListByTable(ctx, tableId)
+	if err != nil {
+		return false, err
+	}
+	for _, order := range orders {
+		invoices, err := s.Invoices.ListByOrder(ctx, order.Order_ID)
+		if err != nil {
+			return false, err
+		}
+		paid := false
+		for _, invoice := range invoices {
+			if invoice.Payment_status != nil && *invoice.Payment_status == "PAID" {
+				paid = true
+			}
+		}
+		if !paid {
+			return true, nil
+		}
 	}
+	return false, nil
 }
@@ -2,96 +2,66 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"restaurant-management-system/database"
 	helper "restaurant-management-system/helpers"
+	"restaurant-management-system/middleware"
 	"restaurant-management-system/models"
-	"strconv"
+	"restaurant-management-system/store"
 	"time"
 
 	"log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
-
-func GetUsers() gin.HandlerFunc {
+func (ctl *Controller) GetUsers() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel() // Ensure context is canceled after function completes
 
-		// Get records per page
-		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
-		if err != nil || recordPerPage < 1 {
-			recordPerPage = 10
-		}
-
-		// Get page number
-		page, err := strconv.Atoi(c.Query("page"))
-		if err != nil || page < 1 {
-			page = 1
-		}
-
-		// Calculate startIndex
-		startIndex := (page - 1) * recordPerPage
-
-		// Optional: Get startIndex from query and handle potential errors
-		if queryStartIndex := c.Query("startIndex"); queryStartIndex != "" {
-			startIndex, err = strconv.Atoi(queryStartIndex)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid startIndex"})
-				return
-			}
-		}
-
-		matchStage := bson.D{{Key: "$match", Value: bson.D{{}}}}
-		projectStage := bson.D{{Key: "$project", Value: bson.D{
-			{Key: "_id", Value: 0},
-			{Key: "total_count", Value: 1},
-			{Key: "user_items", Value: bson.D{{Key: "$slice", Value: bson.A{"$data", startIndex, recordPerPage}}}},
-		}}}
-
-		result, err := userCollection.Aggregate(ctx, mongo.Pipeline{matchStage, projectStage})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occurred while fetching the users"})
+		page, ok := pageRequest(c)
+		if !ok {
 			return
 		}
-
-		var allusers []bson.M
-		if err = result.All(ctx, &allusers); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		userItems, err := ctl.Store.Users.List(ctx, includeDeleted(c), page)
+		if !listed(c, err, "error occurred while fetching the users") {
 			return
 		}
-
-		c.JSON(http.StatusOK, allusers)
+		c.JSON(http.StatusOK, pageView(userItems, viewerOf(c).user))
 	}
 }
 
-func GetUser() gin.HandlerFunc {
+func (ctl *Controller) GetUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		userId := c.Param("user_id")
 
-		var user models.User
-
-		err := userCollection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user)
+		findUser := ctl.Store.Users.FindByID
+		if includeDeleted(c) {
+			findUser = ctl.Store.Users.FindByIDWithDeleted
+		}
+		user, err := findUser(ctx, userId)
 		defer cancel()
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
-		c.JSON(http.StatusOK, user)
+		setETag(c, user.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(user))
 	}
 }
 
-func SignUp() gin.HandlerFunc {
+func (ctl *Controller) SignUp() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var user models.User
 		var validate = validator.New()
@@ -107,87 +77,354 @@ func SignUp() gin.HandlerFunc {
 			return
 		}
 
-		// Check if the email already exists
-
-		countEmail, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
-		defer cancel()
-		if err != nil {
-			log.Panic(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email"})
+		// Anyone can sign up as a customer. Staff accounts are created by an admin at POST /users, which goes through
+		// the authentication middleware; the only staff account signing up creates is the very first account, which
+		// has to be an admin to set the restaurant up.
+		if *user.User_type != models.RoleCustomer {
+			first, err := ctl.isFirstAccount(ctx)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the role"})
+				return
+			}
+			if !first {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only an admin can create a " + *user.User_type + " account, at POST /users"})
+				return
+			}
+			if *user.User_type != models.RoleAdmin {
+				c.JSON(http.StatusForbidden, gin.H{"error": "the first account must be an " + models.RoleAdmin + " account"})
+				return
+			}
 		}
 
-		password := HashPassword(*user.Password)
-		user.Password = &password
-
-		countPhone, err := userCollection.CountDocuments(ctx, bson.M{"phone": user.Phone})
-		defer cancel()
-		if err != nil {
-			log.Panic(err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the phone number"})
+		user, ok := ctl.createUser(ctx, c, user, *user.User_type != models.RoleCustomer)
+		if !ok {
+			return
 		}
 
-		if countEmail > 0 || countPhone > 0 {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "this email or phone number already exists"})
-		}
+		c.JSON(http.StatusOK, gin.H{"InsertedID": user.ID})
 
-		user.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	}
+}
 
-		user.ID = primitive.NewObjectID()
-		user.User_id = user.ID.Hex()
+// firstAccountClaim is claimed in the transaction that creates the first account. The count of isFirstAccount only
+// answers early; two sign ups racing past it cannot both claim.
+const firstAccountClaim = "first_account"
 
-		token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id)
-		user.Token = &token
-		user.Refresh_Token = &refreshToken
+var errNotFirstAccount = errors.New("the first account exists already")
 
-		resultInsertionNumber, inserterr := userCollection.InsertOne(ctx, user)
-		defer cancel()
-		if inserterr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "user was not created"})
-			return
-		}
+// createUser stores a new user from a validated sign up and emails them a verification token. With first set the user
+// is created only as the very first account. It answers any error itself and returns false then.
+func (ctl *Controller) createUser(ctx context.Context, c *gin.Context, user models.User, first bool) (models.User, bool) {
+	countEmail, err := ctl.Store.Users.CountByEmail(ctx, *user.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the email"})
+		return user, false
+	}
 
-		defer cancel()
+	password := HashPassword(*user.Password, ctl.Config.BcryptCost)
+	user.Password = &password
 
-		c.JSON(http.StatusOK, resultInsertionNumber)
+	countPhone, err := ctl.Store.Users.CountByPhone(ctx, *user.Phone)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking for the phone number"})
+		return user, false
+	}
 
+	if countEmail > 0 || countPhone > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "this email or phone number already exists"})
+		return user, false
 	}
+
+	user.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	user.ID = primitive.NewObjectID()
+	user.Version = 1
+	user.User_id = user.ID.Hex()
+	user.Email_verified = false
+	user.Mfa_enabled = false
+	user.Password_reset_required = false
+	user.Deactivated_at = nil
+	user.Deactivated_by = nil
+
+	family := helper.NewTokenFamily()
+	token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, *user.User_type, family)
+	user.Token = &token
+	user.Refresh_Token = &refreshToken
+	user.Token_family = &family
+
+	// The counts above are only a friendly early check; the unique indexes on email and phone settle the race
+	// between two sign ups with the same details. At a sign up nobody is logged in yet, so the audit entry has no
+	// actor.
+	var verificationToken string
+	var verificationExpiresAt time.Time
+	inserterr := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+		if first {
+			if err := tx.Claims.Claim(ctx, firstAccountClaim, user.Created_at); errors.Is(err, store.ErrDuplicate) {
+				return errNotFirstAccount
+			} else if err != nil {
+				return err
+			}
+		}
+		if err := tx.Users.Create(ctx, user); err != nil {
+			return err
+		}
+		if err := record(ctx, c, tx, models.AuditCreate, models.AuditUser, user.User_id, nil, user); err != nil {
+			return err
+		}
+		verificationToken, verificationExpiresAt, err = ctl.issueUserToken(ctx, tx, user, models.PurposeEmailVerification)
+		return err
+	})
+	if errors.Is(inserterr, store.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": "this email or phone number already exists"})
+		return user, false
+	}
+	if errors.Is(inserterr, errNotFirstAccount) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only an admin can create a " + *user.User_type + " account, at POST /users"})
+		return user, false
+	}
+	if inserterr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user was not created"})
+		return user, false
+	}
+	ctl.mailUserToken(ctx, *user.Email, models.PurposeEmailVerification, verificationToken, verificationExpiresAt)
+	return user, true
 }
 
-func Login() gin.HandlerFunc {
+func (ctl *Controller) Login() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
 		defer cancel()
 		var user models.User
-		var foundUser models.User
 
 		if err := c.BindJSON(&user); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if user.Email == nil || user.Password == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "email and password are required"})
+			return
+		}
+
+		// A locked account or IP is refused before the password is even looked at, so guesses made meanwhile
+		// neither cost a bcrypt hash nor tell anything.
+		limits := ctl.loginLimits(*user.Email, c.ClientIP())
+		lockedFor, err := ctl.loginLockedFor(ctx, limits)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the failed logins"})
+			return
+		}
+		if lockedFor > 0 {
+			ctl.recordLoginAttempt(ctx, c, *user.Email, nil, models.LoginLocked)
+			tooManyLogins(c, lockedFor)
+			return
+		}
 
-		err := userCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&foundUser)
+		foundUser, err := ctl.Store.Users.FindByEmail(ctx, *user.Email)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the user"})
+			return
+		}
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "email or password is incorrect"})
+			VerifyPassword(*user.Password, ctl.dummyPasswordHash())
+			ctl.recordLoginAttempt(ctx, c, *user.Email, nil, models.LoginUnknownEmail)
+			if err := ctl.loginFailed(ctx, limits); err != nil {
+				log.Printf("counting the failed login of %s failed: %v", *user.Email, err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "email or password is incorrect"})
 			return
 		}
 
 		passwordIsValid, msg := VerifyPassword(*user.Password, *foundUser.Password)
 		if !passwordIsValid { // Simplified this line
-			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			ctl.recordLoginAttempt(ctx, c, *user.Email, &foundUser, models.LoginWrongPassword)
+			if err := ctl.loginFailed(ctx, limits); err != nil {
+				log.Printf("counting the failed login of %s failed: %v", *user.Email, err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+		if ctl.Config.RequireVerifiedEmail && !foundUser.Email_verified {
+			ctl.recordLoginAttempt(ctx, c, *user.Email, &foundUser, models.LoginEmailNotVerified)
+			c.JSON(http.StatusForbidden, gin.H{"error": "verify your email address before logging in, a new verification email can be requested at /users/verify-email/resend"})
+			return
+		}
+		if foundUser.Deactivated_at != nil {
+			ctl.recordLoginAttempt(ctx, c, *user.Email, &foundUser, models.LoginDeactivated)
+			c.JSON(http.StatusForbidden, gin.H{"error": "this account is deactivated"})
+			return
+		}
+		if foundUser.Password_reset_required {
+			ctl.recordLoginAttempt(ctx, c, *user.Email, &foundUser, models.LoginResetRequired)
+			c.JSON(http.StatusForbidden, gin.H{"error": "a new password is required, set it with the token emailed to you or request another at /users/forgot-password"})
+			return
+		}
+		// A user with two-factor authentication, or whose role requires it, only gets a challenge for now. It is
+		// exchanged for the tokens at /users/login/mfa together with a TOTP code.
+		if foundUser.Mfa_enabled || ctl.mfaRequired(foundUser) {
+			challenge, err := helper.GenerateMfaChallenge(foundUser.User_id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while creating the MFA challenge"})
+				return
+			}
+			ctl.recordLoginAttempt(ctx, c, *user.Email, &foundUser, models.LoginMfaRequired)
+			response := gin.H{"mfa_token": challenge, "expires_in": int(helper.MfaChallengeTTL.Seconds())}
+			if foundUser.Mfa_enabled {
+				response["mfa_required"] = true
+			} else {
+				response["mfa_enrollment_required"] = true
+			}
+			c.JSON(http.StatusOK, response)
 			return
 		}
 
-		token, refreshToken, _ := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id)
+		ctl.completeLogin(ctx, c, foundUser, limits, nil)
+	}
+}
+
+// LoginViewFormat is the user who logged in and the tokens of the login. Recovery_codes are only set when the login
+// also enrolled the user in two-factor authentication.
+type LoginViewFormat struct {
+	UserViewFormat
+	Token          string   `json:"token"`
+	Refresh_token  string   `json:"refresh_token"`
+	Recovery_codes []string `json:"recovery_codes,omitempty"`
+}
+
+// completeLogin issues the tokens of a login whose credentials were all checked and answers with the user.
+func (ctl *Controller) completeLogin(ctx context.Context, c *gin.Context, foundUser models.User, limits []loginLimit, recoveryCodes []string) {
+	// A successful login clears the failures of the account, but not those of the IP: an attacker who owns one
+	// account must not be able to reset the IP counter between guesses at others.
+	if err := ctl.Store.LoginThrottles.Reset(ctx, limits[0].key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while clearing the failed logins"})
+		return
+	}
+
+	role := ""
+	if foundUser.User_type != nil {
+		role = *foundUser.User_type
+	}
+	// Every login starts a new token family. It replaces the stored one, so the refresh token of an earlier login
+	// stops working without counting as reuse.
+	family := helper.NewTokenFamily()
+	token, refreshToken, _ := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id, role, family)
+
+	if err := helper.UpdateAllTokens(ctx, ctl.Store.Users, token, refreshToken, family, foundUser.User_id, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while storing the tokens"})
+		return
+	}
 
-		helper.UpdateAllTokens(token, refreshToken, foundUser.User_id)
+	foundUser, err := ctl.Store.Users.FindByID(ctx, foundUser.User_id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctl.recordLoginAttempt(ctx, c, *foundUser.Email, &foundUser, models.LoginSucceeded)
 
-		c.JSON(http.StatusOK, foundUser)
+	// Nobody was authenticated on this request, so the viewer is the user who just logged in.
+	v := viewer{
+		uid:      foundUser.User_id,
+		contacts: middleware.Can(role, middleware.ReadContacts),
+		audit:    middleware.Can(role, middleware.ReadAudit),
 	}
+	c.JSON(http.StatusOK, LoginViewFormat{
+		UserViewFormat: v.user(foundUser),
+		Token:          token,
+		Refresh_token:  refreshToken,
+		Recovery_codes: recoveryCodes,
+	})
 }
 
-func HashPassword(password string) string {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+// RefreshToken exchanges the stored refresh token of a user for a new token pair and stores the new refresh token in
+// its place, so each refresh token works once. A refresh token of the stored family that was already exchanged can
+// only come from a copy, so it revokes the sessions of the user: neither the copy, nor the token its owner holds, nor
+// any access token issued before is accepted any more, and the user has to log in again.
+func (ctl *Controller) RefreshToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Refresh_token string `json:"refresh_token" binding:"required"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		claims, msg := helper.ValidateToken(request.Refresh_token)
+		if msg != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+		if claims.Token_type != helper.RefreshToken || claims.UId == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "this is not a refresh token"})
+			return
+		}
+
+		// Two refreshes of the same user can race; the version makes sure only one of them rotates the token. The
+		// loser reads the user again and finds its token already rotated.
+		for {
+			user, err := ctl.Store.Users.FindByID(ctx, claims.UId)
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "the user of this refresh token no longer exists"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the user"})
+				return
+			}
+
+			if user.Token_family == nil || *user.Token_family != claims.Family {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "this refresh token is no longer valid, log in again"})
+				return
+			}
+			if user.Refresh_Token == nil || *user.Refresh_Token != request.Refresh_token {
+				// Whoever holds the copy may also hold access tokens issued from it, so those are revoked with the
+				// refresh token, in one transaction.
+				log.Printf("refresh token of user %s was reused, revoking its sessions", user.User_id)
+				err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+					if err := helper.RevokeTokens(ctx, tx.Users, user.User_id); err != nil {
+						return err
+					}
+					return revokeSessions(ctx, tx, user.User_id)
+				})
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while revoking the tokens"})
+					return
+				}
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "this refresh token was already used, all sessions of the user are revoked"})
+				return
+			}
+
+			role := ""
+			if user.User_type != nil {
+				role = *user.User_type
+			}
+			token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, role, claims.Family)
+			err = helper.UpdateAllTokens(ctx, ctl.Store.Users, token, refreshToken, claims.Family, user.User_id, user.Version)
+			if errors.Is(err, store.ErrConflict) {
+				continue
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while storing the tokens"})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{"token": token, "refresh_token": refreshToken})
+			return
+		}
+	}
+}
+
+// isFirstAccount reports whether there is no account yet, counting deleted ones.
+func (ctl *Controller) isFirstAccount(ctx context.Context) (bool, error) {
+	users, err := ctl.Store.Users.List(ctx, true, store.Page{Limit: 1})
+	return users.Total_count == 0, err
+}
+
+func HashPassword(password string, cost int) string {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -207,3 +444,70 @@ func VerifyPassword(userPassword string, providePassword string) (bool, string)
 
 	return check, msg
 }
+
+// DeleteUser soft deletes a user, who can no longer log in. The email and phone stay taken until the user is
+// restored.
+func (ctl *Controller) DeleteUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		userId := c.Param("user_id")
+		if userId == c.GetString("uid") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "you cannot delete yourself"})
+			return
+		}
+
+		var result models.User
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditDelete, models.AuditUser, userId, tx.Users.FindByIDWithDeleted, func() error {
+				user, err := tx.Users.FindByID(ctx, userId)
+				if err != nil {
+					return err
+				}
+				if err := keepAnAdmin(ctx, tx, user); err != nil {
+					return err
+				}
+				return tx.Users.Delete(ctx, userId, deletion(c))
+			})
+			if err != nil {
+				return err
+			}
+			// A deleted user cannot log in any more, and the tokens they already hold stop working too.
+			return revokeSessions(ctx, tx, userId)
+		})
+		if !staffUpdated(c, err, "error occured while deleting the user") {
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(result))
+	}
+}
+
+// RestoreUser brings back a deleted user.
+func (ctl *Controller) RestoreUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		userId := c.Param("user_id")
+
+		var result models.User
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+			result, err = audited(ctx, c, tx, models.AuditRestore, models.AuditUser, userId, tx.Users.FindByIDWithDeleted, func() error {
+				return tx.Users.Restore(ctx, userId)
+			})
+			return err
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user was not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while restoring the user"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, viewerOf(c).user(result))
+	}
+}
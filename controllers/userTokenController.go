@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	helper "restaurant-management-system/helpers"
+	"restaurant-management-system/mailer"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Password resets and email verifications work the same way: a single use token goes to the email address of the
+// user, and whoever sends it back proves they can read that mailbox. The requests for a token answer the same
+// whether the address belongs to an account or not, so they cannot be used to find out who has one.
+
+var errInvalidUserToken = errors.New("the token is invalid, has expired or was already used")
+
+const emailSentMessage = "if an account with this email exists, an email is on its way"
+
+// ForgotPassword emails a password reset token. Asking again replaces the token sent before.
+func (ctl *Controller) ForgotPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := ctl.Store.Users.FindByEmail(ctx, request.Email)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusAccepted, gin.H{"message": emailSentMessage})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the user"})
+			return
+		}
+
+		if err := ctl.sendUserToken(ctx, user, models.PurposePasswordReset); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while creating the reset token"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": emailSentMessage})
+	}
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. Every session of the user ends, in case the
+// reset is because someone else got hold of the password. The reset also proves the user reads the mailbox, so it
+// verifies the email address as well.
+func (ctl *Controller) ResetPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Token    string `json:"token" binding:"required"`
+			Password string `json:"password" binding:"required,min=6"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		password := HashPassword(request.Password, ctl.Config.BcryptCost)
+
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			userToken, err := redeemUserToken(ctx, tx, request.Token, models.PurposePasswordReset)
+			if err != nil {
+				return err
+			}
+			_, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, userToken.User_id, tx.Users.FindByIDWithDeleted, func() error {
+				user, err := tx.Users.FindByID(ctx, userToken.User_id)
+				if err != nil {
+					return err
+				}
+				updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				updateObj := primitive.D{
+					{Key: "password", Value: password},
+					{Key: "password_reset_required", Value: false},
+					{Key: "updated_at", Value: updatedAt},
+				}
+				if user.Email != nil && *user.Email == userToken.Email {
+					updateObj = append(updateObj, bson.E{Key: "email_verified", Value: true})
+				}
+				if err := tx.Users.Update(ctx, user.User_id, updateObj, 0); err != nil {
+					return err
+				}
+				if err := helper.RevokeTokens(ctx, tx.Users, user.User_id); err != nil {
+					return err
+				}
+				return revokeSessions(ctx, tx, user.User_id)
+			})
+			return err
+		})
+		if errors.Is(err, errInvalidUserToken) || errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidUserToken.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while resetting the password"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "the password was changed, log in with the new one"})
+	}
+}
+
+// ResendVerification emails a new verification token to a user whose address is not verified yet.
+func (ctl *Controller) ResendVerification() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := ctl.Store.Users.FindByEmail(ctx, request.Email)
+		if errors.Is(err, store.ErrNotFound) || (err == nil && user.Email_verified) {
+			c.JSON(http.StatusAccepted, gin.H{"message": emailSentMessage})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while fetching the user"})
+			return
+		}
+
+		if err := ctl.sendUserToken(ctx, user, models.PurposeEmailVerification); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while creating the verification token"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": emailSentMessage})
+	}
+}
+
+// VerifyEmail marks the email address of a user verified with a token from the verification email. A token sent
+// to an address the user has since changed no longer counts.
+func (ctl *Controller) VerifyEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(context.Background(), ctl.Config.RequestTimeout.Duration)
+		defer cancel()
+		var request struct {
+			Token string `json:"token" binding:"required"`
+		}
+
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var result models.User
+		err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			userToken, err := redeemUserToken(ctx, tx, request.Token, models.PurposeEmailVerification)
+			if err != nil {
+				return err
+			}
+			result, err = audited(ctx, c, tx, models.AuditUpdate, models.AuditUser, userToken.User_id, tx.Users.FindByIDWithDeleted, func() error {
+				user, err := tx.Users.FindByID(ctx, userToken.User_id)
+				if err != nil {
+					return err
+				}
+				if user.Email == nil || *user.Email != userToken.Email {
+					return errInvalidUserToken
+				}
+				return tx.Users.Update(ctx, user.User_id, primitive.D{{Key: "email_verified", Value: true}}, 0)
+			})
+			return err
+		})
+		if errors.Is(err, errInvalidUserToken) || errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidUserToken.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while verifying the email"})
+			return
+		}
+
+		setETag(c, result.Version)
+		c.JSON(http.StatusOK, gin.H{"message": "the email address is verified"})
+	}
+}
+
+// sendUserToken issues a token for purpose and emails it to the user. An email that cannot be sent is only logged:
+// the user can ask for another one, and telling the caller would reveal that the account exists.
+func (ctl *Controller) sendUserToken(ctx context.Context, user models.User, purpose string) error {
+	var token string
+	var expiresAt time.Time
+	err := ctl.Store.Transaction(ctx, func(ctx context.Context, tx *store.Store) (err error) {
+		token, expiresAt, err = ctl.issueUserToken(ctx, tx, user, purpose)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	ctl.mailUserToken(ctx, *user.Email, purpose, token, expiresAt)
+	return nil
+}
+
+// issueUserToken stores a new token for purpose and returns it. Earlier tokens for the same purpose stop working.
+func (ctl *Controller) issueUserToken(ctx context.Context, tx *store.Store, user models.User, purpose string) (string, time.Time, error) {
+	ttl := ctl.Config.PasswordResetTTL.Duration
+	if purpose == models.PurposeEmailVerification {
+		ttl = ctl.Config.EmailVerificationTTL.Duration
+	}
+	token, hash, err := helper.NewUserToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	if err := tx.UserTokens.UseAll(ctx, user.User_id, purpose, now); err != nil {
+		return "", time.Time{}, err
+	}
+	userToken := models.UserToken{
+		ID:         primitive.NewObjectID(),
+		Purpose:    purpose,
+		User_id:    user.User_id,
+		Email:      *user.Email,
+		Token_hash: hash,
+		Expires_at: now.Add(ttl),
+		Created_at: now,
+	}
+	userToken.User_token_id = userToken.ID.Hex()
+	return token, userToken.Expires_at, tx.UserTokens.Create(ctx, userToken)
+}
+
+func (ctl *Controller) mailUserToken(ctx context.Context, email string, purpose string, token string, expiresAt time.Time) {
+	msg := mailer.Message{To: email}
+	expires := expiresAt.UTC().Format(time.RFC1123)
+	switch purpose {
+	case models.PurposePasswordReset:
+		msg.Subject = "Reset your password"
+		msg.Body = fmt.Sprintf("Someone asked to reset the password of your account. To choose a new one, send this token "+
+			"with the new password to POST /users/reset-password before %s:\n\n%s\n\n"+
+			"If it was not you, ignore this email and your password stays as it is.\n", expires, token)
+	case models.PurposeEmailVerification:
+		msg.Subject = "Verify your email address"
+		msg.Body = fmt.Sprintf("To confirm this is your email address, send this token to POST /users/verify-email "+
+			"before %s:\n\n%s\n", expires, token)
+	}
+	if err := ctl.Mailer.Send(ctx, msg); err != nil {
+		log.Printf("sending the %s email failed: %v", purpose, err)
+	}
+}
+
+// redeemUserToken marks token used and returns it, provided it was issued for purpose and has not expired or been
+// used before. Anything else is errInvalidUserToken, without saying which.
+func redeemUserToken(ctx context.Context, tx *store.Store, token string, purpose string) (models.UserToken, error) {
+	userToken, err := tx.UserTokens.FindByHash(ctx, helper.HashUserToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return userToken, errInvalidUserToken
+	}
+	if err != nil {
+		return userToken, err
+	}
+	if userToken.Purpose != purpose || !userToken.Expires_at.After(time.Now()) {
+		return userToken, errInvalidUserToken
+	}
+	err = tx.UserTokens.Use(ctx, userToken.User_token_id, time.Now())
+	if errors.Is(err, store.ErrConflict) || errors.Is(err, store.ErrNotFound) {
+		return userToken, errInvalidUserToken
+	}
+	return userToken, err
+}
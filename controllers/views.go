@@ -0,0 +1,395 @@
+package controllers
+
+import (
+	"restaurant-management-system/middleware"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Handlers never send a model straight to the client. Like InvoiceViewFormat, each resource has a view that holds
+// only what the client may see, so a field added to a model stays private until a view shows it. Secrets, like
+// password hashes and stored tokens, have no place in any view. A few fields depend on who asks, see viewer.
+
+// viewer decides the fields that depend on who is asking.
+type viewer struct {
+	uid      string
+	contacts bool // may see the phone numbers of other users
+	audit    bool // may see who deleted, deactivated or created something, which is what the audit log is for
+}
+
+func viewerOf(c *gin.Context) viewer {
+	return viewer{
+		uid:      c.GetString("uid"),
+		contacts: middleware.Allowed(c, middleware.ReadContacts),
+		audit:    middleware.Allowed(c, middleware.ReadAudit),
+	}
+}
+
+// auditOnly returns by to viewers who may read the audit log and nil to everyone else.
+func (v viewer) auditOnly(by *string) *string {
+	if !v.audit {
+		return nil
+	}
+	return by
+}
+
+// viewAll applies view to each of items. It never returns nil, so an empty list is sent as [] rather than null.
+func viewAll[T any, V any](items []T, view func(T) V) []V {
+	views := make([]V, 0, len(items))
+	for _, item := range items {
+		views = append(views, view(item))
+	}
+	return views
+}
+
+// PageViewFormat is the envelope of every listing. next_cursor is passed back as ?cursor= for the next page and is
+// null on the last one.
+type PageViewFormat[V any] struct {
+	Items       []V     `json:"items"`
+	Next_cursor *string `json:"next_cursor"`
+	Total_count int     `json:"total_count"`
+}
+
+func pageView[T any, V any](paged store.Paged[T], view func(T) V) PageViewFormat[V] {
+	result := PageViewFormat[V]{Items: viewAll(paged.Items, view), Total_count: paged.Total_count}
+	if paged.Next_cursor != "" {
+		result.Next_cursor = &paged.Next_cursor
+	}
+	return result
+}
+
+// asIs is the view of the records that hold nothing to hide, like the audit log.
+func asIs[T any](item T) T {
+	return item
+}
+
+type UserViewFormat struct {
+	User_id                 string     `json:"user_id"`
+	First_name              *string    `json:"first_name"`
+	Last_name               *string    `json:"last_name"`
+	Email                   *string    `json:"email"`
+	Phone                   *string    `json:"phone,omitempty"` // Only for the user themselves and viewers with users:contacts
+	User_type               *string    `json:"user_type"`
+	Email_verified          bool       `json:"email_verified"`
+	Mfa_enabled             bool       `json:"mfa_enabled"`
+	Password_reset_required bool       `json:"password_reset_required"`
+	Deactivated_at          *time.Time `json:"deactivated_at,omitempty"`
+	Deactivated_by          *string    `json:"deactivated_by,omitempty"`
+	Created_at              time.Time  `json:"created_at"`
+	Updated_at              time.Time  `json:"updated_at"`
+	Deleted_at              *time.Time `json:"deleted_at,omitempty"`
+	Deleted_by              *string    `json:"deleted_by,omitempty"`
+	Version                 int        `json:"version"`
+}
+
+func (v viewer) user(user models.User) UserViewFormat {
+	view := UserViewFormat{
+		User_id:                 user.User_id,
+		First_name:              user.First_name,
+		Last_name:               user.Last_name,
+		Email:                   user.Email,
+		User_type:               user.User_type,
+		Email_verified:          user.Email_verified,
+		Mfa_enabled:             user.Mfa_enabled,
+		Password_reset_required: user.Password_reset_required,
+		Deactivated_at:          user.Deactivated_at,
+		Deactivated_by:          v.auditOnly(user.Deactivated_by),
+		Created_at:              user.Created_at,
+		Updated_at:              user.Updated_at,
+		Deleted_at:              user.Deleted_at,
+		Deleted_by:              v.auditOnly(user.Deleted_by),
+		Version:                 user.Version,
+	}
+	if v.contacts || v.uid == user.User_id {
+		view.Phone = user.Phone
+	}
+	return view
+}
+
+type FoodViewFormat struct {
+	Food_id         string                 `json:"food_id"`
+	Name            *string                `json:"name"`
+	Price           *float64               `json:"price"`
+	Variants        []models.FoodVariant   `json:"variants"` // Empty when the food is sold in the standard sizes at its price
+	Modifier_groups []models.ModifierGroup `json:"modifier_groups"`
+	Food_image      *string                `json:"food_image"`
+	Description     *string                `json:"description"`
+	Tags            []string               `json:"tags"`
+	Available       bool                   `json:"available"`
+	Image           *FoodImageViewFormat   `json:"image,omitempty"`
+	Menu_id         *string                `json:"menu_id"`
+	Created_at      time.Time              `json:"created_at"`
+	Updated_at      time.Time              `json:"updated_at"`
+	Deleted_at      *time.Time             `json:"deleted_at,omitempty"`
+	Deleted_by      *string                `json:"deleted_by,omitempty"`
+	Version         int                    `json:"version"`
+}
+
+func (v viewer) food(food models.Food) FoodViewFormat {
+	if food.Tags == nil {
+		food.Tags = []string{}
+	}
+	if food.Variants == nil {
+		food.Variants = []models.FoodVariant{}
+	}
+	if food.Modifier_groups == nil {
+		food.Modifier_groups = []models.ModifierGroup{}
+	}
+	return FoodViewFormat{
+		Food_id:         food.Food_id,
+		Name:            food.Name,
+		Price:           food.Price,
+		Variants:        food.Variants,
+		Modifier_groups: food.Modifier_groups,
+		Food_image:      food.Food_image,
+		Description:     food.Description,
+		Tags:            food.Tags,
+		Available:       food.Available == nil || *food.Available,
+		Image:           foodImage(food),
+		Menu_id:         food.Menu_id,
+		Created_at:      food.Created_at,
+		Updated_at:      food.Updated_at,
+		Deleted_at:      food.Deleted_at,
+		Deleted_by:      v.auditOnly(food.Deleted_by),
+		Version:         food.Version,
+	}
+}
+
+// FoodImageViewFormat tells a client where to fetch each size of an uploaded image. The URLs change with every
+// upload, so they can be cached for as long as the client likes.
+type FoodImageViewFormat struct {
+	Image_id    string                 `json:"image_id"`
+	Sizes       []models.FoodImageSize `json:"sizes"`
+	Urls        map[string]string      `json:"urls"`
+	Uploaded_at time.Time              `json:"uploaded_at"`
+}
+
+func foodImage(food models.Food) *FoodImageViewFormat {
+	if food.Image == nil {
+		return nil
+	}
+	view := &FoodImageViewFormat{
+		Image_id:    food.Image.Image_id,
+		Sizes:       food.Image.Sizes,
+		Urls:        map[string]string{},
+		Uploaded_at: food.Image.Uploaded_at,
+	}
+	for _, size := range food.Image.Sizes {
+		view.Urls[size.Size] = foodImageUrl(food.Food_id, food.Image.Image_id, size.Size)
+	}
+	return view
+}
+
+type MenuViewFormat struct {
+	Menu_id    string     `json:"menu_id"`
+	Name       string     `json:"name"`
+	Category   string     `json:"category"`
+	Start_date *time.Time `json:"start_date"`
+	End_date   *time.Time `json:"end_date"`
+	Created_at time.Time  `json:"created_at"`
+	Updated_at time.Time  `json:"updated_at"`
+	Deleted_at *time.Time `json:"deleted_at,omitempty"`
+	Deleted_by *string    `json:"deleted_by,omitempty"`
+	Version    int        `json:"version"`
+}
+
+func (v viewer) menu(menu models.Menu) MenuViewFormat {
+	return MenuViewFormat{
+		Menu_id:    menu.Menu_id,
+		Name:       menu.Name,
+		Category:   menu.Category,
+		Start_date: menu.Start_date,
+		End_date:   menu.End_date,
+		Created_at: menu.Created_at,
+		Updated_at: menu.Updated_at,
+		Deleted_at: menu.Deleted_at,
+		Deleted_by: v.auditOnly(menu.Deleted_by),
+		Version:    menu.Version,
+	}
+}
+
+type TableViewFormat struct {
+	Table_id         string     `json:"table_id"`
+	Table_number     *int       `json:"table_number"`
+	Number_of_guests *int       `json:"number_of_guests"`
+	Created_at       time.Time  `json:"created_at"`
+	Updated_at       time.Time  `json:"updated_at"`
+	Deleted_at       *time.Time `json:"deleted_at,omitempty"`
+	Deleted_by       *string    `json:"deleted_by,omitempty"`
+	Version          int        `json:"version"`
+}
+
+func (v viewer) table(table models.Table) TableViewFormat {
+	return TableViewFormat{
+		Table_id:         table.Table_ID,
+		Table_number:     table.Table_Number,
+		Number_of_guests: table.Number_of_guests,
+		Created_at:       table.CreatedAt,
+		Updated_at:       table.UpdatedAt,
+		Deleted_at:       table.Deleted_at,
+		Deleted_by:       v.auditOnly(table.Deleted_by),
+		Version:          table.Version,
+	}
+}
+
+type OrderViewFormat struct {
+	Order_id   string     `json:"order_id"`
+	Order_date time.Time  `json:"order_date"`
+	Table_id   *string    `json:"table_id"`
+	Created_at time.Time  `json:"created_at"`
+	Updated_at time.Time  `json:"updated_at"`
+	Deleted_at *time.Time `json:"deleted_at,omitempty"`
+	Deleted_by *string    `json:"deleted_by,omitempty"`
+	Version    int        `json:"version"`
+}
+
+func (v viewer) order(order models.Order) OrderViewFormat {
+	return OrderViewFormat{
+		Order_id:   order.Order_ID,
+		Order_date: order.Order_Date,
+		Table_id:   order.Table_ID,
+		Created_at: order.CreatedAt,
+		Updated_at: order.UpdatedAt,
+		Deleted_at: order.Deleted_at,
+		Deleted_by: v.auditOnly(order.Deleted_by),
+		Version:    order.Version,
+	}
+}
+
+type OrderItemViewFormat struct {
+	Order_item_id string                     `json:"order_item_id"`
+	Order_id      string                     `json:"order_id"`
+	Food_id       *string                    `json:"food_id"`
+	Quantity      *string                    `json:"quantity"`
+	Unit_price    *float64                   `json:"unit_price"`
+	Modifiers     []models.OrderItemModifier `json:"modifiers"`
+	Created_at    time.Time                  `json:"created_at"`
+	Updated_at    time.Time                  `json:"updated_at"`
+	Deleted_at    *time.Time                 `json:"deleted_at,omitempty"`
+	Deleted_by    *string                    `json:"deleted_by,omitempty"`
+	Version       int                        `json:"version"`
+}
+
+func (v viewer) orderItem(orderItem models.OrderItem) OrderItemViewFormat {
+	if orderItem.Modifiers == nil {
+		orderItem.Modifiers = []models.OrderItemModifier{}
+	}
+	return OrderItemViewFormat{
+		Order_item_id: orderItem.Order_Item_Id,
+		Order_id:      orderItem.Order_ID,
+		Food_id:       orderItem.Food_id,
+		Quantity:      orderItem.Quantity,
+		Unit_price:    orderItem.Unit_Price,
+		Modifiers:     orderItem.Modifiers,
+		Created_at:    orderItem.CreatedAt,
+		Updated_at:    orderItem.UpdatedAt,
+		Deleted_at:    orderItem.Deleted_at,
+		Deleted_by:    v.auditOnly(orderItem.Deleted_by),
+		Version:       orderItem.Version,
+	}
+}
+
+// OrderDetailsViewFormat is the summary of an order that store.OrderItemRepository.ItemsByOrder builds: its items
+// joined with their food and table, and what is due for them.
+type OrderDetailsViewFormat struct {
+	Order_id     string                `bson:"order_id" json:"order_id"`
+	Table_id     *string               `bson:"table_id" json:"table_id"`
+	Table_number *int                  `bson:"table_number" json:"table_number"`
+	Order_items  []OrderLineViewFormat `bson:"order_items" json:"order_items"`
+	Total_count  int                   `bson:"total_count" json:"total_count"`
+	Payment_due  float64               `bson:"payment_due" json:"payment_due"`
+}
+
+type OrderLineViewFormat struct {
+	Order_id     string                     `bson:"order_id" json:"order_id"`
+	Table_id     *string                    `bson:"table_id" json:"table_id"`
+	Table_number *int                       `bson:"table_number" json:"table_number"`
+	Food_name    *string                    `bson:"food_name" json:"food_name"`
+	Food_image   *string                    `bson:"food_image" json:"food_image"`
+	Price        *float64                   `bson:"price" json:"price"` // Current price of the food
+	Quantity     *string                    `bson:"quantity" json:"quantity"`
+	Modifiers    []models.OrderItemModifier `bson:"modifiers" json:"modifiers"`
+	Amount       *float64                   `bson:"amount" json:"amount"` // Price of the food when it was ordered, with its modifiers
+}
+
+// orderDetails reads the summaries ItemsByOrder returns.
+func orderDetails(summaries []primitive.M) ([]OrderDetailsViewFormat, error) {
+	views := []OrderDetailsViewFormat{}
+	for _, summary := range summaries {
+		data, err := bson.Marshal(summary)
+		if err != nil {
+			return nil, err
+		}
+		var view OrderDetailsViewFormat
+		if err := bson.Unmarshal(data, &view); err != nil {
+			return nil, err
+		}
+		for i := range view.Order_items {
+			if view.Order_items[i].Modifiers == nil {
+				view.Order_items[i].Modifiers = []models.OrderItemModifier{}
+			}
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// InvoiceRecordViewFormat is an invoice as it is stored. InvoiceViewFormat adds what is due for the order.
+type InvoiceRecordViewFormat struct {
+	Invoice_id       string     `json:"invoice_id"`
+	Order_id         string     `json:"order_id"`
+	Payment_method   *string    `json:"payment_method"`
+	Payment_status   *string    `json:"payment_status"`
+	Payment_due_date time.Time  `json:"payment_due_date"`
+	Created_at       time.Time  `json:"created_at"`
+	Updated_at       time.Time  `json:"updated_at"`
+	Deleted_at       *time.Time `json:"deleted_at,omitempty"`
+	Deleted_by       *string    `json:"deleted_by,omitempty"`
+	Version          int        `json:"version"`
+}
+
+func (v viewer) invoice(invoice models.Invoice) InvoiceRecordViewFormat {
+	return InvoiceRecordViewFormat{
+		Invoice_id:       invoice.Invoice_id,
+		Order_id:         invoice.Order_id,
+		Payment_method:   invoice.Payment_method,
+		Payment_status:   invoice.Payment_status,
+		Payment_due_date: invoice.Payment_due_date,
+		Created_at:       invoice.Created_at,
+		Updated_at:       invoice.Updated_at,
+		Deleted_at:       invoice.Deleted_at,
+		Deleted_by:       v.auditOnly(invoice.Deleted_by),
+		Version:          invoice.Version,
+	}
+}
+
+type ApiKeyViewFormat struct {
+	Api_key_id string     `json:"api_key_id"`
+	Name       *string    `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Expires_at *time.Time `json:"expires_at,omitempty"`
+	Created_by *string    `json:"created_by,omitempty"`
+	Created_at time.Time  `json:"created_at"`
+	Deleted_at *time.Time `json:"deleted_at,omitempty"`
+	Deleted_by *string    `json:"deleted_by,omitempty"`
+	Version    int        `json:"version"`
+}
+
+func (v viewer) apiKey(apiKey models.ApiKey) ApiKeyViewFormat {
+	return ApiKeyViewFormat{
+		Api_key_id: apiKey.Api_key_id,
+		Name:       apiKey.Name,
+		Scopes:     apiKey.Scopes,
+		Expires_at: apiKey.Expires_at,
+		Created_by: v.auditOnly(&apiKey.Created_by),
+		Created_at: apiKey.Created_at,
+		Deleted_at: apiKey.Deleted_at,
+		Deleted_by: v.auditOnly(apiKey.Deleted_by),
+		Version:    apiKey.Version,
+	}
+}
@@ -3,39 +3,33 @@ package database
 import (
 	"context"
 	"fmt"
-	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func DBInstance() *mongo.Client {
-	MongoDB := "mongodb://localhost:27017"
-	fmt.Print(MongoDB)
-
-	client, err := mongo.NewClient(options.Client().ApplyURI(MongoDB))
+// DBInstance connects to MongoDB and pings the primary, so a wrong URI or an unreachable server is reported at
+// startup instead of on the first request. The caller owns the client and must Disconnect it.
+func DBInstance(ctx context.Context, mongoURI string) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("connecting to mongodb: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	err = client.Connect(ctx)
-
-	if err != nil {
-		log.Fatal(err)
+	if err = client.Ping(ctx, readpref.Primary()); err != nil {
+		client.Disconnect(context.Background())
+		return nil, fmt.Errorf("pinging mongodb: %w", err)
 	}
 
 	fmt.Println("Connected to Mongodb")
-	return client
+	return client, nil
 }
 
-var Client *mongo.Client = DBInstance()
-
-func OpenCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	var collection *mongo.Collection = client.Database("restaurant").Collection(collectionName)
-	return collection
+func OpenDatabase(client *mongo.Client, databaseName string) *mongo.Database {
+	return client.Database(databaseName)
 }
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Handler receives the messages a subscription selected.
+type Handler func(ctx context.Context, m Message)
+
+// Publisher is what the controllers publish through. Publish is called after the write it reports has been
+// committed, so an error only means the event was lost, never that the write was.
+type Publisher interface {
+	Publish(ctx context.Context, actor string, e Event) error
+}
+
+// Bus delivers messages to the subscribers of this process. Used as the Publisher it is the in-memory event bus:
+// Publish delivers straight to the subscribers, which is all the in-memory store and the tests need. With the
+// change stream source it only delivers what Watch reads back from MongoDB, which is how subscribers in every
+// process see every event.
+type Bus struct {
+	mu          sync.RWMutex
+	next        int
+	subscribers map[int]subscription
+}
+
+type subscription struct {
+	eventType string
+	handler   Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: map[int]subscription{}}
+}
+
+// Subscribe registers handler for the messages of eventType, or for every message when eventType is empty. The
+// returned function removes the subscription again.
+func (b *Bus) Subscribe(eventType string, handler Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.subscribers[id] = subscription{eventType: eventType, handler: handler}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Publish delivers e to the subscribers of this process.
+func (b *Bus) Publish(ctx context.Context, actor string, e Event) error {
+	b.Deliver(ctx, NewMessage(actor, e))
+	return nil
+}
+
+// Deliver calls every handler subscribed to the type of m, one after the other and in the goroutine of the caller.
+// A handler with slow work to do should hand it to a goroutine of its own. A handler that panics is logged and
+// skipped, so one broken subscriber cannot fail the request that published the event.
+func (b *Bus) Deliver(ctx context.Context, m Message) {
+	b.mu.RLock()
+	var handlers []Handler
+	for id := 0; id < b.next; id++ {
+		if s, ok := b.subscribers[id]; ok && (s.eventType == "" || s.eventType == m.Type) {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("event subscriber panicked on %s %s: %v", m.Type, m.Event_id, r)
+				}
+			}()
+			handler(ctx, m)
+		}()
+	}
+}
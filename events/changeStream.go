@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// With the change stream source, publishing an event inserts it into the events collection and every process,
+// including the one that published it, reads it back through a change stream and delivers it to its own Bus.
+// Change streams need MongoDB to run as a replica set, like transactions do.
+
+// retryDelay is how long Watch waits before reopening a change stream that failed.
+const retryDelay = 5 * time.Second
+
+// MongoPublisher publishes events by inserting them into a collection that Watch reads.
+type MongoPublisher struct {
+	collection *mongo.Collection
+}
+
+func NewMongoPublisher(collection *mongo.Collection) *MongoPublisher {
+	return &MongoPublisher{collection: collection}
+}
+
+func (p *MongoPublisher) Publish(ctx context.Context, actor string, e Event) error {
+	doc, err := toDocument(NewMessage(actor, e))
+	if err != nil {
+		return err
+	}
+	_, err = p.collection.InsertOne(ctx, doc)
+	return err
+}
+
+// Watch delivers every event inserted into collection from now on to bus, until ctx is cancelled. It returns an
+// error when the change stream cannot be opened at all, for example on a standalone server, and otherwise keeps
+// watching in the background: an interrupted stream is resumed after the last event it delivered.
+func Watch(ctx context.Context, collection *mongo.Collection, bus *Bus) error {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+	stream, err := collection.Watch(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("watching %s: %w", collection.Name(), err)
+	}
+
+	go func() {
+		for {
+			err := deliver(ctx, stream, bus)
+			resumeToken := stream.ResumeToken()
+			stream.Close(context.Background())
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("event change stream interrupted, reopening: %v", err)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(retryDelay):
+				}
+				opts := options.ChangeStream()
+				if resumeToken != nil {
+					opts.SetResumeAfter(resumeToken)
+				}
+				stream, err = collection.Watch(ctx, pipeline, opts)
+				if err == nil {
+					break
+				}
+				log.Printf("reopening the event change stream failed: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// deliver reads stream until it fails or ctx is cancelled. An event that cannot be decoded is logged and skipped.
+func deliver(ctx context.Context, stream *mongo.ChangeStream, bus *Bus) error {
+	for stream.Next(ctx) {
+		var change struct {
+			FullDocument document `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			log.Printf("skipping event: %v", err)
+			continue
+		}
+		m, err := change.FullDocument.message()
+		if err != nil {
+			log.Printf("skipping event: %v", err)
+			continue
+		}
+		bus.Deliver(ctx, m)
+	}
+	return stream.Err()
+}
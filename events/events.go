@@ -0,0 +1,132 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Handlers publish a domain event after a write has been committed, and whatever has to happen as a consequence
+// (notifying the kitchen, printing a receipt, refreshing a cache) subscribes to it instead of living in the handler.
+// An event is a plain struct; its type name is what subscribers select on and what travels with it between
+// processes.
+
+// Event types.
+const (
+	OrderCreated     = "order.created"
+	OrderDeleted     = "order.deleted"
+	InvoiceCreated   = "invoice.created"
+	InvoicePaid      = "invoice.paid"
+	FoodPriceChanged = "food.price_changed"
+)
+
+// Event is implemented by every domain event.
+type Event interface {
+	EventType() string
+}
+
+// OrderCreatedEvent is published when an order is placed, with the items it was placed with, if any.
+type OrderCreatedEvent struct {
+	Order       models.Order       `bson:"order" json:"order"`
+	Order_items []models.OrderItem `bson:"order_items" json:"order_items"`
+}
+
+func (OrderCreatedEvent) EventType() string { return OrderCreated }
+
+// OrderDeletedEvent is published when an order is deleted together with its items.
+type OrderDeletedEvent struct {
+	Order       models.Order       `bson:"order" json:"order"`
+	Order_items []models.OrderItem `bson:"order_items" json:"order_items"`
+}
+
+func (OrderDeletedEvent) EventType() string { return OrderDeleted }
+
+type InvoiceCreatedEvent struct {
+	Invoice models.Invoice `bson:"invoice" json:"invoice"`
+}
+
+func (InvoiceCreatedEvent) EventType() string { return InvoiceCreated }
+
+// InvoicePaidEvent is published when an invoice reaches PAID, whether it was created paid or updated to it.
+type InvoicePaidEvent struct {
+	Invoice models.Invoice `bson:"invoice" json:"invoice"`
+}
+
+func (InvoicePaidEvent) EventType() string { return InvoicePaid }
+
+// FoodPriceChangedEvent is published when an update changes the price of a food or of one of its variants.
+type FoodPriceChangedEvent struct {
+	Food_id string   `bson:"food_id" json:"food_id"`
+	Name    *string  `bson:"name" json:"name"`
+	Before  *float64 `bson:"before" json:"before"`
+	After   *float64 `bson:"after" json:"after"`
+	// The variants of the food, when it has any. Before and After are then the lowest of their prices.
+	Before_variants []models.FoodVariant `bson:"before_variants,omitempty" json:"before_variants,omitempty"`
+	After_variants  []models.FoodVariant `bson:"after_variants,omitempty" json:"after_variants,omitempty"`
+}
+
+func (FoodPriceChangedEvent) EventType() string { return FoodPriceChanged }
+
+// decoders turn the payload of a message read from another process back into its typed event.
+var decoders = map[string]func(payload bson.Raw) (Event, error){
+	OrderCreated:     decodeAs[OrderCreatedEvent],
+	OrderDeleted:     decodeAs[OrderDeletedEvent],
+	InvoiceCreated:   decodeAs[InvoiceCreatedEvent],
+	InvoicePaid:      decodeAs[InvoicePaidEvent],
+	FoodPriceChanged: decodeAs[FoodPriceChangedEvent],
+}
+
+func decodeAs[T Event](payload bson.Raw) (Event, error) {
+	var e T
+	err := bson.Unmarshal(payload, &e)
+	return e, err
+}
+
+// Message is an event as subscribers receive it: the event and who caused it when.
+type Message struct {
+	Event_id string    `bson:"event_id" json:"event_id"`
+	Type     string    `bson:"type" json:"type"`
+	Actor_id string    `bson:"actor_id" json:"actor_id"` // user_id of whoever made the change, empty for a sign up
+	At       time.Time `bson:"at" json:"at"`
+	Event    Event     `bson:"-" json:"event"`
+}
+
+// NewMessage wraps an event published by actor now.
+func NewMessage(actor string, e Event) Message {
+	m := Message{Event_id: primitive.NewObjectID().Hex(), Type: e.EventType(), Actor_id: actor, Event: e}
+	m.At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return m
+}
+
+// document is how a message is stored in the events collection.
+type document struct {
+	Event_id string    `bson:"event_id"`
+	Type     string    `bson:"type"`
+	Actor_id string    `bson:"actor_id"`
+	At       time.Time `bson:"at"`
+	Payload  bson.Raw  `bson:"payload"`
+}
+
+func toDocument(m Message) (document, error) {
+	payload, err := bson.Marshal(m.Event)
+	if err != nil {
+		return document{}, err
+	}
+	return document{Event_id: m.Event_id, Type: m.Type, Actor_id: m.Actor_id, At: m.At, Payload: payload}, nil
+}
+
+func (d document) message() (Message, error) {
+	decode, ok := decoders[d.Type]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown event type %q", d.Type)
+	}
+	e, err := decode(d.Payload)
+	if err != nil {
+		return Message{}, fmt.Errorf("decoding %s event %s: %w", d.Type, d.Event_id, err)
+	}
+	return Message{Event_id: d.Event_id, Type: d.Type, Actor_id: d.Actor_id, At: d.At, Event: e}, nil
+}
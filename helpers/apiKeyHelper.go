@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// An API key reads rms_<api_key_id>_<secret>. The id finds the key without scanning every hash and the secret is
+// 32 random bytes, so a plain SHA-256 of it is as good as a slow password hash and cheap enough for every request.
+const apiKeyPrefix = "rms_"
+
+// GenerateApiKey returns a new key for apiKeyId and the hash to store for it.
+func GenerateApiKey(apiKeyId string) (key string, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return apiKeyPrefix + apiKeyId + "_" + encoded, HashApiKeySecret(encoded), nil
+}
+
+// ParseApiKey splits a key into its id and its secret.
+func ParseApiKey(key string) (apiKeyId string, secret string, ok bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", "", false
+	}
+	apiKeyId, secret, ok = strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	return apiKeyId, secret, ok && apiKeyId != "" && secret != ""
+}
+
+func HashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyApiKeySecret compares in constant time, so the response time does not tell how much of a guess was right.
+func VerifyApiKeySecret(secret string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashApiKeySecret(secret)), []byte(hash)) == 1
+}
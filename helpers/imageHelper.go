@@ -0,0 +1,133 @@
+package helpers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// ImageOriginal is the size name of an uploaded image as it was uploaded.
+const ImageOriginal = "original"
+
+// ImageSizes are the thumbnails made of every uploaded image, by name, with the longest side they are scaled down
+// to. An image that is already smaller gets no thumbnail of that size; its original is served instead.
+var ImageSizes = map[string]int{
+	"thumbnail": 160,
+	"medium":    640,
+}
+
+// ImageTypes are the content types an upload may have. The type is detected from the bytes, whatever the client
+// claims.
+var ImageTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// maxImagePixels refuses images that would take too much memory to decode, however small their file is.
+const maxImagePixels = 40_000_000
+
+// ErrUnsupportedImage is returned for an upload that is not a JPEG, PNG or GIF image, or that cannot be decoded.
+var ErrUnsupportedImage = errors.New("the image must be a JPEG, PNG or GIF")
+
+// ImageVariant is one size of an image, encoded.
+type ImageVariant struct {
+	Content_type string
+	Width        int
+	Height       int
+	Data         []byte
+}
+
+// ResizeImage checks that data is an image of one of the ImageTypes and makes its thumbnails. It returns the
+// original under ImageOriginal and one variant per size it made. Thumbnails of a JPEG are JPEGs, those of a PNG or
+// a GIF are PNGs, which keep transparency.
+func ResizeImage(data []byte) (map[string]ImageVariant, error) {
+	detected := mimetype.Detect(data)
+	if !mimetype.EqualsAny(detected.String(), ImageTypes...) {
+		return nil, fmt.Errorf("%w, not %s", ErrUnsupportedImage, detected.String())
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("the image must have at most %d pixels", maxImagePixels)
+	}
+	var src image.Image
+	switch detected.String() {
+	case "image/jpeg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		src, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		// An animation is served as it was uploaded; its thumbnails show the first frame.
+		src, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	bounds := src.Bounds()
+	variants := map[string]ImageVariant{
+		ImageOriginal: {Content_type: detected.String(), Width: bounds.Dx(), Height: bounds.Dy(), Data: data},
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	for size, longest := range ImageSizes {
+		width, height := bounds.Dx(), bounds.Dy()
+		if max(width, height) <= longest {
+			continue
+		}
+		if width >= height {
+			width, height = longest, max(1, height*longest/width)
+		} else {
+			width, height = max(1, width*longest/height), longest
+		}
+		var encoded bytes.Buffer
+		contentType := "image/png"
+		if detected.Is("image/jpeg") {
+			contentType = "image/jpeg"
+			err = jpeg.Encode(&encoded, shrink(rgba, width, height), &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&encoded, shrink(rgba, width, height))
+		}
+		if err != nil {
+			return nil, err
+		}
+		variants[size] = ImageVariant{Content_type: contentType, Width: width, Height: height, Data: encoded.Bytes()}
+	}
+	return variants, nil
+}
+
+// shrink scales src down to width by height, averaging the block of source pixels that falls on each target pixel.
+// It only ever shrinks, which is all thumbnails need, and keeps the result sharp without an imaging library.
+func shrink(src *image.RGBA, width int, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcHeight/height, max((y+1)*srcHeight/height, y*srcHeight/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcWidth/width, max((x+1)*srcWidth/width, x*srcWidth/width+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride+x0*4 : sy*src.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			at := y*dst.Stride + x*4
+			for i := range sum {
+				dst.Pix[at+i] = uint8(sum[i] / n)
+			}
+		}
+	}
+	return dst
+}
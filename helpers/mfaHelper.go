@@ -0,0 +1,115 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"image/png"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MfaChallengeTTL is how long a user whose password was right has to type the code.
+const MfaChallengeTTL = 5 * time.Minute
+
+// MfaSecretPurpose is what the TOTP secrets are sealed for, see SealSecret.
+const MfaSecretPurpose = "mfa secrets"
+
+const totpPeriod = 30
+
+// MfaProvisioning is what an authenticator app needs to be set up: the secret, the otpauth:// URI with it and the
+// same URI as a QR code, a PNG in a data URI.
+type MfaProvisioning struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+	Qr     string `json:"qr"`
+}
+
+// NewMfaSecret generates a TOTP secret for the account and returns it with its provisioning details.
+func NewMfaSecret(issuer string, account string) (MfaProvisioning, error) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: issuer, AccountName: account, Period: totpPeriod})
+	if err != nil {
+		return MfaProvisioning{}, err
+	}
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return MfaProvisioning{}, err
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return MfaProvisioning{}, err
+	}
+	return MfaProvisioning{
+		Secret: key.Secret(),
+		Uri:    key.URL(),
+		Qr:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes()),
+	}, nil
+}
+
+// VerifyTotp checks code against secret at now, allowing one period of clock drift either way. It returns the time
+// step the code belongs to. A code of a step at or before lastStep is refused, so a code that was seen once cannot
+// be used again.
+func VerifyTotp(secret string, code string, lastStep int64, now time.Time) (step int64, ok bool) {
+	current := now.Unix() / totpPeriod
+	for _, s := range []int64{current - 1, current, current + 1} {
+		if s <= lastStep {
+			continue
+		}
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(s*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(strings.TrimSpace(code))) == 1 {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// NewRecoveryCodes returns n single use codes, like "k3m9q-x7r2p", and the hashes to store for them.
+func NewRecoveryCodes(n int) (codes []string, hashes []string, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))[:10]
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode ignores case, spaces and dashes, which people tend to get wrong when typing a code.
+func HashRecoveryCode(code string) string {
+	normalised := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalised))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateMfaChallenge issues the token Login hands out instead of a token pair when a TOTP code is still missing.
+func GenerateMfaChallenge(uid string) (string, error) {
+	issued := time.Now()
+	return signToken(&SignedDetails{
+		UId:          uid,
+		Token_type:   MfaChallengeToken,
+		Issued_at_ms: issued.UnixMilli(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        primitive.NewObjectID().Hex(),
+			IssuedAt:  issued.Unix(),
+			ExpiresAt: time.Now().Add(MfaChallengeTTL).Unix(),
+		},
+	})
+}
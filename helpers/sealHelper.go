@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// SealSecret encrypts a secret the server has to read back, like a private key, with AES-GCM under a key derived
+// from the secret key and purpose, so a copy of the database alone does not reveal it. Each purpose gets its own
+// key, so a value sealed for one cannot be passed off as another.
+func SealSecret(purpose string, plain []byte) ([]byte, error) {
+	gcm, err := secretCipher(purpose)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// OpenSecret decrypts what SealSecret sealed for purpose. It fails when the secret key has changed since.
+func OpenSecret(purpose string, sealed []byte) ([]byte, error) {
+	gcm, err := secretCipher(purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed secret is too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func secretCipher(purpose string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(purpose + "\x00" + SECRET_KEY))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,303 @@
+package helpers
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// With RS256 or EdDSA tokens are signed by key pairs kept in the signing_keys collection, so every instance of the
+// server signs with the same key and accepts what the others signed. Each key signs for the rotation period. The
+// next key is created an hour before the current one retires and published in the JWKS right away, so services
+// that cache the JWKS know it before the first token signed with it reaches them. A retired key still verifies
+// until the last token it signed has expired.
+
+// keyPublishAhead must stay well above the max-age the JWKS is served with.
+const keyPublishAhead = time.Hour
+
+const keyRefreshInterval = time.Minute
+
+// Private keys are stored sealed for this purpose, see SealSecret.
+const signingKeyPurpose = "signing keys"
+
+// JWK is one public key of the JWKS, in the members RFC 7517 and RFC 8037 define for RSA and Ed25519 keys.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type ringKey struct {
+	stored  models.SigningKey
+	method  jwt.SigningMethod
+	public  crypto.PublicKey
+	private crypto.Signer // nil when the key was sealed with another secret key
+	jwk     JWK
+}
+
+// keyRing is what the instance currently knows of the signing keys. It is replaced as a whole on every refresh.
+type keyRing struct {
+	signing *ringKey
+	keys    map[string]*ringKey
+	jwks    []JWK
+}
+
+var ring struct {
+	mu sync.RWMutex
+	keyRing
+}
+
+// StartSigningKeys loads the signing keys, creating the first one when there is none, and keeps them up to date
+// and rotated until ctx is done. With HS256 it does nothing.
+func StartSigningKeys(ctx context.Context, signingKeys store.SigningKeyRepository) error {
+	if signingAlgorithm == "HS256" {
+		return nil
+	}
+	if err := refreshSigningKeys(ctx, signingKeys); err != nil {
+		return fmt.Errorf("loading the signing keys: %w", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(keyRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := refreshSigningKeys(ctx, signingKeys); err != nil {
+				log.Printf("refreshing the signing keys failed: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// refreshSigningKeys reads the keys again and creates the next key when it is due. Two instances may create a key
+// at the same moment; both are published, and the one activated last signs.
+func refreshSigningKeys(ctx context.Context, signingKeys store.SigningKeyRepository) error {
+	stored, err := signingKeys.List(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	next := buildKeyRing(stored, now)
+
+	activeFrom := time.Time{}
+	switch {
+	case next.signing == nil:
+		// The first start, a change of algorithm or a change of the secret key: nothing can sign, so the new key
+		// has to start at once.
+		activeFrom = now
+	case !hasPendingKey(next, now) && next.signing.stored.Retire_at.Sub(now) <= keyPublishAhead:
+		activeFrom = next.signing.stored.Retire_at
+	}
+	if !activeFrom.IsZero() {
+		key, err := newSigningKey(now, activeFrom)
+		if err != nil {
+			return err
+		}
+		if err := signingKeys.Create(ctx, key); err != nil {
+			return err
+		}
+		log.Printf("created signing key %s, it signs from %s", key.Kid, key.Active_from.Format(time.RFC3339))
+		next = buildKeyRing(append(stored, key), now)
+	}
+
+	ring.mu.Lock()
+	ring.keyRing = next
+	ring.mu.Unlock()
+	return nil
+}
+
+func buildKeyRing(stored []models.SigningKey, now time.Time) keyRing {
+	next := keyRing{keys: map[string]*ringKey{}, jwks: []JWK{}}
+	for _, s := range stored {
+		key, err := loadSigningKey(s)
+		if err != nil {
+			log.Printf("skipping signing key %s: %v", s.Kid, err)
+			continue
+		}
+		next.keys[s.Kid] = key
+		next.jwks = append(next.jwks, key.jwk)
+
+		signs := key.private != nil && s.Algorithm == signingAlgorithm &&
+			!s.Active_from.After(now) && s.Retire_at.After(now)
+		if signs && (next.signing == nil || !s.Active_from.Before(next.signing.stored.Active_from)) {
+			next.signing = key
+		}
+	}
+	return next
+}
+
+// hasPendingKey reports whether a key that will sign in the future already exists.
+func hasPendingKey(r keyRing, now time.Time) bool {
+	for _, key := range r.keys {
+		if key.private != nil && key.stored.Algorithm == signingAlgorithm && key.stored.Active_from.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func newSigningKey(now time.Time, activeFrom time.Time) (models.SigningKey, error) {
+	var public crypto.PublicKey
+	var private crypto.Signer
+	switch signingAlgorithm {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return models.SigningKey{}, err
+		}
+		public, private = &key.PublicKey, key
+	case "EdDSA":
+		publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return models.SigningKey{}, err
+		}
+		public, private = publicKey, privateKey
+	default:
+		return models.SigningKey{}, fmt.Errorf("cannot create a key for %s", signingAlgorithm)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+	sealed, err := SealSecret(signingKeyPurpose, privateDER)
+	if err != nil {
+		return models.SigningKey{}, err
+	}
+
+	key := models.SigningKey{
+		ID:          primitive.NewObjectID(),
+		Algorithm:   signingAlgorithm,
+		Public_key:  publicDER,
+		Private_key: sealed,
+		Created_at:  now,
+		Active_from: activeFrom,
+		Retire_at:   activeFrom.Add(keyRotation),
+	}
+	key.Kid = key.ID.Hex()
+	// Refresh tokens live longer than access tokens, so the last one signed expires RefreshTokenTTL after the key
+	// retires.
+	key.Expires_at = key.Retire_at.Add(RefreshTokenTTL)
+	return key, nil
+}
+
+func loadSigningKey(s models.SigningKey) (*ringKey, error) {
+	public, err := x509.ParsePKIXPublicKey(s.Public_key)
+	if err != nil {
+		return nil, err
+	}
+	key := &ringKey{stored: s, public: public, jwk: JWK{Kid: s.Kid, Use: "sig", Alg: s.Algorithm}}
+	switch p := public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+		key.jwk.Kty = "RSA"
+		key.jwk.N = base64.RawURLEncoding.EncodeToString(p.N.Bytes())
+		key.jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.E)).Bytes())
+	case ed25519.PublicKey:
+		key.method = SigningMethodEdDSA
+		key.jwk.Kty = "OKP"
+		key.jwk.Crv = "Ed25519"
+		key.jwk.X = base64.RawURLEncoding.EncodeToString(p)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+	if key.method.Alg() != s.Algorithm {
+		return nil, fmt.Errorf("the public key does not fit algorithm %s", s.Algorithm)
+	}
+
+	// A key sealed with an earlier secret key still verifies the tokens it signed, it just cannot sign any more.
+	privateDER, err := OpenSecret(signingKeyPurpose, s.Private_key)
+	if err != nil {
+		return key, nil
+	}
+	private, err := x509.ParsePKCS8PrivateKey(privateDER)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+	key.private = signer
+	return key, nil
+}
+
+// JWKS returns the public keys tokens are verified with. It is empty with HS256, whose key must stay secret.
+func JWKS() []JWK {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	if ring.jwks == nil {
+		return []JWK{}
+	}
+	return ring.jwks
+}
+
+// signToken signs claims with the configured algorithm. An asymmetrically signed token names its key in kid.
+func signToken(claims jwt.Claims) (string, error) {
+	if signingAlgorithm == "HS256" {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
+	}
+
+	ring.mu.RLock()
+	key := ring.signing
+	ring.mu.RUnlock()
+	if key == nil {
+		return "", errors.New("no signing key is available")
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.stored.Kid
+	return token.SignedString(key.private)
+}
+
+// verificationKey is the jwt.Keyfunc of ValidateToken. A token is only accepted with the algorithm of the key it
+// names, so a token cannot pass one kind of key off as another. Switching from HS256 to an asymmetric algorithm
+// therefore logs every user out once.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if signingAlgorithm == "HS256" {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return []byte(SECRET_KEY), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	ring.mu.RLock()
+	key := ring.keys[kid]
+	ring.mu.RUnlock()
+	if key == nil {
+		return nil, errors.New("the token was signed with an unknown key")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.public, nil
+}
@@ -3,54 +3,110 @@ package helpers
 import (
 	"context"
 	"log"
-	"os"
-	"restaurant-management-system/database"
+	"restaurant-management-system/config"
+	"restaurant-management-system/store"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
+// SECRET_KEY, the signing algorithm and the token lifetimes are set once at startup by Configure.
+var SECRET_KEY string
 
-var SECRET_KEY string = os.Getenv("SECRET_KEY")
+var signingAlgorithm = "HS256"
+var keyRotation = 30 * 24 * time.Hour
+
+var AccessTokenTTL = 24 * time.Hour
+var RefreshTokenTTL = 168 * time.Hour
+
+func Configure(cfg config.Config) {
+	SECRET_KEY = cfg.SecretKey
+	signingAlgorithm = cfg.JWTAlgorithm
+	keyRotation = cfg.JWTKeyRotation.Duration
+	AccessTokenTTL = cfg.AccessTokenTTL.Duration
+	RefreshTokenTTL = cfg.RefreshTokenTTL.Duration
+}
+
+// Token types. A refresh token is only accepted by the refresh endpoint, an MFA challenge only by the second step
+// of a login and an access token only by the Authentication middleware. Tokens issued before the type was carried
+// have none and count as access tokens.
+const (
+	AccessToken       = "access"
+	RefreshToken      = "refresh"
+	MfaChallengeToken = "mfa_challenge"
+)
 
 type SignedDetails struct {
 	Email      string
 	First_name string
 	Last_name  string
 	UId        string
+	Role       string // the User_type of the user when the token was issued
+	Token_type string
+	// Family is shared by every token pair descending from one login through refreshes. Reusing a refresh token that
+	// was already rotated revokes the family.
+	Family string
+	// Issued_at_ms is IssuedAt in milliseconds. Revocations are compared against it: IssuedAt only has whole seconds,
+	// which cannot tell a token issued just before a revocation from one issued just after it.
+	Issued_at_ms int64
 	jwt.StandardClaims
 }
 
-func GenerateAllTokens(email string, firstName string, lastName string, uid string) (signedToken string, signedRefreshToken string, err error) {
+// Issued is when the token was issued, to the millisecond for tokens that carry Issued_at_ms.
+func (claims *SignedDetails) Issued() time.Time {
+	if claims.Issued_at_ms != 0 {
+		return time.UnixMilli(claims.Issued_at_ms)
+	}
+	return time.Unix(claims.IssuedAt, 0)
+}
+
+// NewTokenFamily starts the family of a login.
+func NewTokenFamily() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// GenerateAllTokens issues an access token and a refresh token in family. Every token gets its own id, so two pairs
+// issued in the same second still differ.
+func GenerateAllTokens(email string, firstName string, lastName string, uid string, role string, family string) (signedToken string, signedRefreshToken string, err error) {
+	issued := time.Now()
 	claims := &SignedDetails{
-		Email:      email,
-		First_name: firstName,
-		Last_name:  lastName,
-		UId:        uid,
+		Email:        email,
+		First_name:   firstName,
+		Last_name:    lastName,
+		UId:          uid,
+		Role:         role,
+		Token_type:   AccessToken,
+		Family:       family,
+		Issued_at_ms: issued.UnixMilli(),
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
+			Id:        primitive.NewObjectID().Hex(),
+			IssuedAt:  issued.Unix(),
+			ExpiresAt: time.Now().Local().Add(AccessTokenTTL).Unix(),
 		},
 	}
 
 	refreshClaims := &SignedDetails{
+		UId:          uid,
+		Token_type:   RefreshToken,
+		Family:       family,
+		Issued_at_ms: issued.UnixMilli(),
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
+			Id:        primitive.NewObjectID().Hex(),
+			IssuedAt:  issued.Unix(),
+			ExpiresAt: time.Now().Local().Add(RefreshTokenTTL).Unix(),
 		},
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
+	token, err := signToken(claims)
 
 	if err != nil {
 		log.Panic(err)
 		return
 	}
 
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(SECRET_KEY))
+	refreshToken, err := signToken(refreshClaims)
 
 	if err != nil {
 		log.Panic(err)
@@ -60,45 +116,42 @@ func GenerateAllTokens(email string, firstName string, lastName string, uid stri
 	return token, refreshToken, err
 }
 
-func UpdateAllTokens(signedToken string, signedRefreshToken string, userId string) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
+// UpdateAllTokens stores the token pair of family on the user, which makes signedRefreshToken the only refresh token
+// of the user that is accepted. With a version other than 0 it returns store.ErrConflict when the user changed in
+// the meantime, like the other updates.
+func UpdateAllTokens(ctx context.Context, users store.UserRepository, signedToken string, signedRefreshToken string, family string, userId string, version int) error {
 	var updateObj primitive.D
 
 	updateObj = append(updateObj, bson.E{Key: "token", Value: signedToken})
 	updateObj = append(updateObj, bson.E{Key: "refresh_token", Value: signedRefreshToken})
+	updateObj = append(updateObj, bson.E{Key: "token_family", Value: family})
 
 	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	updateObj = append(updateObj, bson.E{Key: "updated_at", Value: Updated_at})
 
-	upsert := true
-	filter := bson.M{"user_id": userId}
-	opt := options.UpdateOptions{
-		Upsert: &upsert,
-	}
+	return users.Update(ctx, userId, updateObj, version)
+}
 
-	_, err := userCollection.UpdateOne(
-		ctx,
-		filter,
-		bson.D{bson.E{Key: "$set", Value: updateObj}},
-		&opt,
-	)
+// RevokeTokens clears the stored tokens of the user, so no refresh token of theirs is accepted until they log in
+// again.
+func RevokeTokens(ctx context.Context, users store.UserRepository, userId string) error {
+	var updateObj primitive.D
 
-	defer cancel()
+	updateObj = append(updateObj, bson.E{Key: "token", Value: nil})
+	updateObj = append(updateObj, bson.E{Key: "refresh_token", Value: nil})
+	updateObj = append(updateObj, bson.E{Key: "token_family", Value: nil})
 
-	if err != nil {
-		log.Panic(err)
-		return
-	}
+	Updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	updateObj = append(updateObj, bson.E{Key: "updated_at", Value: Updated_at})
+
+	return users.Update(ctx, userId, updateObj, 0)
 }
 
 func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
 	token, err := jwt.ParseWithClaims(
 		signedToken,
 		&SignedDetails{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(SECRET_KEY), nil
-		},
+		verificationKey,
 	)
 
 	if err != nil {
@@ -0,0 +1,189 @@
+package integrity
+
+import (
+	"context"
+	"time"
+
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The collections reference each other by their custom string ids and nothing in MongoDB keeps those references
+// consistent. This package finds the references that point at a document that does not exist and, when asked,
+// repairs the ones that can be repaired without a human deciding what the data should have been. A deleted document
+// counts as missing, and deleted documents are not checked themselves.
+
+// Repair actions.
+const (
+	// RepairDelete soft deletes the referencing document. Used when it means nothing without its parent, like an
+	// order item whose order is gone.
+	RepairDelete = "delete"
+	// RepairUnset clears the dangling reference and keeps the document.
+	RepairUnset = "unset"
+	// RepairManual is reported only. Deleting a food, an item of a real order or an invoice would lose data someone
+	// cares about.
+	RepairManual = "manual"
+)
+
+// Problem is one dangling reference.
+type Problem struct {
+	Collection string `json:"collection"`
+	Id         string `json:"id"`
+	Field      string `json:"field"`
+	Missing    string `json:"missing"` // the id the field points at
+	Repair     string `json:"repair"`
+	Repaired   bool   `json:"repaired"`
+	Error      string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Checked_at time.Time `json:"checked_at"`
+	DryRun     bool      `json:"dry_run"`
+	Problems   []Problem `json:"problems"`
+	Repaired   int       `json:"repaired"`
+}
+
+// pageSize is how many documents are read per page while collecting them.
+const pageSize = 500
+
+// all reads every page of a listing.
+func all[T any](ctx context.Context, list func(ctx context.Context, page store.Page) (store.Paged[T], error)) ([]T, error) {
+	var items []T
+	page := store.Page{Limit: pageSize}
+	for {
+		paged, err := list(ctx, page)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, paged.Items...)
+		if paged.Next_cursor == "" {
+			return items, nil
+		}
+		page.Cursor = paged.Next_cursor
+	}
+}
+
+// live lists the documents that are not deleted.
+func live[T any](list func(ctx context.Context, includeDeleted bool, page store.Page) (store.Paged[T], error)) func(ctx context.Context, page store.Page) (store.Paged[T], error) {
+	return func(ctx context.Context, page store.Page) (store.Paged[T], error) {
+		return list(ctx, false, page)
+	}
+}
+
+// Check reports every dangling reference without changing anything.
+func Check(ctx context.Context, s *store.Store) (Report, error) {
+	return run(ctx, s, false, "")
+}
+
+// Repair applies the repair of every problem whose action is not manual. With apply false it only reports what
+// it would do, which is what the check endpoint and a dry run show. by is recorded as deleted_by on the documents
+// it deletes.
+func Repair(ctx context.Context, s *store.Store, apply bool, by string) (Report, error) {
+	return run(ctx, s, apply, by)
+}
+
+func run(ctx context.Context, s *store.Store, apply bool, by string) (Report, error) {
+	report := Report{DryRun: !apply, Problems: []Problem{}}
+	report.Checked_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	menus, err := all(ctx, live(s.Menus.List))
+	if err != nil {
+		return report, err
+	}
+	menuIds := map[string]bool{}
+	for _, menu := range menus {
+		menuIds[menu.Menu_id] = true
+	}
+
+	foods, err := all(ctx, func(ctx context.Context, page store.Page) (store.Paged[models.Food], error) {
+		return s.Foods.List(ctx, store.FoodFilter{}, page)
+	})
+	if err != nil {
+		return report, err
+	}
+	foodIds := map[string]bool{}
+	for _, food := range foods {
+		foodIds[food.Food_id] = true
+	}
+
+	tables, err := all(ctx, live(s.Tables.List))
+	if err != nil {
+		return report, err
+	}
+	tableIds := map[string]bool{}
+	for _, table := range tables {
+		tableIds[table.Table_ID] = true
+	}
+
+	orders, err := all(ctx, live(s.Orders.List))
+	if err != nil {
+		return report, err
+	}
+	orderIds := map[string]bool{}
+	for _, order := range orders {
+		orderIds[order.Order_ID] = true
+	}
+
+	orderItems, err := all(ctx, live(s.OrderItems.List))
+	if err != nil {
+		return report, err
+	}
+	invoices, err := all(ctx, live(s.Invoices.List))
+	if err != nil {
+		return report, err
+	}
+
+	for _, food := range foods {
+		if food.Menu_id != nil && !menuIds[*food.Menu_id] {
+			report.add(Problem{Collection: store.FoodCollection, Id: food.Food_id, Field: "menu_id", Missing: *food.Menu_id, Repair: RepairManual})
+		}
+	}
+	for _, order := range orders {
+		if order.Table_ID != nil && !tableIds[*order.Table_ID] {
+			p := Problem{Collection: store.OrderCollection, Id: order.Order_ID, Field: "table_id", Missing: *order.Table_ID, Repair: RepairUnset}
+			if apply {
+				p.done(s.Orders.Update(ctx, order.Order_ID, primitive.D{bson.E{Key: "table_id", Value: nil}}, order.Version))
+			}
+			report.add(p)
+		}
+	}
+	for _, orderItem := range orderItems {
+		if !orderIds[orderItem.Order_ID] {
+			p := Problem{Collection: store.OrderItemCollection, Id: orderItem.Order_Item_Id, Field: "order_id", Missing: orderItem.Order_ID, Repair: RepairDelete}
+			if apply {
+				p.done(s.OrderItems.Delete(ctx, orderItem.Order_Item_Id, store.Deletion{At: report.Checked_at, By: by}))
+			}
+			report.add(p)
+			// A deleted item needs no further checks.
+			continue
+		}
+		if orderItem.Food_id != nil && !foodIds[*orderItem.Food_id] {
+			report.add(Problem{Collection: store.OrderItemCollection, Id: orderItem.Order_Item_Id, Field: "food_id", Missing: *orderItem.Food_id, Repair: RepairManual})
+		}
+	}
+	for _, invoice := range invoices {
+		if !orderIds[invoice.Order_id] {
+			report.add(Problem{Collection: store.InvoiceCollection, Id: invoice.Invoice_id, Field: "order_id", Missing: invoice.Order_id, Repair: RepairManual})
+		}
+	}
+
+	return report, nil
+}
+
+func (p *Problem) done(err error) {
+	if err != nil {
+		p.Error = err.Error()
+		return
+	}
+	p.Repaired = true
+}
+
+func (r *Report) add(p Problem) {
+	r.Problems = append(r.Problems, p)
+	if p.Repaired {
+		r.Repaired++
+	}
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"restaurant-management-system/config"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers the emails the server sends on its own, like password reset links. The handlers send after
+// their change has been committed, so a failed send never undoes the change.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// New builds the mailer cfg.Mailer names: "smtp" sends through an SMTP server, "file" writes every message to
+// cfg.MailDir and "memory" keeps them in process, which is only useful to tests and demos.
+func New(cfg config.Config) (Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "file":
+		return NewFileMailer(cfg.MailDir, cfg.MailFrom), nil
+	case "memory":
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+}
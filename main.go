@@ -1,48 +1,45 @@
 package main
 
 import (
+	"context"
+	"log"
 	"os"
-	"restaurant-management-system/database"
-	middleware "restaurant-management-system/middleware"
-	routes "restaurant-management-system/routes"
-
-	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
+	"os/signal"
+	"restaurant-management-system/app"
+	"restaurant-management-system/config"
+	"syscall"
 )
 
-// foodCollection :- This is a variable that holds a reference to the food collection in your MongoDB database. It has the type *mongo.Collection, meaning it's a pointer to a mongo.Collection object (part of the official MongoDB Go driver
-
-// used to create a MongoDB collection
-var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "food")
-
-// OpenCollection function to get a collection from the database
-func OpenCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	// Assuming you have a specific database, like "mydb"
-	collection := client.Database("mydb").Collection(collectionName)
-	return collection
-}
-
 func main() {
-	port := os.Getenv(("PORT"))
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "integrity":
+			runIntegrity(os.Args[2:])
+			return
+		}
+	}
 
-	if port == "" {
-		port = "8000"
+	// config.Load refuses to return a usable configuration without a signing key, so the server never starts with
+	// tokens that anyone could forge.
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
 	}
-	// t creates a new instance of the Gin engine (router) without any default middleware.
-	router := gin.New()
-	// gin.Logger() is a built-in middleware provided by the Gin framework. It logs details about each HTTP request the router receives and the corresponding response. This helps in debugging and monitoring the behavior of your application
-	// gin.Logger() logs important information like HTTP methods, paths, response status codes, client IP addresses, and request processing time.
-	router.Use(gin.Logger())
-	routes.UserRoutes(router)
-	// used to attach custom authentication middleware to your Gin router. Middleware in Gin acts like a filter that processes every request before it reaches your route handlers. This particular middleware is for authentication, ensuring that only users who are authenticated (logged in or have valid credentials) can access certain routes.
-	router.Use(middleware.Authentication())
 
-	routes.FoodRoutes(router)
-	routes.MenuRoutes(router)
-	routes.TableRoutes(router)
-	routes.OrderRoutes(router)
-	routes.OrderItemRoutes(router)
-	routes.InvoiceRoutes(router)
+	// ctx is cancelled on SIGINT or SIGTERM, which starts the graceful shutdown in App.Run.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a, err := app.New(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	router.Run(":" + port)
+	if err := a.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("server stopped")
 }
@@ -1,34 +1,163 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"restaurant-management-system/helpers"
+	"restaurant-management-system/store"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Authentication() gin.HandlerFunc {
+// Authentication accepts either a valid access token that has not been revoked or an API key. Checking revocations
+// costs a query per request; it is what makes a logout, a revoked session or the deletion or deactivation of a user
+// take effect before the token expires.
+//
+// A missing or unusable credential is answered with 401 and a WWW-Authenticate challenge. Whether the caller may do
+// what it asked is decided afterwards by Authorize, which answers 403.
+func Authentication(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		clientToken := c.Request.Header.Get("token")
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			authenticateApiKey(c, s, key)
+			return
+		}
+
+		clientToken := BearerToken(c)
 		if clientToken == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "No Authorization header provided"})
-			c.Abort()
+			unauthorized(c, "", "an access token is required: send Authorization: Bearer <token>")
 			return
 		}
 
 		claims, err := helpers.ValidateToken(clientToken)
 		if err != "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+			unauthorized(c, "invalid_token", err)
+			return
+		}
+
+		if claims.Token_type == helpers.RefreshToken {
+			unauthorized(c, "invalid_token", "a refresh token cannot be used to authenticate, exchange it at /users/refresh")
+			return
+		}
+		// Tokens issued before token types existed have none and are access tokens.
+		if claims.Token_type != helpers.AccessToken && claims.Token_type != "" {
+			unauthorized(c, "invalid_token", "this token cannot be used to authenticate, finish the login first")
+			return
+		}
+
+		revoked, revokedErr := s.Revocations.IsRevoked(c.Request.Context(), claims.Id, claims.UId, claims.Issued())
+		if revokedErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the token"})
 			c.Abort()
 			return
 		}
+		if revoked {
+			unauthorized(c, "invalid_token", "this token was revoked, log in again")
+			return
+		}
 
 		c.Set("email", claims.Email)
 		c.Set("first_name", claims.First_name)
 		c.Set("last_name", claims.Last_name)
 		c.Set("uid", claims.UId)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
 
 		c.Next()
 
 	}
 }
+
+// BearerToken returns the access token of the request. It comes from Authorization: Bearer, or from the token
+// header older clients send.
+func BearerToken(c *gin.Context) string {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return c.GetHeader("token")
+}
+
+// authenticateApiKey lets a request with a live API key through. Its uid is api_key:<api_key_id>, which is how
+// the audit log and deleted_by name it, and it is authorized by the scopes of the key instead of a role.
+func authenticateApiKey(c *gin.Context, s *store.Store, key string) {
+	apiKeyId, secret, ok := helpers.ParseApiKey(key)
+	if !ok {
+		unauthorized(c, "invalid_token", "the API key is malformed")
+		return
+	}
+	apiKey, err := s.ApiKeys.FindByID(c.Request.Context(), apiKeyId)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the API key"})
+		c.Abort()
+		return
+	}
+	if err != nil || !helpers.VerifyApiKeySecret(secret, apiKey.Key_hash) {
+		unauthorized(c, "invalid_token", "the API key is invalid or revoked")
+		return
+	}
+	if apiKey.Expires_at != nil && !apiKey.Expires_at.After(time.Now()) {
+		unauthorized(c, "invalid_token", "the API key has expired")
+		return
+	}
+	active, err := creatorActive(c.Request.Context(), s, apiKey.Created_by)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the API key"})
+		c.Abort()
+		return
+	}
+	if !active {
+		unauthorized(c, "invalid_token", "the creator of the API key was deactivated or deleted")
+		return
+	}
+
+	c.Set("uid", "api_key:"+apiKey.Api_key_id)
+	c.Set("api_key_id", apiKey.Api_key_id)
+	c.Set("scopes", apiKey.Scopes)
+
+	c.Next()
+}
+
+// maxApiKeyChain bounds how many keys that created keys creatorActive follows back to a user.
+const maxApiKeyChain = 8
+
+// creatorActive reports whether createdBy, the creator of an API key, can still act. A user must be neither deleted
+// nor deactivated; a key must be live and unexpired and have an active creator itself. The check is made on every
+// request, so a key stops working when its creator is deactivated and works again when they are reactivated.
+func creatorActive(ctx context.Context, s *store.Store, createdBy string) (bool, error) {
+	for range maxApiKeyChain {
+		apiKeyId, byKey := strings.CutPrefix(createdBy, "api_key:")
+		if !byKey {
+			user, err := s.Users.FindByID(ctx, createdBy)
+			if errors.Is(err, store.ErrNotFound) {
+				return false, nil
+			}
+			return err == nil && user.Deactivated_at == nil, err
+		}
+		apiKey, err := s.ApiKeys.FindByID(ctx, apiKeyId)
+		if errors.Is(err, store.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if apiKey.Expires_at != nil && !apiKey.Expires_at.After(time.Now()) {
+			return false, nil
+		}
+		createdBy = apiKey.Created_by
+	}
+	return false, nil
+}
+
+// unauthorized answers 401 with the challenge of RFC 6750. errorCode is empty when no credential was sent at all.
+func unauthorized(c *gin.Context, errorCode string, message string) {
+	challenge := `Bearer realm="restaurant-management-system"`
+	if errorCode != "" {
+		challenge += `, error="` + errorCode + `"`
+	}
+	c.Header("WWW-Authenticate", challenge)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	c.Abort()
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"restaurant-management-system/models"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission names something a route lets a caller do. Routes ask for a permission, not for a role, so changing
+// what a role may do only means changing rolePermissions.
+type Permission string
+
+const (
+	ReadMenu      Permission = "menu:read"       // list menus and foods
+	WriteMenu     Permission = "menu:write"      // create, change and delete menus and foods, including prices
+	ReadTables    Permission = "tables:read"     // list tables
+	WriteTables   Permission = "tables:write"    // create, change and delete tables
+	ReadOrders    Permission = "orders:read"     // list orders and their items
+	WriteOrders   Permission = "orders:write"    // take orders and change or void their items
+	ReadInvoices  Permission = "invoices:read"   // list invoices
+	WriteInvoices Permission = "invoices:write"  // create invoices and mark them paid
+	ReadUsers     Permission = "users:read"      // list users
+	ReadContacts  Permission = "users:contacts"  // see the phone numbers of other users
+	ManageUsers   Permission = "users:manage"    // create staff, change roles, deactivate, delete and restore users
+	ReadAudit     Permission = "audit:read"      // read the audit log
+	ManageApiKeys Permission = "api_keys:manage" // issue and revoke API keys
+	Maintain      Permission = "maintain"        // check and repair the data
+)
+
+var rolePermissions = map[string][]Permission{
+	models.RoleAdmin: {
+		ReadMenu, WriteMenu, ReadTables, WriteTables, ReadOrders, WriteOrders, ReadInvoices, WriteInvoices,
+		ReadUsers, ReadContacts, ManageUsers, ReadAudit, ManageApiKeys, Maintain,
+	},
+	models.RoleManager: {
+		ReadMenu, WriteMenu, ReadTables, WriteTables, ReadOrders, WriteOrders, ReadInvoices, WriteInvoices,
+		ReadUsers, ReadContacts, ReadAudit,
+	},
+	models.RoleCashier:  {ReadMenu, ReadTables, ReadOrders, ReadInvoices, WriteInvoices},
+	models.RoleWaiter:   {ReadMenu, ReadTables, ReadOrders, WriteOrders, ReadInvoices},
+	models.RoleChef:     {ReadMenu, ReadOrders},
+	models.RoleCustomer: {ReadMenu},
+}
+
+// Can reports whether role grants permission.
+func Can(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPermission reports whether p names a permission, which is what the scopes of an API key must be.
+func IsPermission(p string) bool {
+	for _, permissions := range rolePermissions {
+		for _, permission := range permissions {
+			if string(permission) == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Allowed reports whether the caller of c was granted permission, by the scopes of their API key or else by their
+// role. Handlers use it to decide what to show, Authorize to decide whether to go on at all.
+func Allowed(c *gin.Context, permission Permission) bool {
+	if scopes, ok := c.Get("scopes"); ok {
+		return slices.Contains(scopes.([]string), string(permission))
+	}
+	return Can(c.GetString("role"), permission)
+}
+
+// Authorize only lets callers whose role grants permission through. It must run after Authentication, which puts
+// the role from the token in the context, or the scopes of an API key. A token issued before roles were carried has
+// no role and is refused until its user logs in again.
+func Authorize(permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Allowed(c, permission) {
+			if _, ok := c.Get("scopes"); ok {
+				c.JSON(http.StatusForbidden, gin.H{"error": "the API key does not have the scope " + string(permission)})
+			} else {
+				c.JSON(http.StatusForbidden, gin.H{"error": "your role does not allow " + string(permission)})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,156 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The migrations package brings a MongoDB database up to the schema the code expects. Every Migration has a version
+// number; the versions that have been applied are recorded in the schema_migrations collection, so each step runs
+// once per database no matter how many times the server restarts.
+//
+// Steps must be idempotent. Two replicas starting at the same time can both decide a step is pending; the unique
+// index on schema_migrations.version lets only one of them record it, but both may have executed it.
+
+const migrationsCollection = "schema_migrations"
+
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// AppliedMigration is the document stored in schema_migrations for every step that ran.
+type AppliedMigration struct {
+	Version     int       `bson:"version" json:"version"`
+	Description string    `bson:"description" json:"description"`
+	Applied_at  time.Time `bson:"applied_at" json:"applied_at"`
+}
+
+// Status describes one known migration and whether it has been applied.
+type Status struct {
+	Migration Migration
+	Applied   *AppliedMigration
+}
+
+// All returns every known migration in version order.
+func All() []Migration {
+	all := append([]Migration(nil), migrations...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Run applies every pending migration in version order and stops at the first failure.
+func Run(ctx context.Context, db *mongo.Database) error {
+	statuses, err := GetStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	collection := db.Collection(migrationsCollection)
+	for _, status := range statuses {
+		if status.Applied != nil {
+			continue
+		}
+		m := status.Migration
+		log.Printf("applying migration %d: %s", m.Version, m.Description)
+		if err := m.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+
+		applied := AppliedMigration{Version: m.Version, Description: m.Description}
+		applied.Applied_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if _, err := collection.InsertOne(ctx, applied); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("recording migration %d: %w", m.Version, err)
+		}
+	}
+	return nil
+}
+
+// GetStatus lists every known migration together with its schema_migrations record, if any.
+func GetStatus(ctx context.Context, db *mongo.Database) ([]Status, error) {
+	collection := db.Collection(migrationsCollection)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("preparing %s: %w", migrationsCollection, err)
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var applied []AppliedMigration
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, err
+	}
+	byVersion := map[int]AppliedMigration{}
+	for _, a := range applied {
+		byVersion[a.Version] = a
+	}
+
+	var statuses []Status
+	for _, m := range All() {
+		status := Status{Migration: m}
+		if a, ok := byVersion[m.Version]; ok {
+			status.Applied = &a
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// createIndexes creates the given indexes on a collection. Creating an index that already exists with the same
+// options is a no-op in MongoDB, which keeps the index steps idempotent.
+func createIndexes(ctx context.Context, db *mongo.Database, collection string, indexes ...mongo.IndexModel) error {
+	if _, err := db.Collection(collection).Indexes().CreateMany(ctx, indexes); err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.HasErrorCode(11000) {
+			return fmt.Errorf("%s contains duplicates, resolve them before retrying: %w", collection, err)
+		}
+		return fmt.Errorf("creating indexes on %s: %w", collection, err)
+	}
+	return nil
+}
+
+// uniqueIndex only covers documents where field holds a value of bsonType, so old documents left without the field
+// (for example users created by an upserting update) do not collide on null.
+func uniqueIndex(field string, bsonType string) mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: field, Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetName(field + "_unique").
+			SetPartialFilterExpression(bson.M{field: bson.M{"$type": bsonType}}),
+	}
+}
+
+func index(field string) mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetName(field + "_1"),
+	}
+}
+
+// renameField moves the value of a field written under the wrong name to the right one. The stray field was written
+// by an update after the document was created, so its value wins over the one already held by the right field.
+func renameField(ctx context.Context, db *mongo.Database, collection string, from string, to string) error {
+	_, err := db.Collection(collection).UpdateMany(ctx,
+		bson.M{from: bson.M{"$exists": true}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{to: "$" + from}}},
+			{{Key: "$unset", Value: from}},
+		},
+	)
+	return err
+}
@@ -0,0 +1,309 @@
+package migrations
+
+import (
+	"context"
+	"restaurant-management-system/store"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations lists every schema change in the order it was introduced. Never edit or renumber a step that has
+// shipped; add a new one instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "unique indexes on the custom identifiers",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			ids := map[string]string{
+				store.FoodCollection:      "food_id",
+				store.MenuCollection:      "menu_id",
+				store.TableCollection:     "table_id",
+				store.OrderCollection:     "order_id",
+				store.OrderItemCollection: "order_item_id",
+				store.InvoiceCollection:   "invoice_id",
+				store.UserCollection:      "user_id",
+			}
+			for collection, field := range ids {
+				if err := createIndexes(ctx, db, collection, uniqueIndex(field, "string")); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "move orders that CreateOrder wrote to the menu collection",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Menus never have an order_id, so every menu document holding one is an order.
+			menus := db.Collection(store.MenuCollection)
+			cursor, err := menus.Find(ctx, bson.M{"order_id": bson.M{"$exists": true}})
+			if err != nil {
+				return err
+			}
+			var misplaced []bson.M
+			if err := cursor.All(ctx, &misplaced); err != nil {
+				return err
+			}
+
+			orders := db.Collection(store.OrderCollection)
+			for _, order := range misplaced {
+				count, err := orders.CountDocuments(ctx, bson.M{"order_id": order["order_id"]})
+				if err != nil {
+					return err
+				}
+				if count == 0 {
+					if _, err := orders.InsertOne(ctx, order); err != nil && !mongo.IsDuplicateKeyError(err) {
+						return err
+					}
+				}
+				if _, err := menus.DeleteOne(ctx, bson.M{"_id": order["_id"]}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     3,
+		Description: "backfill menu_id and table_id written under the wrong field name by the update handlers",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// UpdateFood used to $set "menu" and UpdateOrder "table".
+			if err := renameField(ctx, db, store.FoodCollection, "menu", "menu_id"); err != nil {
+				return err
+			}
+			return renameField(ctx, db, store.OrderCollection, "table", "table_id")
+		},
+	},
+	{
+		Version:     4,
+		Description: "unique email, phone and table_number; secondary indexes on references",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// These replace the racy CountDocuments checks in SignUp.
+			if err := createIndexes(ctx, db, store.UserCollection,
+				uniqueIndex("email", "string"),
+				uniqueIndex("phone", "string"),
+			); err != nil {
+				return err
+			}
+			if err := createIndexes(ctx, db, store.TableCollection, uniqueIndex("table_number", "number")); err != nil {
+				return err
+			}
+			if err := createIndexes(ctx, db, store.OrderItemCollection, index("order_id"), index("food_id")); err != nil {
+				return err
+			}
+			if err := createIndexes(ctx, db, store.FoodCollection, index("menu_id")); err != nil {
+				return err
+			}
+			if err := createIndexes(ctx, db, store.OrderCollection, index("table_id")); err != nil {
+				return err
+			}
+			return createIndexes(ctx, db, store.InvoiceCollection, index("order_id"))
+		},
+	},
+	{
+		Version:     5,
+		Description: "start every document at version 1",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Updates filter on the version an If-Match names, so a document without one could never be updated
+			// under a precondition.
+			collections := []string{
+				store.FoodCollection, store.MenuCollection, store.TableCollection, store.OrderCollection,
+				store.OrderItemCollection, store.InvoiceCollection, store.UserCollection,
+			}
+			for _, collection := range collections {
+				_, err := db.Collection(collection).UpdateMany(ctx,
+					bson.M{"version": bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{"version": 1}},
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     6,
+		Description: "add indexes for querying the audit log",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// The log is read newest first, either whole or narrowed to one document or one actor.
+			byResource := mongo.IndexModel{
+				Keys:    bson.D{{Key: "resource", Value: 1}, {Key: "resource_id", Value: 1}, {Key: "at", Value: -1}},
+				Options: options.Index().SetName("resource_1_resource_id_1_at_-1"),
+			}
+			byActor := mongo.IndexModel{
+				Keys:    bson.D{{Key: "actor_id", Value: 1}, {Key: "at", Value: -1}},
+				Options: options.Index().SetName("actor_id_1_at_-1"),
+			}
+			return createIndexes(ctx, db, store.AuditCollection, uniqueIndex("audit_id", "string"), index("at"), byResource, byActor)
+		},
+	},
+	{
+		Version:     7,
+		Description: "expire published domain events after a week",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Subscribers read events through a change stream as they are inserted, so the documents only have to
+			// stay around long enough for a subscriber that was down to resume.
+			expire := mongo.IndexModel{
+				Keys:    bson.D{{Key: "at", Value: 1}},
+				Options: options.Index().SetName("at_ttl").SetExpireAfterSeconds(7 * 24 * 60 * 60),
+			}
+			return createIndexes(ctx, db, store.EventCollection, expire)
+		},
+	},
+	{
+		Version:     8,
+		Description: "turn USER accounts into CUSTOMER accounts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// USER was the only role besides ADMIN before the staff roles existed. Nothing it could do needs more
+			// than a customer may, so that is what those accounts become; an admin can promote them.
+			_, err := db.Collection(store.UserCollection).UpdateMany(ctx,
+				bson.M{"user_type": "USER"},
+				bson.M{"$set": bson.M{"user_type": "CUSTOMER"}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     9,
+		Description: "add indexes for checking token revocations",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Every authenticated request looks up the revocations of its token and its user. A revocation is
+			// useless once the tokens it covers have expired, so MongoDB removes it then.
+			expire := mongo.IndexModel{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+			}
+			return createIndexes(ctx, db, store.RevocationCollection, index("jti"), index("user_id"), expire)
+		},
+	},
+	{
+		Version:     10,
+		Description: "add a unique index on api_key_id",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Every request made with an API key looks it up by its id.
+			return createIndexes(ctx, db, store.ApiKeyCollection, uniqueIndex("api_key_id", "string"))
+		},
+	},
+	{
+		Version:     11,
+		Description: "add indexes for password reset and email verification tokens",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Tokens are looked up by their hash. An expired token cannot be used any more, so MongoDB removes it.
+			expire := mongo.IndexModel{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+			}
+			return createIndexes(ctx, db, store.UserTokenCollection,
+				uniqueIndex("user_token_id", "string"), uniqueIndex("token_hash", "string"), index("user_id"), expire)
+		},
+	},
+	{
+		Version:     12,
+		Description: "treat the email of existing users as verified",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Accounts created before verification existed never got a verification email. Requiring one would lock
+			// every one of them out as soon as require_verified_email is turned on.
+			_, err := db.Collection(store.UserCollection).UpdateMany(ctx,
+				bson.M{"email_verified": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"email_verified": true}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     13,
+		Description: "add indexes for login attempts and failed login counts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Security reviews look attempts up by email, user, address and time. Each throttle is counted with an
+			// upsert by its key, which must be unique so that two upserts cannot create two counts. A throttle that
+			// has not failed for a day is past any lockout the configuration allows, so MongoDB removes it.
+			if err := createIndexes(ctx, db, store.LoginAttemptCollection,
+				uniqueIndex("login_attempt_id", "string"), index("email"), index("user_id"), index("ip"), index("at")); err != nil {
+				return err
+			}
+			expire := mongo.IndexModel{
+				Keys:    bson.D{{Key: "last_failure", Value: 1}},
+				Options: options.Index().SetName("last_failure_ttl").SetExpireAfterSeconds(24 * 60 * 60),
+			}
+			return createIndexes(ctx, db, store.LoginThrottleCollection, uniqueIndex("key", "string"), expire)
+		},
+	},
+	{
+		Version:     14,
+		Description: "add indexes for the token signing keys",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Once the last token a key signed has expired it is useless, so MongoDB removes it.
+			expire := mongo.IndexModel{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+			}
+			return createIndexes(ctx, db, store.SigningKeyCollection, uniqueIndex("kid", "string"), expire)
+		},
+	},
+	{
+		Version:     15,
+		Description: "add a text index and filter indexes for searching foods",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// A collection can have only one text index, so it covers both searched fields. A match in the name
+			// counts more than one in the description.
+			text := mongo.IndexModel{
+				Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+				Options: options.Index().SetName("name_description_text").
+					SetWeights(bson.D{{Key: "name", Value: 10}, {Key: "description", Value: 1}}),
+			}
+			return createIndexes(ctx, db, store.FoodCollection, text, index("price"), index("tags"), index("created_at"))
+		},
+	},
+	{
+		Version:     16,
+		Description: "add indexes in the order of the paged listings",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// A listing sorts by its time and then its id, and a cursor continues after both, so one compound index
+			// serves the sort and the cursor.
+			byKeys := func(name string, keys bson.D) mongo.IndexModel {
+				return mongo.IndexModel{Keys: keys, Options: options.Index().SetName(name)}
+			}
+			oldestFirst := map[string]string{
+				store.FoodCollection:      "food_id",
+				store.MenuCollection:      "menu_id",
+				store.TableCollection:     "table_id",
+				store.OrderCollection:     "order_id",
+				store.OrderItemCollection: "order_item_id",
+				store.InvoiceCollection:   "invoice_id",
+				store.UserCollection:      "user_id",
+				store.ApiKeyCollection:    "api_key_id",
+			}
+			for collection, key := range oldestFirst {
+				keys := bson.D{{Key: "created_at", Value: 1}, {Key: key, Value: 1}}
+				if err := createIndexes(ctx, db, collection, byKeys("created_at_1_"+key+"_1", keys)); err != nil {
+					return err
+				}
+			}
+			newestFirst := map[string]string{
+				store.AuditCollection:        "audit_id",
+				store.LoginAttemptCollection: "login_attempt_id",
+			}
+			for collection, key := range newestFirst {
+				keys := bson.D{{Key: "at", Value: -1}, {Key: key, Value: -1}}
+				if err := createIndexes(ctx, db, collection, byKeys("at_-1_"+key+"_-1", keys)); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     17,
+		Description: "add a unique index on the name of one-time claims",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// The index is what makes a claim one-time: of two sign ups racing to be the first account, the second
+			// insert of the claim fails. Creating it also creates the collection, which a transaction cannot do on
+			// older servers.
+			return createIndexes(ctx, db, store.ClaimCollection, uniqueIndex("name", "string"))
+		},
+	},
+}
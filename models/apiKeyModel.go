@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ApiKey is a long lived credential for a device or an integration that cannot log in, like a kitchen screen. The
+// key itself is only shown once, when it is created; the database keeps a hash of its secret part.
+type ApiKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Api_key_id string             `bson:"api_key_id" json:"api_key_id"`
+	Name       *string            `bson:"name" json:"name" validate:"required,min=2,max=100"` // What the key is for, like "kitchen screen 2"
+	Key_hash   string             `bson:"key_hash" json:"-"`                                  // SHA-256 of the secret, hex encoded
+	Scopes     []string           `bson:"scopes" json:"scopes" validate:"required,min=1"`     // Permissions granted to the key, like "orders:read"
+	Expires_at *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`   // The key is refused from then on; nil never expires
+	Created_by string             `bson:"created_by" json:"created_by"`                       // user_id of the admin who created it, or api_key:<api_key_id> of the key
+	Created_at time.Time          `bson:"created_at" json:"created_at"`
+	Deleted_at *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Set when the key is revoked
+	Deleted_by *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"` // user_id of whoever revoked it
+	Version    int                `bson:"version" json:"version"`                           // Incremented by every write, sent as the ETag
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit actions.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// Audited resources.
+const (
+	AuditFood      = "food"
+	AuditMenu      = "menu"
+	AuditTable     = "table"
+	AuditOrder     = "order"
+	AuditOrderItem = "order_item"
+	AuditInvoice   = "invoice"
+	AuditUser      = "user"
+	AuditApiKey    = "api_key"
+)
+
+// AuditEntry records one change to one document. Entries are only ever inserted, never updated or deleted.
+type AuditEntry struct {
+	ID          primitive.ObjectID     `bson:"_id,omitempty"`
+	Audit_id    string                 `bson:"audit_id" json:"audit_id"`
+	Actor_id    string                 `bson:"actor_id" json:"actor_id"`       // user_id from the token, empty for signup
+	Actor_email string                 `bson:"actor_email" json:"actor_email"` // email from the token at the time of the change
+	Action      string                 `bson:"action" json:"action"`           // create, update, delete or restore
+	Resource    string                 `bson:"resource" json:"resource"`       // food, menu, table, order, order_item, invoice or user
+	Resource_id string                 `bson:"resource_id" json:"resource_id"` // The custom identifier of the changed document
+	Changes     map[string]AuditChange `bson:"changes" json:"changes"`         // Changed fields only, keyed by their bson name
+	At          time.Time              `bson:"at" json:"at"`
+}
+
+type AuditChange struct {
+	Before interface{} `bson:"before" json:"before"`
+	After  interface{} `bson:"after" json:"after"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Claim records that something which may only ever happen once has happened, such as the sign up of the first
+// account. Its Name is unique, so of two requests racing to do it only one can write the claim.
+type Claim struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	Name       string             `bson:"name" json:"name"`
+	Claimed_at time.Time          `bson:"claimed_at" json:"claimed_at"`
+}
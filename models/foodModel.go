@@ -15,13 +15,65 @@ import (
 // Use pointers if you want to allow the field to be omitted or set to nil.
 
 type Food struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty"`
-	Name       *string            `json:"name" validate:"required,min=2,max=100"` // Name of the food, required and between 2-100 characters
-	Price      *float64           `json:"price" validate:"required"`              // Price of the food, required
-	Food_image *string            `json:"food_image" validate:"required"`
-	Created_at time.Time          `bson:"created_at" json:"created_at"`               // Time of creation
-	Updated_at time.Time          `bson:"updated_at" json:"updated_at"`               // Time of last update
-	Food_id    string             `bson:"food_id" json:"food_id"`                     // Custom food identifier
-	Menu_id    *string            `bson:"menu_id" json:"menu_id" validate:"required"` // Reference to the menu the food belongs to
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	Name            *string            `json:"name" validate:"required,min=2,max=100"`                           // Name of the food, required and between 2-100 characters
+	Price           *float64           `json:"price"`                                                            // Price of the food, the lowest variant price when it has variants
+	Variants        []FoodVariant      `bson:"variants" json:"variants" validate:"omitempty,dive"`               // The sizes or other ways it is sold in, each with its price
+	Modifier_groups []ModifierGroup    `bson:"modifier_groups" json:"modifier_groups" validate:"omitempty,dive"` // Choices that come with it, like a side or extras
+	Food_image      *string            `json:"food_image"`                                                       // A URL, or the image endpoint once an image was uploaded
+	Image           *FoodImage         `bson:"image,omitempty" json:"image,omitempty"`
+	Description     *string            `json:"description" validate:"omitempty,max=1000"`               // Searched together with the name
+	Tags            []string           `bson:"tags" json:"tags" validate:"omitempty,dive,min=1,max=50"` // Lower case, like vegan or spicy
+	Available       *bool              `bson:"available" json:"available"`                              // Whether the kitchen can make it right now, true unless set
+	Created_at      time.Time          `bson:"created_at" json:"created_at"`                            // Time of creation
+	Updated_at      time.Time          `bson:"updated_at" json:"updated_at"`                            // Time of last update
+	Food_id         string             `bson:"food_id" json:"food_id"`                                  // Custom food identifier
+	Menu_id         *string            `bson:"menu_id" json:"menu_id" validate:"required"`              // Reference to the menu the food belongs to
+	Deleted_at      *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`        // Set when the food is deleted
+	Deleted_by      *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`        // user_id of whoever deleted it
+	Version         int                `bson:"version" json:"version"`                                  // Incremented by every write, sent as the ETag
 
 }
+
+// StandardSizes are the portion sizes of a food that has no variants. It is sold in each of them at its Price.
+var StandardSizes = []string{"S", "M", "L"}
+
+// FoodVariant is one way a food is sold, a portion size like S, M or L or something of its own like bottle or
+// glass, with its price. An order item names the variant it is for in its quantity.
+type FoodVariant struct {
+	Name  string   `bson:"name" json:"name" validate:"required,min=1,max=30"`
+	Price *float64 `bson:"price" json:"price" validate:"required,gte=0"`
+}
+
+// ModifierGroup is a choice that comes with a food, like a side to choose or extras to add. An order item of the
+// food picks between Min and Max of its modifiers, so a group with Min 1 must be chosen from.
+type ModifierGroup struct {
+	Name      string     `bson:"name" json:"name" validate:"required,min=1,max=50"`
+	Min       int        `bson:"min" json:"min" validate:"gte=0"`
+	Max       int        `bson:"max" json:"max" validate:"gte=1"`
+	Modifiers []Modifier `bson:"modifiers" json:"modifiers" validate:"required,min=1,dive"`
+}
+
+// Modifier is one option of a modifier group with what it adds to the price of the item, which is 0 for something
+// like no onions.
+type Modifier struct {
+	Name  string   `bson:"name" json:"name" validate:"required,min=1,max=50"`
+	Price *float64 `bson:"price" json:"price" validate:"required,gte=0"`
+}
+
+// FoodImage describes the image uploaded for a food. Its blobs are named foods/<food_id>/<image_id>/<size>, so a
+// new upload never overwrites the blobs an older version of the food points at.
+type FoodImage struct {
+	Image_id    string          `bson:"image_id" json:"image_id"`
+	Sizes       []FoodImageSize `bson:"sizes" json:"sizes"` // The original and the thumbnails made of it
+	Uploaded_at time.Time       `bson:"uploaded_at" json:"uploaded_at"`
+	Uploaded_by string          `bson:"uploaded_by" json:"uploaded_by"`
+}
+
+type FoodImageSize struct {
+	Size         string `bson:"size" json:"size"` // original, or the name of a thumbnail size
+	Content_type string `bson:"content_type" json:"content_type"`
+	Width        int    `bson:"width" json:"width"`
+	Height       int    `bson:"height" json:"height"`
+	Bytes        int    `bson:"bytes" json:"bytes"`
+}
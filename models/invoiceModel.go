@@ -20,4 +20,7 @@ type Invoice struct {
 	Payment_due_date time.Time          `bson:"payment_due_date" json:"payment_due_date" validate:"required"`                // Amount due, required
 	Created_at       time.Time          `bson:"created_at" json:"created_at"`                                                // Time of invoice creation
 	Updated_at       time.Time          `bson:"updated_at" json:"updated_at"`                                                // Time of last invoice update
+	Deleted_at       *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`                            // Set when the invoice is deleted
+	Deleted_by       *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`                            // user_id of whoever deleted it
+	Version          int                `bson:"version" json:"version"`                                                      // Incremented by every write, sent as the ETag
 }
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Outcomes of a LoginAttempt.
+const (
+	LoginSucceeded        = "succeeded"
+	LoginUnknownEmail     = "unknown_email"
+	LoginWrongPassword    = "wrong_password"
+	LoginLocked           = "locked"
+	LoginEmailNotVerified = "email_not_verified"
+	LoginMfaRequired      = "mfa_required" // The password was right, a TOTP code was asked for
+	LoginWrongMfaCode     = "wrong_mfa_code"
+	LoginDeactivated      = "deactivated"
+	LoginResetRequired    = "password_reset_required"
+)
+
+// LoginAttempt records one call to the login endpoint, whatever its outcome. Like the audit log, attempts are only
+// ever inserted.
+type LoginAttempt struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"`
+	Login_attempt_id string             `bson:"login_attempt_id" json:"login_attempt_id"`
+	Email            string             `bson:"email" json:"email"`                         // As sent, lower cased
+	User_id          *string            `bson:"user_id,omitempty" json:"user_id,omitempty"` // Set when the email belongs to a user
+	Ip               string             `bson:"ip" json:"ip"`
+	User_agent       string             `bson:"user_agent" json:"user_agent"`
+	Outcome          string             `bson:"outcome" json:"outcome"` // One of the Login outcome constants
+	At               time.Time          `bson:"at" json:"at"`
+}
+
+// LoginThrottle counts the recent failed logins of one account or one client IP, identified by Key. See
+// controllers.loginLockedFor for how the count turns into a lockout.
+type LoginThrottle struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Key          string             `bson:"key" json:"key"` // "account:<email>" or "ip:<address>"
+	Failures     int                `bson:"failures" json:"failures"`
+	Last_failure time.Time          `bson:"last_failure" json:"last_failure"`
+}
@@ -15,4 +15,7 @@ type Menu struct {
 	Created_at time.Time          `bson:"created_at" json:"created_at"`                              // Time of menu creation
 	Updated_at time.Time          `bson:"updated_at" json:"updated_at"`                              // Time of last menu update
 	Menu_id    string             `bson:"menu_id" json:"menu_id"`                                    // Custom menu identifier
+	Deleted_at *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`          // Set when the menu is deleted
+	Deleted_by *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`          // user_id of whoever deleted it
+	Version    int                `bson:"version" json:"version"`                                    // Incremented by every write, sent as the ETag
 }
@@ -9,14 +9,30 @@ import (
 type OrderItem struct {
 	ID primitive.ObjectID `bson:"_id,omitempty"`
 
-	Quantity *string `bson:"quantity" json:"quantity" validate:"required, eq=S|eq=M|eq=L"`
+	// Quantity is the variant of the food the item is for: S, M or L, or a variant of the food's own like bottle.
+	Quantity *string `bson:"quantity" json:"quantity" validate:"required,min=1,max=30"`
 
 	Order_ID      string `bson:"order_id" json:"order_id" validate:"required"`
 	Order_Item_Id string `bson:"order_item_id" json:"order_item_id" `
 
-	Unit_Price *float64 `bson:"unit_price" json:"unit_price" validate:"required"`
+	Unit_Price *float64 `bson:"unit_price" json:"unit_price"` // Taken from the food by the server, whatever the client sends
 	Food_id    *string  `bson:"food_id" json:"food_id" validate:"required" `
 
+	// Modifiers are chosen from the modifier groups of the food. Their prices are added to the unit price.
+	Modifiers []OrderItemModifier `bson:"modifiers" json:"modifiers" validate:"omitempty,dive"`
+
 	CreatedAt time.Time `bson:"created_at" json:"created_at"` // Time of order creation
 	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+
+	Deleted_at *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Set when the item or its order is deleted
+	Deleted_by *string    `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"` // user_id of whoever deleted it
+	Version    int        `bson:"version" json:"version"`                           // Incremented by every write, sent as the ETag
+}
+
+// OrderItemModifier is a modifier chosen for an order item, by the name of its group and its own. Its price is taken
+// from the food when the item is ordered, so changing the food later does not change what the item costs.
+type OrderItemModifier struct {
+	Group string   `bson:"group" json:"group" validate:"required"`
+	Name  string   `bson:"name" json:"name" validate:"required"`
+	Price *float64 `bson:"price" json:"price"`
 }
@@ -11,6 +11,9 @@ type Order struct {
 	Order_Date time.Time          `bson:"order_date" json:"order_date" validate:"required"` // Custom order identifier (required)
 	Table_ID   *string            `bson:"table_id" json:"table_id" validate:"required"`     // Reference to the associated Table (required)
 	Order_ID   string             `bson:"order_id" json:"order_id" `
-	CreatedAt  time.Time          `bson:"created_at" json:"created_at"` // Time of order creation
-	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"` // Time of last order update
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`                     // Time of order creation
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`                     // Time of last order update
+	Deleted_at *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Set when the order is deleted
+	Deleted_by *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"` // user_id of whoever deleted it
+	Version    int                `bson:"version" json:"version"`                           // Incremented by every write, sent as the ETag
 }
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Revocation rejects access tokens before they expire. It covers either the single token with id Jti, after a
+// logout, or every token of User_id issued up to Before, after all sessions of the user were revoked. Once
+// Expires_at has passed every token it covers has expired on its own, so it can be forgotten.
+type Revocation struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	Revocation_id string             `bson:"revocation_id" json:"revocation_id"`
+	Jti           *string            `bson:"jti,omitempty" json:"jti,omitempty"`
+	User_id       *string            `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Before        *time.Time         `bson:"before,omitempty" json:"before,omitempty"`
+	Expires_at    time.Time          `bson:"expires_at" json:"expires_at"`
+}
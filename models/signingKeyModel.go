@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SigningKey is one key pair that signs access and refresh tokens when they are signed asymmetrically. It is
+// published in the JWKS from Created_at, signs from Active_from until Retire_at and verifies until Expires_at, when
+// the last token it signed has expired.
+type SigningKey struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"`
+	Kid         string             `bson:"kid" json:"kid"`
+	Algorithm   string             `bson:"algorithm" json:"algorithm"`   // RS256 or EdDSA
+	Public_key  []byte             `bson:"public_key" json:"public_key"` // PKIX, DER encoded
+	Private_key []byte             `bson:"private_key" json:"-"`         // PKCS #8, DER encoded and sealed with the secret key
+	Created_at  time.Time          `bson:"created_at" json:"created_at"`
+	Active_from time.Time          `bson:"active_from" json:"active_from"`
+	Retire_at   time.Time          `bson:"retire_at" json:"retire_at"`
+	Expires_at  time.Time          `bson:"expires_at" json:"expires_at"`
+}
@@ -13,4 +13,7 @@ type Table struct {
 	Table_ID         string             `bson:"table_id,omitempty" json:"table_id,omitempty"`                       // Associated order (optional, if table is occupied)
 	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`                                       // Time of table creation
 	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`                                       // Time of last table update
+	Deleted_at       *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`                   // Set when the table is deleted
+	Deleted_by       *string            `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`                   // user_id of whoever deleted it
+	Version          int                `bson:"version" json:"version"`                                             // Incremented by every write, sent as the ETag
 }
@@ -12,6 +12,20 @@ import (
 
 // for memory efficiency: pointers in Go are more memory efficient than non-pointer fields.
 
+// Roles a user can have, stored in User_type and carried in the token. What each role may do is decided per route by
+// middleware.Authorize.
+const (
+	RoleAdmin    = "ADMIN"
+	RoleManager  = "MANAGER"
+	RoleCashier  = "CASHIER"
+	RoleWaiter   = "WAITER"
+	RoleChef     = "CHEF"
+	RoleCustomer = "CUSTOMER"
+)
+
+// Roles lists every role, most privileged first.
+var Roles = []string{RoleAdmin, RoleManager, RoleCashier, RoleWaiter, RoleChef, RoleCustomer}
+
 type User struct {
 	ID            primitive.ObjectID `bson:"_id"`                                          // MongoDB ObjectID
 	First_name    *string            `json:"first_name" validate:"required,min=2,max=100"` // User's first name (required, length 2-100)
@@ -21,9 +35,30 @@ type User struct {
 	Phone         *string            `json:"phone" validate:"required"`                    // User's phone number (required)
 	Token         *string            `json:"token"`                                        // Token (optional)
 	Refresh_Token *string            `json:"refresh_token"`
+	Token_family  *string            `json:"-"` // Family of the stored refresh token, see helpers.SignedDetails
+	// Email_verified is set once the user followed the verification email, or reset the password through an email
+	// sent to the same address.
+	Email_verified bool `bson:"email_verified" json:"email_verified"`
+	// Mfa_enabled is set once the user confirmed a TOTP authenticator, from then on a login also needs a code from
+	// it. The secrets are sealed with helpers.SealSecret and, like the recovery code hashes, never leave the server.
+	Mfa_enabled        bool     `bson:"mfa_enabled" json:"mfa_enabled"`
+	Mfa_secret         []byte   `bson:"mfa_secret,omitempty" json:"-"`
+	Mfa_pending_secret []byte   `bson:"mfa_pending_secret,omitempty" json:"-"` // Enrolled but not confirmed yet
+	Mfa_recovery_codes []string `bson:"mfa_recovery_codes,omitempty" json:"-"` // SHA-256 of the unused recovery codes
+	Mfa_last_step      int64    `bson:"mfa_last_step,omitempty" json:"-"`      // TOTP time step of the last accepted code
+	// Password_reset_required keeps the user from logging in until they set a new password through the reset email
+	// an admin had sent them.
+	Password_reset_required bool `bson:"password_reset_required" json:"password_reset_required"`
+	// A deactivated user keeps their account and data but can neither log in nor use the tokens they hold, until an
+	// admin reactivates them.
+	Deactivated_at *time.Time `bson:"deactivated_at,omitempty" json:"deactivated_at,omitempty"`
+	Deactivated_by *string    `bson:"deactivated_by,omitempty" json:"deactivated_by,omitempty"` // user_id of whoever deactivated them
 	// Refresh token (optional)
-	User_type  *string   `json:"user_type" validate:"required,eq=ADMIN|eq=USER"` // User type (required, must be "ADMIN" or "USER")
-	Created_at time.Time `json:"created_at"`                                     // Time of account creation
-	Updated_at time.Time `json:"updated_at"`                                     // Time of last account update
-	User_id    string    `json:"user_id"`                                        // Custom user identifier
+	User_type  *string    `json:"user_type" validate:"required,eq=ADMIN|eq=MANAGER|eq=CASHIER|eq=WAITER|eq=CHEF|eq=CUSTOMER"` // The role of the user, one of the Role constants
+	Created_at time.Time  `json:"created_at"`                                                                                 // Time of account creation
+	Updated_at time.Time  `json:"updated_at"`                                                                                 // Time of last account update
+	User_id    string     `json:"user_id"`                                                                                    // Custom user identifier
+	Deleted_at *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`                                           // Set when the user is deleted
+	Deleted_by *string    `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`                                           // user_id of whoever deleted it
+	Version    int        `bson:"version" json:"version"`                                                                     // Incremented by every write, sent as the ETag
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Purposes of a UserToken.
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+)
+
+// UserToken is a single use token sent to the email address of a user, to reset the password or to prove the
+// address is theirs. Only a hash of the token is stored. Used_at is set when it is used, or when a newer token for
+// the same purpose replaces it.
+type UserToken struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	User_token_id string             `bson:"user_token_id" json:"user_token_id"`
+	Purpose       string             `bson:"purpose" json:"purpose"` // One of the Purpose constants
+	User_id       string             `bson:"user_id" json:"user_id"`
+	Email         string             `bson:"email" json:"email"`  // The address the token was sent to
+	Token_hash    string             `bson:"token_hash" json:"-"` // SHA-256 of the token, hex encoded
+	Expires_at    time.Time          `bson:"expires_at" json:"expires_at"`
+	Used_at       *time.Time         `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	Created_at    time.Time          `bson:"created_at" json:"created_at"`
+}
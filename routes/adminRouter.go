@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	admin := incomingRoutes.Group("/admin", middleware.Authorize(middleware.Maintain))
+	admin.GET("/integrity", ctl.CheckIntegrity())          // Report dangling references
+	admin.POST("/integrity/repair", ctl.RepairIntegrity()) // Repair them, dry run unless ?apply=true
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ApiKeyRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	apiKeys := incomingRoutes.Group("/api-keys", middleware.Authorize(middleware.ManageApiKeys))
+	apiKeys.GET("", ctl.GetApiKeys())                  // ?include_deleted=true also lists revoked keys
+	apiKeys.POST("", ctl.CreateApiKey())               // The response carries the key, it is never shown again
+	apiKeys.DELETE("/:api_key_id", ctl.RevokeApiKey()) // Revoking cannot be undone
+}
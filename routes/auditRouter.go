@@ -0,0 +1,13 @@
+package routes
+
+import (
+	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AuditRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	audit := incomingRoutes.Group("/audit", middleware.Authorize(middleware.ReadAudit))
+	audit.GET("", ctl.GetAuditLog()) // Who changed what, filtered by resource, resource_id, actor and from/to
+}
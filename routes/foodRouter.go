@@ -2,13 +2,18 @@ package routes
 
 import (
 	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func FoodRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/foods", controller.GetFoods())
-	incomingRoutes.GET("/foods/:food_id", controller.GetFood())
-	incomingRoutes.POST("/foods", controller.CreateFood())
-	incomingRoutes.PATCH("/foods/:food_id", controller.UpdateFood())
+func FoodRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/foods", middleware.Authorize(middleware.ReadMenu), ctl.GetFoods())
+	incomingRoutes.GET("/foods/:food_id", middleware.Authorize(middleware.ReadMenu), ctl.GetFood())
+	incomingRoutes.POST("/foods", middleware.Authorize(middleware.WriteMenu), ctl.CreateFood())
+	incomingRoutes.PATCH("/foods/:food_id", middleware.Authorize(middleware.WriteMenu), ctl.UpdateFood())
+	incomingRoutes.DELETE("/foods/:food_id", middleware.Authorize(middleware.WriteMenu), ctl.DeleteFood())
+	incomingRoutes.POST("/foods/:food_id/restore", middleware.Authorize(middleware.WriteMenu), ctl.RestoreFood())
+	incomingRoutes.GET("/foods/:food_id/image", middleware.Authorize(middleware.ReadMenu), ctl.GetFoodImage())      // ?size=thumbnail or medium, the original by default
+	incomingRoutes.POST("/foods/:food_id/image", middleware.Authorize(middleware.WriteMenu), ctl.UploadFoodImage()) // multipart/form-data with the field image
 }
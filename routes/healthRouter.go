@@ -0,0 +1,12 @@
+package routes
+
+import (
+	controller "restaurant-management-system/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func HealthRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/healthz", ctl.Healthz()) // Liveness probe
+	incomingRoutes.GET("/readyz", ctl.Readyz())   // Readiness probe, pings MongoDB
+}
@@ -2,13 +2,16 @@ package routes
 
 import (
 	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func InvoiceRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/invoices", controller.GetInvoices())
-	incomingRoutes.GET("/invoices/:invoice_id", controller.GetInvoice())
-	incomingRoutes.POST("/invoices", controller.CreateInvoice())
-	incomingRoutes.PATCH("/invoices/:invoice_id", controller.UpdateInvoice())
+func InvoiceRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/invoices", middleware.Authorize(middleware.ReadInvoices), ctl.GetInvoices())
+	incomingRoutes.GET("/invoices/:invoice_id", middleware.Authorize(middleware.ReadInvoices), ctl.GetInvoice())
+	incomingRoutes.POST("/invoices", middleware.Authorize(middleware.WriteInvoices), ctl.CreateInvoice())
+	incomingRoutes.PATCH("/invoices/:invoice_id", middleware.Authorize(middleware.WriteInvoices), ctl.UpdateInvoice())
+	incomingRoutes.DELETE("/invoices/:invoice_id", middleware.Authorize(middleware.WriteInvoices), ctl.DeleteInvoice())
+	incomingRoutes.POST("/invoices/:invoice_id/restore", middleware.Authorize(middleware.WriteInvoices), ctl.RestoreInvoice())
 }
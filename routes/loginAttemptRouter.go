@@ -0,0 +1,13 @@
+package routes
+
+import (
+	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func LoginAttemptRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/login-attempts", middleware.Authorize(middleware.ReadAudit), ctl.GetLoginAttempts())      // Filtered by email, user_id, ip, outcome and from/to
+	incomingRoutes.POST("/login-attempts/unlock-ip", middleware.Authorize(middleware.ManageUsers), ctl.UnlockIP()) // Clears the failed logins of an IP address
+}
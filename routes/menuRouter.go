@@ -2,13 +2,16 @@ package routes
 
 import (
 	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func MenuRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/menus", controller.GetMenus())              // Retrieve all menus
-	incomingRoutes.GET("/menus/:menu_id", controller.GetMenu())      // Retrieve a specific menu by ID
-	incomingRoutes.POST("/menus", controller.CreateMenu())           // Create a new menu
-	incomingRoutes.PATCH("/menus/:menu_id", controller.UpdateMenu()) // Update a specific menu by ID
+func MenuRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/menus", middleware.Authorize(middleware.ReadMenu), ctl.GetMenus())                // Retrieve all menus
+	incomingRoutes.GET("/menus/:menu_id", middleware.Authorize(middleware.ReadMenu), ctl.GetMenu())        // Retrieve a specific menu by ID
+	incomingRoutes.POST("/menus", middleware.Authorize(middleware.WriteMenu), ctl.CreateMenu())            // Create a new menu
+	incomingRoutes.PATCH("/menus/:menu_id", middleware.Authorize(middleware.WriteMenu), ctl.UpdateMenu())  // Update a specific menu by ID
+	incomingRoutes.DELETE("/menus/:menu_id", middleware.Authorize(middleware.WriteMenu), ctl.DeleteMenu()) // Soft delete a menu that has no foods left
+	incomingRoutes.POST("/menus/:menu_id/restore", middleware.Authorize(middleware.WriteMenu), ctl.RestoreMenu())
 }
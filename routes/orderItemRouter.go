@@ -2,14 +2,17 @@ package routes
 
 import (
 	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func OrderItemRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/orderItems", controller.GetOrderItems()) // Retrieve all tables
-	incomingRoutes.GET("/orderItems/:orderItem_id", controller.GetOrderItem())
-	incomingRoutes.GET("/orderItems-order/:order_id", controller.GetOrderItemsByOrder())
-	incomingRoutes.POST("/orderItems", controller.CreateOrderItem()) // Create a new table
-	incomingRoutes.PATCH("/orderItems/:orderItem_id", controller.UpdateOrderItem())
+func OrderItemRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/orderItems", middleware.Authorize(middleware.ReadOrders), ctl.GetOrderItems()) // Retrieve all tables
+	incomingRoutes.GET("/orderItems/:orderItem_id", middleware.Authorize(middleware.ReadOrders), ctl.GetOrderItem())
+	incomingRoutes.GET("/orderItems-order/:order_id", middleware.Authorize(middleware.ReadOrders), ctl.GetOrderItemsByOrder())
+	incomingRoutes.POST("/orderItems", middleware.Authorize(middleware.WriteOrders), ctl.CreateOrderItem()) // Create a new table
+	incomingRoutes.PATCH("/orderItems/:orderItem_id", middleware.Authorize(middleware.WriteOrders), ctl.UpdateOrderItem())
+	incomingRoutes.DELETE("/orderItems/:orderItem_id", middleware.Authorize(middleware.WriteOrders), ctl.DeleteOrderItem())
+	incomingRoutes.POST("/orderItems/:orderItem_id/restore", middleware.Authorize(middleware.WriteOrders), ctl.RestoreOrderItem())
 }
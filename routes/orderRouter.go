@@ -2,13 +2,16 @@ package routes
 
 import (
 	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func OrderRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/orders", controller.GetOrders())               // Retrieve all orders
-	incomingRoutes.GET("/orders/:order_id", controller.GetOrder())      // Retrieve a specific order by ID
-	incomingRoutes.POST("/orders", controller.CreateOrder())            // Create a new order
-	incomingRoutes.PATCH("/orders/:order_id", controller.UpdateOrder()) // Update a specific order by ID
+func OrderRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/orders", middleware.Authorize(middleware.ReadOrders), ctl.GetOrders())                 // Retrieve all orders
+	incomingRoutes.GET("/orders/:order_id", middleware.Authorize(middleware.ReadOrders), ctl.GetOrder())        // Retrieve a specific order by ID
+	incomingRoutes.POST("/orders", middleware.Authorize(middleware.WriteOrders), ctl.CreateOrder())             // Create a new order
+	incomingRoutes.PATCH("/orders/:order_id", middleware.Authorize(middleware.WriteOrders), ctl.UpdateOrder())  // Update a specific order by ID
+	incomingRoutes.DELETE("/orders/:order_id", middleware.Authorize(middleware.WriteOrders), ctl.DeleteOrder()) // Soft delete an order and its items
+	incomingRoutes.POST("/orders/:order_id/restore", middleware.Authorize(middleware.WriteOrders), ctl.RestoreOrder())
 }
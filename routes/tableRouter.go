@@ -2,13 +2,16 @@ package routes
 
 import (
 	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func TableRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/tables", controller.GetTables())               //
-	incomingRoutes.GET("/tables/:table_id", controller.GetTable())      //
-	incomingRoutes.POST("/tables", controller.CreateTable())            //
-	incomingRoutes.PATCH("/tables/:table_id", controller.UpdateTable()) //
+func TableRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/tables", middleware.Authorize(middleware.ReadTables), ctl.GetTables())                //
+	incomingRoutes.GET("/tables/:table_id", middleware.Authorize(middleware.ReadTables), ctl.GetTable())       //
+	incomingRoutes.POST("/tables", middleware.Authorize(middleware.WriteTables), ctl.CreateTable())            //
+	incomingRoutes.PATCH("/tables/:table_id", middleware.Authorize(middleware.WriteTables), ctl.UpdateTable()) //
+	incomingRoutes.DELETE("/tables/:table_id", middleware.Authorize(middleware.WriteTables), ctl.DeleteTable())
+	incomingRoutes.POST("/tables/:table_id/restore", middleware.Authorize(middleware.WriteTables), ctl.RestoreTable())
 }
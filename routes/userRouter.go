@@ -2,14 +2,43 @@ package routes
 
 import (
 	controller "restaurant-management-system/controllers"
+	middleware "restaurant-management-system/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Define UserRoutes function that will attach user-related routes to the router
-func UserRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/users", controller.GetUsers())
-	incomingRoutes.GET("/users/:user_id", controller.GetUser())
-	incomingRoutes.POST("/users/signup", controller.SignUp())
-	incomingRoutes.POST("/users/login", controller.Login())
+func UserRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.POST("/users/signup", ctl.SignUp())
+	incomingRoutes.POST("/users/login", ctl.Login())
+	incomingRoutes.POST("/users/login/mfa", ctl.LoginMfa())                     // Completes a login with a TOTP or recovery code
+	incomingRoutes.POST("/users/login/mfa/enroll", ctl.LoginMfaEnroll())        // Enrolls a user whose role requires two-factor authentication
+	incomingRoutes.POST("/users/refresh", ctl.RefreshToken())                   // Exchange a refresh token for a new token pair
+	incomingRoutes.POST("/users/forgot-password", ctl.ForgotPassword())         // Emails a password reset token
+	incomingRoutes.POST("/users/reset-password", ctl.ResetPassword())           // Sets a new password with that token
+	incomingRoutes.POST("/users/verify-email", ctl.VerifyEmail())               // Verifies the email address with the token sent at sign up
+	incomingRoutes.POST("/users/verify-email/resend", ctl.ResendVerification()) // Emails a new verification token
+	// These routes are registered before the Authentication middleware applies to every route, so they add it
+	// themselves: the role and the uid come from the token.
+	authenticated := middleware.Authentication(ctl.Store)
+	incomingRoutes.GET("/users/me", authenticated, ctl.GetProfile())
+	incomingRoutes.PATCH("/users/me", authenticated, ctl.UpdateProfile())
+	incomingRoutes.POST("/users/me/password", authenticated, ctl.ChangePassword())
+	incomingRoutes.GET("/users", authenticated, middleware.Authorize(middleware.ReadUsers), ctl.GetUsers())
+	incomingRoutes.GET("/users/:user_id", authenticated, middleware.Authorize(middleware.ReadUsers), ctl.GetUser())
+	incomingRoutes.POST("/users", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.CreateStaff()) // Creates an account with any role
+	incomingRoutes.POST("/users/logout", authenticated, ctl.Logout())
+	incomingRoutes.POST("/users/mfa/enroll", authenticated, ctl.EnrollMfa())
+	incomingRoutes.POST("/users/mfa/confirm", authenticated, ctl.ConfirmMfa())
+	incomingRoutes.POST("/users/mfa/disable", authenticated, ctl.DisableMfa())
+	incomingRoutes.POST("/users/mfa/recovery-codes", authenticated, ctl.RegenerateRecoveryCodes())
+	incomingRoutes.POST("/users/:user_id/revoke-sessions", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.RevokeSessions())
+	incomingRoutes.POST("/users/:user_id/unlock", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.UnlockUser()) // Clears the failed logins of the account
+	incomingRoutes.POST("/users/:user_id/mfa/reset", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.ResetMfa())
+	incomingRoutes.PATCH("/users/:user_id/role", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.ChangeRole())
+	incomingRoutes.POST("/users/:user_id/deactivate", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.DeactivateUser())
+	incomingRoutes.POST("/users/:user_id/reactivate", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.ReactivateUser())
+	incomingRoutes.POST("/users/:user_id/force-password-reset", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.ForcePasswordReset())
+	incomingRoutes.DELETE("/users/:user_id", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.DeleteUser())
+	incomingRoutes.POST("/users/:user_id/restore", authenticated, middleware.Authorize(middleware.ManageUsers), ctl.RestoreUser())
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	controller "restaurant-management-system/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WellKnownRoutes(incomingRoutes *gin.Engine, ctl *controller.Controller) {
+	incomingRoutes.GET("/.well-known/jwks.json", ctl.JWKS()) // Public keys of the tokens, empty with HS256
+}
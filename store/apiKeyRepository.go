@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ApiKeyRepository has no update: a key's scopes are fixed when it is created, so a key that should be able to do
+// something else is replaced. Deleting a key revokes it and there is no restore.
+type ApiKeyRepository interface {
+	// List returns one page of the keys that are not revoked, or of every key with includeDeleted, oldest first.
+	List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.ApiKey], error)
+	// FindByID returns ErrNotFound for a revoked key. FindByIDWithDeleted returns it.
+	FindByID(ctx context.Context, apiKeyId string) (models.ApiKey, error)
+	FindByIDWithDeleted(ctx context.Context, apiKeyId string) (models.ApiKey, error)
+	Create(ctx context.Context, apiKey models.ApiKey) error
+	// Delete revokes the key. It returns ErrNotFound when the key does not exist or is already revoked.
+	Delete(ctx context.Context, apiKeyId string, d Deletion) error
+}
+
+type mongoApiKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoApiKeyRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.ApiKey], error) {
+	return findPage[models.ApiKey](ctx, r.collection, liveFilter(bson.M{}, includeDeleted), oldestFirst("api_key_id"), page)
+}
+
+func (r *mongoApiKeyRepository) FindByID(ctx context.Context, apiKeyId string) (models.ApiKey, error) {
+	return findByKey[models.ApiKey](ctx, r.collection, "api_key_id", apiKeyId, false)
+}
+
+func (r *mongoApiKeyRepository) FindByIDWithDeleted(ctx context.Context, apiKeyId string) (models.ApiKey, error) {
+	return findByKey[models.ApiKey](ctx, r.collection, "api_key_id", apiKeyId, true)
+}
+
+func (r *mongoApiKeyRepository) Create(ctx context.Context, apiKey models.ApiKey) error {
+	_, err := r.collection.InsertOne(ctx, apiKey)
+	return mongoError(err)
+}
+
+func (r *mongoApiKeyRepository) Delete(ctx context.Context, apiKeyId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"api_key_id": apiKeyId}, d, true)
+}
+
+type memoryApiKeyRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryApiKeyRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.ApiKey], error) {
+	apiKeys, err := r.db.apiKeys.find(nil, includeDeleted)
+	if err != nil {
+		return Paged[models.ApiKey]{}, err
+	}
+	return pageOf(apiKeys, oldestFirst("api_key_id"), page, nil)
+}
+
+func (r *memoryApiKeyRepository) FindByID(ctx context.Context, apiKeyId string) (models.ApiKey, error) {
+	return r.db.apiKeys.get(apiKeyId, false)
+}
+
+func (r *memoryApiKeyRepository) FindByIDWithDeleted(ctx context.Context, apiKeyId string) (models.ApiKey, error) {
+	return r.db.apiKeys.get(apiKeyId, true)
+}
+
+func (r *memoryApiKeyRepository) Create(ctx context.Context, apiKey models.ApiKey) error {
+	return r.db.apiKeys.insert(apiKey)
+}
+
+func (r *memoryApiKeyRepository) Delete(ctx context.Context, apiKeyId string, d Deletion) error {
+	return r.db.apiKeys.markDeleted(apiKeyId, d)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AuditFilter narrows an audit query. Empty fields and zero times do not filter.
+type AuditFilter struct {
+	Resource    string
+	Resource_id string
+	Actor_id    string
+	From        time.Time // inclusive
+	To          time.Time // exclusive
+}
+
+// AuditRepository has no update or delete on purpose: the audit log is append only.
+type AuditRepository interface {
+	Create(ctx context.Context, entry models.AuditEntry) error
+	// List returns one page of the entries matching filter, newest first.
+	List(ctx context.Context, filter AuditFilter, page Page) (Paged[models.AuditEntry], error)
+}
+
+type mongoAuditRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoAuditRepository) Create(ctx context.Context, entry models.AuditEntry) error {
+	_, err := r.collection.InsertOne(ctx, entry)
+	return mongoError(err)
+}
+
+func (r *mongoAuditRepository) List(ctx context.Context, filter AuditFilter, page Page) (Paged[models.AuditEntry], error) {
+	query := bson.M{}
+	if filter.Resource != "" {
+		query["resource"] = filter.Resource
+	}
+	if filter.Resource_id != "" {
+		query["resource_id"] = filter.Resource_id
+	}
+	if filter.Actor_id != "" {
+		query["actor_id"] = filter.Actor_id
+	}
+	at := bson.M{}
+	if !filter.From.IsZero() {
+		at["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		at["$lt"] = filter.To
+	}
+	if len(at) > 0 {
+		query["at"] = at
+	}
+
+	return findPage[models.AuditEntry](ctx, r.collection, query, newestFirst("audit_id"), page)
+}
+
+type memoryAuditRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryAuditRepository) Create(ctx context.Context, entry models.AuditEntry) error {
+	return r.db.audit.insert(entry)
+}
+
+func (r *memoryAuditRepository) List(ctx context.Context, filter AuditFilter, page Page) (Paged[models.AuditEntry], error) {
+	entries, err := r.db.audit.find(func(entry models.AuditEntry) bool {
+		return (filter.Resource == "" || entry.Resource == filter.Resource) &&
+			(filter.Resource_id == "" || entry.Resource_id == filter.Resource_id) &&
+			(filter.Actor_id == "" || entry.Actor_id == filter.Actor_id) &&
+			(filter.From.IsZero() || !entry.At.Before(filter.From)) &&
+			(filter.To.IsZero() || entry.At.Before(filter.To))
+	}, false)
+	if err != nil {
+		return Paged[models.AuditEntry]{}, err
+	}
+	return pageOf(entries, newestFirst("audit_id"), page, nil)
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ClaimRepository keeps the one-time claims. A claim is never released.
+type ClaimRepository interface {
+	// Claim claims name at at. It returns ErrDuplicate when name was claimed before, so inside a transaction it
+	// guards a step that may only ever be taken once.
+	Claim(ctx context.Context, name string, at time.Time) error
+}
+
+type mongoClaimRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoClaimRepository) Claim(ctx context.Context, name string, at time.Time) error {
+	_, err := r.collection.InsertOne(ctx, models.Claim{Name: name, Claimed_at: at})
+	return mongoError(err)
+}
+
+type memoryClaimRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryClaimRepository) Claim(ctx context.Context, name string, at time.Time) error {
+	return r.db.claims.insert(models.Claim{Name: name, Claimed_at: at})
+}
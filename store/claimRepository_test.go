@@ -0,0 +1,44 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"restaurant-management-system/store"
+)
+
+func TestClaimsAreTakenOnce(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		if err := s.Claims.Claim(ctx, "first_account", time.Now()); err != nil {
+			t.Fatalf("the first claim failed: %v", err)
+		}
+		if err := s.Claims.Claim(ctx, "first_account", time.Now()); !errors.Is(err, store.ErrDuplicate) {
+			t.Fatalf("claiming again returned %v, want ErrDuplicate", err)
+		}
+		if err := s.Claims.Claim(ctx, "other", time.Now()); err != nil {
+			t.Fatalf("claiming another name failed: %v", err)
+		}
+	})
+}
+
+func TestClaimsRollBackWithTheirTransaction(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		refused := errors.New("refused")
+		err := s.Transaction(ctx, func(ctx context.Context, tx *store.Store) error {
+			if err := tx.Claims.Claim(ctx, "first_account", time.Now()); err != nil {
+				return err
+			}
+			return refused
+		})
+		if !errors.Is(err, refused) {
+			t.Fatalf("the transaction returned %v", err)
+		}
+		if err := s.Claims.Claim(ctx, "first_account", time.Now()); err != nil {
+			t.Fatalf("claiming after the rollback failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FoodFilter narrows a food query. Empty fields and nil pointers do not filter.
+type FoodFilter struct {
+	Search          string // words looked up in the name and description
+	Menu_id         string
+	Min_price       *float64 // inclusive
+	Max_price       *float64 // inclusive
+	Tags            []string // a food has to carry all of them
+	Available       *bool
+	Sort            string // name, price or created_at; empty sorts by relevance when searching, else by created_at
+	Descending      bool   // reverses Sort
+	Include_deleted bool
+}
+
+type FoodRepository interface {
+	// List returns one page of the foods matching filter, in the order it asks for.
+	List(ctx context.Context, filter FoodFilter, page Page) (Paged[models.Food], error)
+	// FindByID returns ErrNotFound for a deleted food. FindByIDWithDeleted returns it.
+	FindByID(ctx context.Context, foodId string) (models.Food, error)
+	FindByIDWithDeleted(ctx context.Context, foodId string) (models.Food, error)
+	// CountByMenu counts the foods of a menu that are not deleted.
+	CountByMenu(ctx context.Context, menuId string) (int64, error)
+	Create(ctx context.Context, food models.Food) error
+	// Update applies updateObj as a $set on the food and increments its version. With a version other than 0 it
+	// returns ErrConflict unless the food is still at that version. A missing or deleted food is ErrNotFound.
+	Update(ctx context.Context, foodId string, updateObj primitive.D, version int) error
+	// Delete soft deletes the food. It returns ErrNotFound when the food does not exist or is already deleted.
+	Delete(ctx context.Context, foodId string, d Deletion) error
+	Restore(ctx context.Context, foodId string) error
+}
+
+type mongoFoodRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoFoodRepository) List(ctx context.Context, filter FoodFilter, page Page) (Paged[models.Food], error) {
+	query := liveFilter(bson.M{}, filter.Include_deleted)
+	if filter.Search != "" {
+		// $text uses the text index on name and description, and has to be part of the first $match.
+		query["$text"] = bson.M{"$search": filter.Search}
+	}
+	if filter.Menu_id != "" {
+		query["menu_id"] = filter.Menu_id
+	}
+	price := bson.M{}
+	if filter.Min_price != nil {
+		price["$gte"] = *filter.Min_price
+	}
+	if filter.Max_price != nil {
+		price["$lte"] = *filter.Max_price
+	}
+	if len(price) > 0 {
+		query["price"] = price
+	}
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$all": filter.Tags}
+	}
+	// Foods from before availability existed have none and are available.
+	if filter.Available != nil && *filter.Available {
+		query["available"] = bson.M{"$ne": false}
+	} else if filter.Available != nil {
+		query["available"] = false
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: query}}}
+	if filter.Search != "" {
+		// The score is stored in a field, so that it can be sorted on and held by a cursor.
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}})
+	}
+	return aggregatePage[models.Food](ctx, r.collection, pipeline, foodOrder(filter), page)
+}
+
+func (r *mongoFoodRepository) FindByID(ctx context.Context, foodId string) (models.Food, error) {
+	return findByKey[models.Food](ctx, r.collection, "food_id", foodId, false)
+}
+
+func (r *mongoFoodRepository) FindByIDWithDeleted(ctx context.Context, foodId string) (models.Food, error) {
+	return findByKey[models.Food](ctx, r.collection, "food_id", foodId, true)
+}
+
+func (r *mongoFoodRepository) CountByMenu(ctx context.Context, menuId string) (int64, error) {
+	return r.collection.CountDocuments(ctx, liveFilter(bson.M{"menu_id": menuId}, false))
+}
+
+func (r *mongoFoodRepository) Create(ctx context.Context, food models.Food) error {
+	_, err := r.collection.InsertOne(ctx, food)
+	return mongoError(err)
+}
+
+func (r *mongoFoodRepository) Update(ctx context.Context, foodId string, updateObj primitive.D, version int) error {
+	return updateVersioned(ctx, r.collection, "food_id", foodId, updateObj, version)
+}
+
+func (r *mongoFoodRepository) Delete(ctx context.Context, foodId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"food_id": foodId}, d, true)
+}
+
+func (r *mongoFoodRepository) Restore(ctx context.Context, foodId string) error {
+	return restore(ctx, r.collection, bson.M{"food_id": foodId}, true)
+}
+
+type memoryFoodRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryFoodRepository) List(ctx context.Context, filter FoodFilter, page Page) (Paged[models.Food], error) {
+	// Text search is approximated by matching whole words of name and description, without the stemming of a text
+	// index, and relevance is the number of search words a food contains.
+	words := strings.Fields(strings.ToLower(filter.Search))
+	score := map[string]int{}
+	foods, err := r.db.foods.find(func(food models.Food) bool {
+		if len(words) > 0 {
+			text := map[string]bool{}
+			for _, field := range []*string{food.Name, food.Description} {
+				if field != nil {
+					for _, word := range strings.FieldsFunc(strings.ToLower(*field), isNotWordRune) {
+						text[word] = true
+					}
+				}
+			}
+			for _, word := range words {
+				if text[word] {
+					score[food.Food_id]++
+				}
+			}
+			if score[food.Food_id] == 0 {
+				return false
+			}
+		}
+		if filter.Menu_id != "" && (food.Menu_id == nil || *food.Menu_id != filter.Menu_id) {
+			return false
+		}
+		if filter.Min_price != nil && (food.Price == nil || *food.Price < *filter.Min_price) {
+			return false
+		}
+		if filter.Max_price != nil && (food.Price == nil || *food.Price > *filter.Max_price) {
+			return false
+		}
+		for _, tag := range filter.Tags {
+			if !slices.Contains(food.Tags, tag) {
+				return false
+			}
+		}
+		return filter.Available == nil || (food.Available == nil || *food.Available) == *filter.Available
+	}, filter.Include_deleted)
+	if err != nil {
+		return Paged[models.Food]{}, err
+	}
+	return pageOf(foods, foodOrder(filter), page, func(food models.Food) bson.M {
+		return bson.M{"score": score[food.Food_id]}
+	})
+}
+
+func (r *memoryFoodRepository) FindByID(ctx context.Context, foodId string) (models.Food, error) {
+	return r.db.foods.get(foodId, false)
+}
+
+func (r *memoryFoodRepository) FindByIDWithDeleted(ctx context.Context, foodId string) (models.Food, error) {
+	return r.db.foods.get(foodId, true)
+}
+
+func (r *memoryFoodRepository) CountByMenu(ctx context.Context, menuId string) (int64, error) {
+	foods, err := r.db.foods.find(func(food models.Food) bool {
+		return food.Menu_id != nil && *food.Menu_id == menuId
+	}, false)
+	return int64(len(foods)), err
+}
+
+func (r *memoryFoodRepository) Create(ctx context.Context, food models.Food) error {
+	return r.db.foods.insert(food)
+}
+
+func (r *memoryFoodRepository) Update(ctx context.Context, foodId string, updateObj primitive.D, version int) error {
+	return r.db.foods.update(foodId, updateObj, version)
+}
+
+func (r *memoryFoodRepository) Delete(ctx context.Context, foodId string, d Deletion) error {
+	return r.db.foods.markDeleted(foodId, d)
+}
+
+func (r *memoryFoodRepository) Restore(ctx context.Context, foodId string) error {
+	return r.db.foods.unmarkDeleted(foodId)
+}
+
+// foodOrder is the order filter asks for. The food_id breaks ties, so that a page boundary between equal names or
+// prices does not move between requests.
+func foodOrder(filter FoodFilter) bson.D {
+	direction := 1
+	if filter.Descending {
+		direction = -1
+	}
+	switch {
+	case filter.Sort != "":
+		return bson.D{{Key: filter.Sort, Value: direction}, {Key: "food_id", Value: 1}}
+	case filter.Search != "":
+		return bson.D{{Key: "score", Value: -1}, {Key: "food_id", Value: 1}}
+	default:
+		return oldestFirst("food_id")
+	}
+}
+
+// isNotWordRune splits text into the words a text index would see.
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
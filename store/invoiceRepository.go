@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type InvoiceRepository interface {
+	// List returns one page of the invoices that are not deleted, or of every invoice with includeDeleted,
+	// oldest first.
+	List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Invoice], error)
+	// FindByID returns ErrNotFound for a deleted invoice. FindByIDWithDeleted returns it.
+	FindByID(ctx context.Context, invoiceId string) (models.Invoice, error)
+	FindByIDWithDeleted(ctx context.Context, invoiceId string) (models.Invoice, error)
+	// ListByOrder returns the invoices of an order that are not deleted.
+	ListByOrder(ctx context.Context, orderId string) ([]models.Invoice, error)
+	Create(ctx context.Context, invoice models.Invoice) error
+	// Update applies updateObj as a $set on the invoice and increments its version. With a version other than 0 it
+	// returns ErrConflict unless the invoice is still at that version. A missing or deleted invoice is ErrNotFound.
+	Update(ctx context.Context, invoiceId string, updateObj primitive.D, version int) error
+	// Delete soft deletes the invoice. It returns ErrNotFound when the invoice does not exist or is already deleted.
+	Delete(ctx context.Context, invoiceId string, d Deletion) error
+	Restore(ctx context.Context, invoiceId string) error
+}
+
+type mongoInvoiceRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoInvoiceRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Invoice], error) {
+	return findPage[models.Invoice](ctx, r.collection, liveFilter(bson.M{}, includeDeleted), oldestFirst("invoice_id"), page)
+}
+
+func (r *mongoInvoiceRepository) FindByID(ctx context.Context, invoiceId string) (models.Invoice, error) {
+	return findByKey[models.Invoice](ctx, r.collection, "invoice_id", invoiceId, false)
+}
+
+func (r *mongoInvoiceRepository) FindByIDWithDeleted(ctx context.Context, invoiceId string) (models.Invoice, error) {
+	return findByKey[models.Invoice](ctx, r.collection, "invoice_id", invoiceId, true)
+}
+
+func (r *mongoInvoiceRepository) ListByOrder(ctx context.Context, orderId string) ([]models.Invoice, error) {
+	return findAll[models.Invoice](ctx, r.collection, liveFilter(bson.M{"order_id": orderId}, false))
+}
+
+func (r *mongoInvoiceRepository) Create(ctx context.Context, invoice models.Invoice) error {
+	_, err := r.collection.InsertOne(ctx, invoice)
+	return mongoError(err)
+}
+
+func (r *mongoInvoiceRepository) Update(ctx context.Context, invoiceId string, updateObj primitive.D, version int) error {
+	return updateVersioned(ctx, r.collection, "invoice_id", invoiceId, updateObj, version)
+}
+
+func (r *mongoInvoiceRepository) Delete(ctx context.Context, invoiceId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"invoice_id": invoiceId}, d, true)
+}
+
+func (r *mongoInvoiceRepository) Restore(ctx context.Context, invoiceId string) error {
+	return restore(ctx, r.collection, bson.M{"invoice_id": invoiceId}, true)
+}
+
+type memoryInvoiceRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryInvoiceRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Invoice], error) {
+	invoices, err := r.db.invoices.find(nil, includeDeleted)
+	if err != nil {
+		return Paged[models.Invoice]{}, err
+	}
+	return pageOf(invoices, oldestFirst("invoice_id"), page, nil)
+}
+
+func (r *memoryInvoiceRepository) FindByID(ctx context.Context, invoiceId string) (models.Invoice, error) {
+	return r.db.invoices.get(invoiceId, false)
+}
+
+func (r *memoryInvoiceRepository) FindByIDWithDeleted(ctx context.Context, invoiceId string) (models.Invoice, error) {
+	return r.db.invoices.get(invoiceId, true)
+}
+
+func (r *memoryInvoiceRepository) ListByOrder(ctx context.Context, orderId string) ([]models.Invoice, error) {
+	return r.db.invoices.find(func(invoice models.Invoice) bool {
+		return invoice.Order_id == orderId
+	}, false)
+}
+
+func (r *memoryInvoiceRepository) Create(ctx context.Context, invoice models.Invoice) error {
+	return r.db.invoices.insert(invoice)
+}
+
+func (r *memoryInvoiceRepository) Update(ctx context.Context, invoiceId string, updateObj primitive.D, version int) error {
+	return r.db.invoices.update(invoiceId, updateObj, version)
+}
+
+func (r *memoryInvoiceRepository) Delete(ctx context.Context, invoiceId string, d Deletion) error {
+	return r.db.invoices.markDeleted(invoiceId, d)
+}
+
+func (r *memoryInvoiceRepository) Restore(ctx context.Context, invoiceId string) error {
+	return r.db.invoices.unmarkDeleted(invoiceId)
+}
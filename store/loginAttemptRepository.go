@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoginAttemptFilter narrows a query of the login attempts. Empty fields and zero times do not filter.
+type LoginAttemptFilter struct {
+	Email   string
+	User_id string
+	Ip      string
+	Outcome string
+	From    time.Time // inclusive
+	To      time.Time // exclusive
+}
+
+// LoginAttemptRepository keeps the record of every login attempt. Like the audit log it is append only.
+type LoginAttemptRepository interface {
+	Create(ctx context.Context, attempt models.LoginAttempt) error
+	// List returns one page of the attempts matching filter, newest first.
+	List(ctx context.Context, filter LoginAttemptFilter, page Page) (Paged[models.LoginAttempt], error)
+}
+
+type mongoLoginAttemptRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoLoginAttemptRepository) Create(ctx context.Context, attempt models.LoginAttempt) error {
+	_, err := r.collection.InsertOne(ctx, attempt)
+	return mongoError(err)
+}
+
+func (r *mongoLoginAttemptRepository) List(ctx context.Context, filter LoginAttemptFilter, page Page) (Paged[models.LoginAttempt], error) {
+	query := bson.M{}
+	if filter.Email != "" {
+		query["email"] = filter.Email
+	}
+	if filter.User_id != "" {
+		query["user_id"] = filter.User_id
+	}
+	if filter.Ip != "" {
+		query["ip"] = filter.Ip
+	}
+	if filter.Outcome != "" {
+		query["outcome"] = filter.Outcome
+	}
+	at := bson.M{}
+	if !filter.From.IsZero() {
+		at["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		at["$lt"] = filter.To
+	}
+	if len(at) > 0 {
+		query["at"] = at
+	}
+
+	return findPage[models.LoginAttempt](ctx, r.collection, query, newestFirst("login_attempt_id"), page)
+}
+
+type memoryLoginAttemptRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryLoginAttemptRepository) Create(ctx context.Context, attempt models.LoginAttempt) error {
+	return r.db.loginAttempts.insert(attempt)
+}
+
+func (r *memoryLoginAttemptRepository) List(ctx context.Context, filter LoginAttemptFilter, page Page) (Paged[models.LoginAttempt], error) {
+	attempts, err := r.db.loginAttempts.find(func(attempt models.LoginAttempt) bool {
+		return (filter.Email == "" || attempt.Email == filter.Email) &&
+			(filter.User_id == "" || (attempt.User_id != nil && *attempt.User_id == filter.User_id)) &&
+			(filter.Ip == "" || attempt.Ip == filter.Ip) &&
+			(filter.Outcome == "" || attempt.Outcome == filter.Outcome) &&
+			(filter.From.IsZero() || !attempt.At.Before(filter.From)) &&
+			(filter.To.IsZero() || attempt.At.Before(filter.To))
+	}, false)
+	if err != nil {
+		return Paged[models.LoginAttempt]{}, err
+	}
+	return pageOf(attempts, newestFirst("login_attempt_id"), page, nil)
+}
+
+// LoginThrottleRepository counts failed logins per account and per client IP.
+type LoginThrottleRepository interface {
+	// Find returns the throttle of key, or ErrNotFound when key has no recent failures.
+	Find(ctx context.Context, key string) (models.LoginThrottle, error)
+	// Fail counts a failed login for key at at. Failures older than window are forgotten first, so the count starts
+	// over once key has stayed quiet for that long. Fail is atomic on its own and must not be called inside a
+	// transaction: on MongoDB a lost race to create the count aborts the transaction it ran in.
+	Fail(ctx context.Context, key string, at time.Time, window time.Duration) (models.LoginThrottle, error)
+	// Reset forgets the failures of key. A key without failures is left as it is.
+	Reset(ctx context.Context, key string) error
+}
+
+type mongoLoginThrottleRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoLoginThrottleRepository) Find(ctx context.Context, key string) (models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	err := r.collection.FindOne(ctx, bson.M{"key": key}).Decode(&throttle)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return throttle, ErrNotFound
+	}
+	return throttle, err
+}
+
+// Fail counts in a single pipeline update, so concurrent failures cannot overwrite each other's count. Two upserts
+// of a new key can still race; the loser hits the unique index on key and simply tries again.
+func (r *mongoLoginThrottleRepository) Fail(ctx context.Context, key string, at time.Time, window time.Duration) (models.LoginThrottle, error) {
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"key": key,
+		"failures": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$last_failure", at.Add(-window)}},
+			bson.M{"$add": bson.A{"$failures", 1}},
+			1,
+		}},
+		"last_failure": at,
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var throttle models.LoginThrottle
+	for attempt := 0; ; attempt++ {
+		err := mongoError(r.collection.FindOneAndUpdate(ctx, bson.M{"key": key}, update, opts).Decode(&throttle))
+		if errors.Is(err, ErrDuplicate) && attempt == 0 {
+			continue
+		}
+		return throttle, err
+	}
+}
+
+func (r *mongoLoginThrottleRepository) Reset(ctx context.Context, key string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"key": key})
+	return mongoError(err)
+}
+
+type memoryLoginThrottleRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryLoginThrottleRepository) Find(ctx context.Context, key string) (models.LoginThrottle, error) {
+	return r.db.loginThrottles.get(key, false)
+}
+
+// Fail counts in a transaction of its own, which holds every lock of the in-memory store, so reading the count and
+// writing it back cannot interleave with another failure.
+func (r *memoryLoginThrottleRepository) Fail(ctx context.Context, key string, at time.Time, window time.Duration) (models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+	err := r.db.transaction(func(tx *memoryDatabase) (err error) {
+		throttle, err = tx.loginThrottles.get(key, false)
+		if errors.Is(err, ErrNotFound) {
+			throttle = models.LoginThrottle{Key: key}
+		} else if err != nil {
+			return err
+		}
+		if throttle.Last_failure.After(at.Add(-window)) {
+			throttle.Failures++
+		} else {
+			throttle.Failures = 1
+		}
+		throttle.Last_failure = at
+		tx.loginThrottles.remove(key)
+		return tx.loginThrottles.insert(throttle)
+	})
+	return throttle, err
+}
+
+func (r *memoryLoginThrottleRepository) Reset(ctx context.Context, key string) error {
+	r.db.loginThrottles.remove(key)
+	return nil
+}
@@ -0,0 +1,73 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"restaurant-management-system/store"
+)
+
+func TestLoginThrottlesCountFailures(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		key := "account:user@example.com"
+		window := 15 * time.Minute
+		now := time.Now().UTC().Truncate(time.Millisecond)
+
+		if _, err := s.LoginThrottles.Find(ctx, key); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("finding a key without failures returned %v, want ErrNotFound", err)
+		}
+		for want := 1; want <= 2; want++ {
+			throttle, err := s.LoginThrottles.Fail(ctx, key, now, window)
+			if err != nil || throttle.Failures != want {
+				t.Fatalf("failure %d counted %d, %v", want, throttle.Failures, err)
+			}
+		}
+		throttle, err := s.LoginThrottles.Find(ctx, key)
+		if err != nil || throttle.Failures != 2 || !throttle.Last_failure.Equal(now) {
+			t.Fatalf("finding the key returned %+v, %v", throttle, err)
+		}
+
+		// A key that stayed quiet for the window starts over.
+		throttle, err = s.LoginThrottles.Fail(ctx, key, now.Add(window), window)
+		if err != nil || throttle.Failures != 1 {
+			t.Fatalf("a failure after the window counted %d, %v", throttle.Failures, err)
+		}
+
+		if err := s.LoginThrottles.Reset(ctx, key); err != nil {
+			t.Fatalf("resetting the key: %v", err)
+		}
+		if _, err := s.LoginThrottles.Find(ctx, key); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("finding a reset key returned %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestConcurrentLoginFailuresAreAllCounted(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		key := "ip:192.0.2.1"
+		now := time.Now()
+		const failures = 20
+
+		var wg sync.WaitGroup
+		for range failures {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.LoginThrottles.Fail(ctx, key, now, time.Minute); err != nil {
+					t.Errorf("counting a failure: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		throttle, err := s.LoginThrottles.Find(ctx, key)
+		if err != nil || throttle.Failures != failures {
+			t.Fatalf("%d concurrent failures counted %d, %v", failures, throttle.Failures, err)
+		}
+	})
+}
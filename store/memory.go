@@ -0,0 +1,347 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryDatabase holds one memoryCollection per resource. The repositories share it so that lookups across
+// collections (for example the food details of an order item) see the same data.
+type memoryDatabase struct {
+	foods          *memoryCollection[models.Food]
+	menus          *memoryCollection[models.Menu]
+	tables         *memoryCollection[models.Table]
+	orders         *memoryCollection[models.Order]
+	orderItems     *memoryCollection[models.OrderItem]
+	invoices       *memoryCollection[models.Invoice]
+	users          *memoryCollection[models.User]
+	audit          *memoryCollection[models.AuditEntry]
+	revocations    *memoryCollection[models.Revocation]
+	apiKeys        *memoryCollection[models.ApiKey]
+	userTokens     *memoryCollection[models.UserToken]
+	loginAttempts  *memoryCollection[models.LoginAttempt]
+	loginThrottles *memoryCollection[models.LoginThrottle]
+	signingKeys    *memoryCollection[models.SigningKey]
+	claims         *memoryCollection[models.Claim]
+}
+
+func newMemoryDatabase() *memoryDatabase {
+	return &memoryDatabase{
+		foods:          newMemoryCollection[models.Food]("food_id"),
+		menus:          newMemoryCollection[models.Menu]("menu_id"),
+		tables:         newMemoryCollection[models.Table]("table_id", "table_number"),
+		orders:         newMemoryCollection[models.Order]("order_id"),
+		orderItems:     newMemoryCollection[models.OrderItem]("order_item_id"),
+		invoices:       newMemoryCollection[models.Invoice]("invoice_id"),
+		users:          newMemoryCollection[models.User]("user_id", "email", "phone"),
+		audit:          newMemoryCollection[models.AuditEntry]("audit_id"),
+		revocations:    newMemoryCollection[models.Revocation]("revocation_id"),
+		apiKeys:        newMemoryCollection[models.ApiKey]("api_key_id"),
+		userTokens:     newMemoryCollection[models.UserToken]("user_token_id", "token_hash"),
+		loginAttempts:  newMemoryCollection[models.LoginAttempt]("login_attempt_id"),
+		loginThrottles: newMemoryCollection[models.LoginThrottle]("key"),
+		signingKeys:    newMemoryCollection[models.SigningKey]("kid"),
+		claims:         newMemoryCollection[models.Claim]("name"),
+	}
+}
+
+// lockers returns the lock of every collection, always in the same order so that taking them all cannot deadlock.
+func (db *memoryDatabase) lockers() []sync.Locker {
+	return []sync.Locker{
+		&db.foods.mu, &db.menus.mu, &db.tables.mu, &db.orders.mu, &db.orderItems.mu, &db.invoices.mu, &db.users.mu,
+		&db.audit.mu, &db.revocations.mu, &db.apiKeys.mu, &db.userTokens.mu, &db.loginAttempts.mu,
+		&db.loginThrottles.mu, &db.signingKeys.mu, &db.claims.mu,
+	}
+}
+
+// transaction runs fn against a copy of the database and swaps the copy in when fn succeeds. Every collection stays
+// write locked meanwhile, so no other write can land between the copy and the swap and be lost. Transactions are
+// serialised by the same locks.
+func (db *memoryDatabase) transaction(fn func(tx *memoryDatabase) error) error {
+	for _, l := range db.lockers() {
+		l.Lock()
+	}
+	defer func() {
+		for _, l := range db.lockers() {
+			l.Unlock()
+		}
+	}()
+
+	tx := &memoryDatabase{
+		foods:          db.foods.clone(),
+		menus:          db.menus.clone(),
+		tables:         db.tables.clone(),
+		orders:         db.orders.clone(),
+		orderItems:     db.orderItems.clone(),
+		invoices:       db.invoices.clone(),
+		users:          db.users.clone(),
+		audit:          db.audit.clone(),
+		revocations:    db.revocations.clone(),
+		apiKeys:        db.apiKeys.clone(),
+		userTokens:     db.userTokens.clone(),
+		loginAttempts:  db.loginAttempts.clone(),
+		loginThrottles: db.loginThrottles.clone(),
+		signingKeys:    db.signingKeys.clone(),
+		claims:         db.claims.clone(),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	db.foods.replace(tx.foods)
+	db.menus.replace(tx.menus)
+	db.tables.replace(tx.tables)
+	db.orders.replace(tx.orders)
+	db.orderItems.replace(tx.orderItems)
+	db.invoices.replace(tx.invoices)
+	db.users.replace(tx.users)
+	db.audit.replace(tx.audit)
+	db.revocations.replace(tx.revocations)
+	db.apiKeys.replace(tx.apiKeys)
+	db.userTokens.replace(tx.userTokens)
+	db.loginAttempts.replace(tx.loginAttempts)
+	db.loginThrottles.replace(tx.loginThrottles)
+	db.signingKeys.replace(tx.signingKeys)
+	db.claims.replace(tx.claims)
+	return nil
+}
+
+// memoryCollection stores documents the way MongoDB would see them: every document is kept as a bson.M so that
+// $set style updates behave exactly like they do against a real collection, and every read decodes a fresh copy so
+// callers can never mutate the stored data through a pointer field.
+type memoryCollection[T any] struct {
+	mu     sync.RWMutex
+	key    string   // bson name of the custom identifier, e.g. "food_id"
+	unique []string // other fields that must be unique, like the unique indexes created by the migrations
+	order  []string // insertion order, so listings are stable
+	docs   map[string]bson.M
+}
+
+func newMemoryCollection[T any](key string, unique ...string) *memoryCollection[T] {
+	return &memoryCollection[T]{key: key, unique: unique, docs: map[string]bson.M{}}
+}
+
+// clone copies the collection. Stored documents are never modified in place, only replaced, so the copies can share
+// them. The caller must hold the lock.
+func (m *memoryCollection[T]) clone() *memoryCollection[T] {
+	docs := make(map[string]bson.M, len(m.docs))
+	for id, doc := range m.docs {
+		docs[id] = doc
+	}
+	return &memoryCollection[T]{
+		key:    m.key,
+		unique: m.unique,
+		order:  append([]string(nil), m.order...),
+		docs:   docs,
+	}
+}
+
+// replace takes over the documents of other. The caller must hold the lock.
+func (m *memoryCollection[T]) replace(other *memoryCollection[T]) {
+	m.docs = other.docs
+	m.order = other.order
+}
+
+// violatesUnique reports whether doc would share a unique field with a document other than id. Missing and null
+// values never collide. The caller must hold the lock.
+func (m *memoryCollection[T]) violatesUnique(id string, doc bson.M) bool {
+	for _, field := range m.unique {
+		value, ok := doc[field]
+		if !ok || value == nil {
+			continue
+		}
+		for otherId, other := range m.docs {
+			if otherId != id && other[field] == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toDocument(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func fromDocument[T any](doc bson.M) (T, error) {
+	var v T
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return v, err
+	}
+	err = bson.Unmarshal(data, &v)
+	return v, err
+}
+
+func (m *memoryCollection[T]) insert(v T) error {
+	doc, err := toDocument(v)
+	if err != nil {
+		return err
+	}
+	id, _ := doc[m.key].(string)
+	if id == "" {
+		return fmt.Errorf("%s is required", m.key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.docs[id]; ok || m.violatesUnique(id, doc) {
+		return ErrDuplicate
+	}
+	m.docs[id] = doc
+	m.order = append(m.order, id)
+	return nil
+}
+
+// deleted reports whether a stored document carries a soft delete stamp.
+func deleted(doc bson.M) bool {
+	return doc["deleted_at"] != nil
+}
+
+func (m *memoryCollection[T]) get(id string, includeDeleted bool) (T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	doc, ok := m.docs[id]
+	if !ok || (!includeDeleted && deleted(doc)) {
+		var zero T
+		return zero, ErrNotFound
+	}
+	return fromDocument[T](doc)
+}
+
+// find returns every document accepted by match, in insertion order. A nil match accepts everything. Deleted
+// documents are only considered with includeDeleted.
+func (m *memoryCollection[T]) find(match func(T) bool, includeDeleted bool) ([]T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := []T{}
+	for _, id := range m.order {
+		if !includeDeleted && deleted(m.docs[id]) {
+			continue
+		}
+		v, err := fromDocument[T](m.docs[id])
+		if err != nil {
+			return nil, err
+		}
+		if match == nil || match(v) {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// update applies a $set document to a live document and increments its version, like updateVersioned does
+// against MongoDB. With a version other than 0 the document must still be at that version.
+func (m *memoryCollection[T]) update(id string, set primitive.D, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	doc, ok := m.docs[id]
+	if !ok || deleted(doc) {
+		return ErrNotFound
+	}
+	if version != 0 && docVersion(doc) != version {
+		return ErrConflict
+	}
+
+	updated := withFields(doc, bson.M{"version": docVersion(doc) + 1})
+	for _, e := range set {
+		updated[e.Key] = e.Value
+	}
+	// Round trip through T so that only fields the model knows about survive, and values are normalised to what a
+	// read would return.
+	v, err := fromDocument[T](updated)
+	if err != nil {
+		return err
+	}
+	normalised, err := toDocument(v)
+	if err != nil {
+		return err
+	}
+	if m.violatesUnique(id, normalised) {
+		return ErrDuplicate
+	}
+	m.docs[id] = normalised
+	return nil
+}
+
+// markDeleted stamps a live document as deleted.
+func (m *memoryCollection[T]) markDeleted(id string, d Deletion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	doc, ok := m.docs[id]
+	if !ok || deleted(doc) {
+		return ErrNotFound
+	}
+	m.docs[id] = withFields(doc, bson.M{
+		"deleted_at": primitive.NewDateTimeFromTime(d.At),
+		"deleted_by": d.By,
+		"version":    docVersion(doc) + 1,
+	})
+	return nil
+}
+
+// unmarkDeleted removes the soft delete stamp from a document. A document that is not deleted is left as it is.
+func (m *memoryCollection[T]) unmarkDeleted(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	doc, ok := m.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if !deleted(doc) {
+		return nil
+	}
+	restored := withFields(doc, bson.M{"version": docVersion(doc) + 1})
+	delete(restored, "deleted_at")
+	delete(restored, "deleted_by")
+	m.docs[id] = restored
+	return nil
+}
+
+// remove deletes a document for good. Only collections whose documents are not soft deleted use it.
+func (m *memoryCollection[T]) remove(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.docs[id]; !ok {
+		return
+	}
+	delete(m.docs, id)
+	m.order = slices.DeleteFunc(m.order, func(other string) bool { return other == id })
+}
+
+// docVersion reads the version of a stored document. BSON may hold it as a 32 or a 64 bit integer.
+func docVersion(doc bson.M) int {
+	switch v := doc["version"].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+	return 0
+}
+
+// withFields returns a copy of doc with fields set. Stored documents are replaced, never modified in place.
+func withFields(doc bson.M, fields bson.M) bson.M {
+	updated := bson.M{}
+	for k, v := range doc {
+		updated[k] = v
+	}
+	for k, v := range fields {
+		updated[k] = v
+	}
+	return updated
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MenuRepository interface {
+	// List returns one page of the menus that are not deleted, or of every menu with includeDeleted, oldest first.
+	List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Menu], error)
+	// FindByID returns ErrNotFound for a deleted menu. FindByIDWithDeleted returns it.
+	FindByID(ctx context.Context, menuId string) (models.Menu, error)
+	FindByIDWithDeleted(ctx context.Context, menuId string) (models.Menu, error)
+	Create(ctx context.Context, menu models.Menu) error
+	// Update applies updateObj as a $set on the menu and increments its version. With a version other than 0 it
+	// returns ErrConflict unless the menu is still at that version. A missing or deleted menu is ErrNotFound.
+	Update(ctx context.Context, menuId string, updateObj primitive.D, version int) error
+	// Delete soft deletes the menu. It returns ErrNotFound when the menu does not exist or is already deleted.
+	Delete(ctx context.Context, menuId string, d Deletion) error
+	Restore(ctx context.Context, menuId string) error
+}
+
+type mongoMenuRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoMenuRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Menu], error) {
+	return findPage[models.Menu](ctx, r.collection, liveFilter(bson.M{}, includeDeleted), oldestFirst("menu_id"), page)
+}
+
+func (r *mongoMenuRepository) FindByID(ctx context.Context, menuId string) (models.Menu, error) {
+	return findByKey[models.Menu](ctx, r.collection, "menu_id", menuId, false)
+}
+
+func (r *mongoMenuRepository) FindByIDWithDeleted(ctx context.Context, menuId string) (models.Menu, error) {
+	return findByKey[models.Menu](ctx, r.collection, "menu_id", menuId, true)
+}
+
+func (r *mongoMenuRepository) Create(ctx context.Context, menu models.Menu) error {
+	_, err := r.collection.InsertOne(ctx, menu)
+	return mongoError(err)
+}
+
+func (r *mongoMenuRepository) Update(ctx context.Context, menuId string, updateObj primitive.D, version int) error {
+	return updateVersioned(ctx, r.collection, "menu_id", menuId, updateObj, version)
+}
+
+func (r *mongoMenuRepository) Delete(ctx context.Context, menuId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"menu_id": menuId}, d, true)
+}
+
+func (r *mongoMenuRepository) Restore(ctx context.Context, menuId string) error {
+	return restore(ctx, r.collection, bson.M{"menu_id": menuId}, true)
+}
+
+type memoryMenuRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryMenuRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Menu], error) {
+	menus, err := r.db.menus.find(nil, includeDeleted)
+	if err != nil {
+		return Paged[models.Menu]{}, err
+	}
+	return pageOf(menus, oldestFirst("menu_id"), page, nil)
+}
+
+func (r *memoryMenuRepository) FindByID(ctx context.Context, menuId string) (models.Menu, error) {
+	return r.db.menus.get(menuId, false)
+}
+
+func (r *memoryMenuRepository) FindByIDWithDeleted(ctx context.Context, menuId string) (models.Menu, error) {
+	return r.db.menus.get(menuId, true)
+}
+
+func (r *memoryMenuRepository) Create(ctx context.Context, menu models.Menu) error {
+	return r.db.menus.insert(menu)
+}
+
+func (r *memoryMenuRepository) Update(ctx context.Context, menuId string, updateObj primitive.D, version int) error {
+	return r.db.menus.update(menuId, updateObj, version)
+}
+
+func (r *memoryMenuRepository) Delete(ctx context.Context, menuId string, d Deletion) error {
+	return r.db.menus.markDeleted(menuId, d)
+}
+
+func (r *memoryMenuRepository) Restore(ctx context.Context, menuId string) error {
+	return r.db.menus.unmarkDeleted(menuId)
+}
@@ -0,0 +1,416 @@
+package store
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OrderItemRepository interface {
+	// List returns one page of the order items that are not deleted, or of every order item with includeDeleted,
+	// oldest first.
+	List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.OrderItem], error)
+	// FindByID returns ErrNotFound for a deleted order item. FindByIDWithDeleted returns it.
+	FindByID(ctx context.Context, orderItemId string) (models.OrderItem, error)
+	FindByIDWithDeleted(ctx context.Context, orderItemId string) (models.OrderItem, error)
+	// ListByOrder returns the items of an order, leaving the deleted ones out unless includeDeleted is set.
+	ListByOrder(ctx context.Context, orderId string, includeDeleted bool) ([]models.OrderItem, error)
+	CreateMany(ctx context.Context, orderItems []models.OrderItem) error
+	// Update applies updateObj as a $set on the order item and increments its version. With a version other than 0 it
+	// returns ErrConflict unless the order item is still at that version. A missing or deleted order item is ErrNotFound.
+	Update(ctx context.Context, orderItemId string, updateObj primitive.D, version int) error
+	// Delete soft deletes the order item. It returns ErrNotFound when the item does not exist or is already deleted.
+	Delete(ctx context.Context, orderItemId string, d Deletion) error
+	Restore(ctx context.Context, orderItemId string) error
+	// DeleteByOrder soft deletes the items of an order that are not deleted yet, with the same stamp as the order.
+	DeleteByOrder(ctx context.Context, orderId string, d Deletion) error
+	// RestoreByOrder restores the items of an order that were deleted at deletedAt, which are the ones DeleteByOrder
+	// deleted together with the order. Items deleted on their own before stay deleted.
+	RestoreByOrder(ctx context.Context, orderId string, deletedAt time.Time) error
+	// ItemsByOrder returns the items of an order joined with their food and table, grouped into a single summary
+	// holding order_id, table_id, table_number, order_items, total_count and payment_due. Deleted items are left out.
+	ItemsByOrder(ctx context.Context, id string) ([]primitive.M, error)
+}
+
+type mongoOrderItemRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoOrderItemRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.OrderItem], error) {
+	return findPage[models.OrderItem](ctx, r.collection, liveFilter(bson.M{}, includeDeleted), oldestFirst("order_item_id"), page)
+}
+
+func (r *mongoOrderItemRepository) FindByID(ctx context.Context, orderItemId string) (models.OrderItem, error) {
+	return findByKey[models.OrderItem](ctx, r.collection, "order_item_id", orderItemId, false)
+}
+
+func (r *mongoOrderItemRepository) FindByIDWithDeleted(ctx context.Context, orderItemId string) (models.OrderItem, error) {
+	return findByKey[models.OrderItem](ctx, r.collection, "order_item_id", orderItemId, true)
+}
+
+func (r *mongoOrderItemRepository) ListByOrder(ctx context.Context, orderId string, includeDeleted bool) ([]models.OrderItem, error) {
+	return findAll[models.OrderItem](ctx, r.collection, liveFilter(bson.M{"order_id": orderId}, includeDeleted))
+}
+
+func (r *mongoOrderItemRepository) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+	orderItemsToBeInserted := []interface{}{}
+	for _, orderItem := range orderItems {
+		orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
+	}
+	_, err := r.collection.InsertMany(ctx, orderItemsToBeInserted)
+	return mongoError(err)
+}
+
+func (r *mongoOrderItemRepository) Update(ctx context.Context, orderItemId string, updateObj primitive.D, version int) error {
+	return updateVersioned(ctx, r.collection, "order_item_id", orderItemId, updateObj, version)
+}
+
+func (r *mongoOrderItemRepository) ItemsByOrder(ctx context.Context, id string) (orderItems []primitive.M, err error) {
+	// $match: The $match is like a filter in a search. In this case, were saying, "Hey MongoDB, find documents (which are like rows in SQL databases) where the order_id equals the id that we passed into the function
+	matchStage := bson.D{{Key: "$match", Value: liveFilter(bson.M{"order_id": id}, false)}}
+	//Its purpose is to join two collections in MongoDB, much like how a SQL JOIN works.
+	// In this line, you're trying to get more details about the food associated with each item in the order. The order items are stored in one collection, and the food details are stored in another collection. This stage connects the two.
+	// from: "food" This tells MongoDB that the additional information you need is in the food collection
+	// localField: "food_id" This is the field in the current collection (order items) that will be used to match the documents. Here, its food_id, which links each order item to a specific food item.
+	// foreignField: "food_id"  This is the field in the other collection (food) that MongoDB will use to find matching documents. The food_id field in the food collection needs to match the food_id in the order item
+	// as: "food"  This is the name of the new field that will hold the matched data from the food collection. After the lookup, each order item will have a new field called food, which contains details about the food item (like its name, price, etc.).
+	// Before lookupStage:
+
+	// You just have:
+
+	// [
+	//   { "order_id": "123", "food_id": "001", "quantity": 2 },
+	// 	  { "order_id": "123", "food_id": "002", "quantity": 1 }
+	// ]
+
+	// This only tells you the food_id and quantity, but not much else.
+
+	// After lookupStage:
+
+	// [
+	// {
+	// "order_id": "123",
+	// "food_id": "001",
+	// "quantity": 2,
+	// "food": {
+	// 	"name": "Pizza",
+	// 	"price": 10.0,
+	// 	"food_image": "pizza.jpg"
+	// }
+	// },
+	// {
+	// "order_id": "123",
+	// "food_id": "002",
+	// "quantity": 1,
+	// "food": {
+	// "name": "Burger",
+	// "price": 5.0,
+	// "food_image": "burger.jpg"
+	// }
+	// }
+	// ]
+
+	lookupStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: FoodCollection}, {Key: "localField", Value: "food_id"}, {Key: "foreignField", Value: "food_id"}, {Key: "as", Value: "food"}}}}
+	// $unwind takes an array from a document and splits it into separate documents for each item in that array.
+	// It helps make it easier to work with data that has arrays.
+	//  path
+
+	// What It Means: The path specifies which field you want to unwind. In this case, its "$food".
+	// How It Works: By saying path: "$food", you are telling MongoDB that you want to take the food array from each order document and create separate documents for each food item.
+
+	// Before unwindStage:
+
+	// {
+	// "order_id": 1,
+	// "customer_name": "Alice",
+	// "food_ids": ["f1", "f2"],
+	// "food_items": [
+	// { "food_id": "f1", "name": "Pizza" },
+	// { "food_id": "f2", "name": "Burger" }
+	// ]
+	// }
+
+	// After unwindStage:
+
+	// {
+	// "order_id": 1,
+	// "customer_name": "Alice",
+	// "food_ids": ["f1", "f2"],
+	// "food_items": { "food_id": "f1", "name": "Pizza" }
+	// }
+
+	// {
+	// "order_id": 1,
+	// "customer_name": "Alice",
+	// "food_ids": ["f1", "f2"],
+	// "food_items": { "food_id": "f2", "name": "Burger" }
+	// }
+
+	// The primary purpose of the $unwind stage in MongoDB is to deconstruct an array field from the input documents. This means that if you have a document containing an array, $unwind will create a new document for each element in that array, effectively "flattening" it
+
+	// Simply ANTI-ARRAY :- Benefit: This allows for easier manipulation and querying of individual array elements in MongoDB.
+
+	//  preserveNullAndEmptyArrays: true ensures that documents with null values or empty arrays for the field being unwound are not excluded from the results. Instead, they will appear in the output, but the unwound field will have a null value.
+
+	unwindStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$food"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	// Seek Mock Data Example if confused
+	// Purpose of the as Key:
+
+	// The as key in the $lookup stage specifies the name of the new field that will be created in the resulting documents. In your case, this is the "order" field.
+	// The documents from the order collection that match the join condition will be included in this new field as an array.
+
+	// Why the Food Name Is Not Included:
+
+	// The $lookup only brings in data from the order collection based on the matching order_id.
+	// It does not include fields from the food collection in the result of the lookup because that part is handled separately in your pipeline.
+
+	// We are concerned with key as it's value
+	lookupOrderStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: OrderCollection}, {Key: "localField", Value: "order_id"}, {Key: "foreignField", Value: "order_id"}, {Key: "as", Value: "order"}}}}
+	unwindOrderStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$order"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	lookupTableStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: TableCollection}, {Key: "localField", Value: "order.table_id"}, {Key: "foreignField", Value: "table_id"}, {Key: "as", Value: "table"}}}}
+	unwindTableStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$table"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
+
+	// The $project stage is crucial for shaping the final output of your aggregation pipeline. It allows you to control which fields are included, excluded, or renamed in the output documents, helping you create a cleaner and more relevant data structure for further processing or displaying in your application.
+
+	projectStage := bson.D{
+		{Key: "$project", Value: bson.D{
+			// This line excludes the id field from the output documents. Setting a field to 0 in the $project stage means it won't appear in the resulting documents.
+
+			// The amount charged for an item is the unit price stored on the order item, not the current food price,
+			// with the prices of its modifiers added.
+			{Key: "_id", Value: 0},
+			{Key: "amount", Value: bson.D{{Key: "$round", Value: bson.A{
+				bson.D{{Key: "$add", Value: bson.A{"$unit_price", bson.D{{Key: "$sum", Value: "$modifiers.price"}}}}},
+				2,
+			}}}},
+			{Key: "modifiers", Value: "$modifiers"},
+			{Key: "food_name", Value: "$food.name"},
+			{Key: "food_image", Value: "$food.food_image"},
+			{Key: "table_number", Value: "$table.table_number"},
+			{Key: "table_id", Value: "$table.table_id"},
+			{Key: "order_id", Value: "$order_id"},
+			{Key: "price", Value: "$food.price"},
+			{Key: "quantity", Value: 1},
+		}}}
+
+	// The aggregation groups the documents based on both the order_id and table_number. This means that if multiple items belong to the same order (i.e., they have the same order_id) and were placed at the same table (i.e., they have the same table_number), they will be grouped together in a single result.
+
+	groupStage := bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: bson.D{
+			{Key: "order_id", Value: "$order_id"},
+			{Key: "table_number", Value: "$table_number"},
+		}},
+		{Key: "table_id", Value: bson.D{{Key: "$first", Value: "$table_id"}}},
+		{Key: "order_id", Value: bson.D{{Key: "$first", Value: "$order_id"}}},
+		{Key: "table_number", Value: bson.D{{Key: "$first", Value: "$table_number"}}},
+		// quantity holds the variant, like S, M or L, not a count, so every order item counts once.
+		{Key: "total_count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		{Key: "order_items", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}},
+		{Key: "payment_due", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+	}}}
+
+	//  In this code, the projectStage2 is applying a projection on the results that were produced by the previous groupStage.
+
+	// The earlier stage typically comes early in the pipeline, before the data is grouped. It helps to reduce the amount of data that is processed by focusing only on the fields you actually need.
+
+	// This second projection stage is used to restructure the grouped results and prepare the final output
+
+	projectStage2 := bson.D{
+		{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "order_id", Value: 1},
+			{Key: "table_id", Value: 1},
+			{Key: "payment_due", Value: 1},
+			{Key: "table_number", Value: "$_id.table_number"},
+			{Key: "order_items", Value: 1},
+			{Key: "total_count", Value: 1},
+		}}}
+
+	//  the MongoDB aggregation pipeline is executed in sequence, with each stage processing the output of the previous one. This means the order of the stages is very important because each stage depends on the results from the stages before it.
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		matchStage,
+		lookupStage,
+		unwindStage,
+		lookupOrderStage,
+		unwindOrderStage,
+		lookupTableStage,
+		unwindTableStage,
+		projectStage,
+		groupStage,
+		projectStage2,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	orderItems = []primitive.M{}
+	if err = cursor.All(ctx, &orderItems); err != nil {
+		return nil, err
+	}
+
+	return orderItems, nil
+}
+
+func (r *mongoOrderItemRepository) Delete(ctx context.Context, orderItemId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"order_item_id": orderItemId}, d, true)
+}
+
+func (r *mongoOrderItemRepository) Restore(ctx context.Context, orderItemId string) error {
+	return restore(ctx, r.collection, bson.M{"order_item_id": orderItemId}, true)
+}
+
+func (r *mongoOrderItemRepository) DeleteByOrder(ctx context.Context, orderId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"order_id": orderId}, d, false)
+}
+
+func (r *mongoOrderItemRepository) RestoreByOrder(ctx context.Context, orderId string, deletedAt time.Time) error {
+	return restore(ctx, r.collection, bson.M{"order_id": orderId, "deleted_at": deletedAt}, false)
+}
+
+type memoryOrderItemRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryOrderItemRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.OrderItem], error) {
+	orderItems, err := r.db.orderItems.find(nil, includeDeleted)
+	if err != nil {
+		return Paged[models.OrderItem]{}, err
+	}
+	return pageOf(orderItems, oldestFirst("order_item_id"), page, nil)
+}
+
+func (r *memoryOrderItemRepository) FindByID(ctx context.Context, orderItemId string) (models.OrderItem, error) {
+	return r.db.orderItems.get(orderItemId, false)
+}
+
+func (r *memoryOrderItemRepository) FindByIDWithDeleted(ctx context.Context, orderItemId string) (models.OrderItem, error) {
+	return r.db.orderItems.get(orderItemId, true)
+}
+
+func (r *memoryOrderItemRepository) ListByOrder(ctx context.Context, orderId string, includeDeleted bool) ([]models.OrderItem, error) {
+	return r.db.orderItems.find(func(orderItem models.OrderItem) bool {
+		return orderItem.Order_ID == orderId
+	}, includeDeleted)
+}
+
+func (r *memoryOrderItemRepository) CreateMany(ctx context.Context, orderItems []models.OrderItem) error {
+	for _, orderItem := range orderItems {
+		if err := r.db.orderItems.insert(orderItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryOrderItemRepository) Update(ctx context.Context, orderItemId string, updateObj primitive.D, version int) error {
+	return r.db.orderItems.update(orderItemId, updateObj, version)
+}
+
+// ItemsByOrder builds the same summary as the Mongo aggregation pipeline by joining the collections in Go.
+func (r *memoryOrderItemRepository) ItemsByOrder(ctx context.Context, id string) ([]primitive.M, error) {
+	items, err := r.db.orderItems.find(func(orderItem models.OrderItem) bool {
+		return orderItem.Order_ID == id
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return []primitive.M{}, nil
+	}
+
+	var tableId, tableNumber interface{}
+	// Like $lookup, the joins do not care whether the joined documents are deleted.
+	if order, err := r.db.orders.get(id, true); err == nil && order.Table_ID != nil {
+		if table, err := r.db.tables.get(*order.Table_ID, true); err == nil {
+			tableId = table.Table_ID
+			tableNumber = table.Table_Number
+		}
+	}
+
+	orderItems := []primitive.M{}
+	paymentDue := 0.0
+	for _, orderItem := range items {
+		entry := primitive.M{
+			"order_id":     orderItem.Order_ID,
+			"table_id":     tableId,
+			"table_number": tableNumber,
+			"quantity":     orderItem.Quantity,
+			"modifiers":    orderItem.Modifiers,
+			"amount":       nil,
+		}
+		if orderItem.Unit_Price != nil {
+			amount := *orderItem.Unit_Price
+			for _, modifier := range orderItem.Modifiers {
+				if modifier.Price != nil {
+					amount += *modifier.Price
+				}
+			}
+			amount = math.Round(amount*100) / 100
+			entry["amount"] = amount
+			paymentDue += amount
+		}
+		if orderItem.Food_id != nil {
+			if food, err := r.db.foods.get(*orderItem.Food_id, true); err == nil {
+				entry["food_name"] = food.Name
+				entry["food_image"] = food.Food_image
+				entry["price"] = food.Price
+			}
+		}
+		orderItems = append(orderItems, entry)
+	}
+
+	return []primitive.M{{
+		"order_id":     id,
+		"table_id":     tableId,
+		"table_number": tableNumber,
+		"order_items":  orderItems,
+		"total_count":  len(orderItems),
+		"payment_due":  paymentDue,
+	}}, nil
+}
+
+func (r *memoryOrderItemRepository) Delete(ctx context.Context, orderItemId string, d Deletion) error {
+	return r.db.orderItems.markDeleted(orderItemId, d)
+}
+
+func (r *memoryOrderItemRepository) Restore(ctx context.Context, orderItemId string) error {
+	return r.db.orderItems.unmarkDeleted(orderItemId)
+}
+
+func (r *memoryOrderItemRepository) DeleteByOrder(ctx context.Context, orderId string, d Deletion) error {
+	items, err := r.db.orderItems.find(func(orderItem models.OrderItem) bool {
+		return orderItem.Order_ID == orderId
+	}, false)
+	if err != nil {
+		return err
+	}
+	for _, orderItem := range items {
+		if err := r.db.orderItems.markDeleted(orderItem.Order_Item_Id, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryOrderItemRepository) RestoreByOrder(ctx context.Context, orderId string, deletedAt time.Time) error {
+	items, err := r.db.orderItems.find(func(orderItem models.OrderItem) bool {
+		return orderItem.Order_ID == orderId && orderItem.Deleted_at != nil && orderItem.Deleted_at.Equal(deletedAt)
+	}, true)
+	if err != nil {
+		return err
+	}
+	for _, orderItem := range items {
+		if err := r.db.orderItems.unmarkDeleted(orderItem.Order_Item_Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
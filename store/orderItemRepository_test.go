@@ -0,0 +1,52 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+)
+
+func TestOrderSummariesAddModifiersToTheItems(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		name, menuId, tableId, number, guests := "Burger", "menu", "table", 1, 2
+		if err := s.Tables.Create(ctx, models.Table{Table_ID: tableId, Table_Number: &number, Number_of_guests: &guests, CreatedAt: now, Version: 1}); err != nil {
+			t.Fatalf("creating the table: %v", err)
+		}
+		if err := s.Orders.Create(ctx, models.Order{Order_ID: "order", Table_ID: &tableId, Order_Date: now, CreatedAt: now, Version: 1}); err != nil {
+			t.Fatalf("creating the order: %v", err)
+		}
+		food := models.Food{Food_id: "burger", Name: &name, Price: ptr(8.0), Menu_id: &menuId, Created_at: now, Version: 1}
+		if err := s.Foods.Create(ctx, food); err != nil {
+			t.Fatalf("creating the food: %v", err)
+		}
+		items := []models.OrderItem{
+			{Order_Item_Id: "salad", Quantity: ptr("M"), Unit_Price: ptr(8.0), Modifiers: []models.OrderItemModifier{
+				{Group: "side", Name: "salad", Price: ptr(1.25)},
+				{Group: "extras", Name: "extra cheese", Price: ptr(1.5)},
+			}},
+			{Order_Item_Id: "fries", Quantity: ptr("S"), Unit_Price: ptr(8.0), Modifiers: []models.OrderItemModifier{
+				{Group: "side", Name: "fries", Price: ptr(0.0)},
+			}},
+		}
+		for i := range items {
+			items[i].Order_ID, items[i].Food_id, items[i].CreatedAt, items[i].Version = "order", &food.Food_id, now, 1
+		}
+		if err := s.OrderItems.CreateMany(ctx, items); err != nil {
+			t.Fatalf("creating the items: %v", err)
+		}
+
+		summaries, err := s.OrderItems.ItemsByOrder(ctx, "order")
+		if err != nil || len(summaries) != 1 {
+			t.Fatalf("summing up the order returned %v, %v", summaries, err)
+		}
+		// The salad and the extra cheese add 2.75 to the first burger.
+		if due, ok := summaries[0]["payment_due"].(float64); !ok || due != 18.75 {
+			t.Fatalf("the order comes to %v, want 18.75", summaries[0]["payment_due"])
+		}
+	})
+}
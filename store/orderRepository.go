@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OrderRepository interface {
+	// List returns one page of the orders that are not deleted, or of every order with includeDeleted, oldest first.
+	List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Order], error)
+	// FindByID returns ErrNotFound for a deleted order. FindByIDWithDeleted returns it.
+	FindByID(ctx context.Context, orderId string) (models.Order, error)
+	FindByIDWithDeleted(ctx context.Context, orderId string) (models.Order, error)
+	// ListByTable returns the orders placed at a table that are not deleted.
+	ListByTable(ctx context.Context, tableId string) ([]models.Order, error)
+	Create(ctx context.Context, order models.Order) error
+	// Update applies updateObj as a $set on the order and increments its version. With a version other than 0 it
+	// returns ErrConflict unless the order is still at that version. A missing or deleted order is ErrNotFound.
+	Update(ctx context.Context, orderId string, updateObj primitive.D, version int) error
+	// Delete soft deletes the order. It returns ErrNotFound when the order does not exist or is already deleted.
+	Delete(ctx context.Context, orderId string, d Deletion) error
+	Restore(ctx context.Context, orderId string) error
+}
+
+type mongoOrderRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoOrderRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Order], error) {
+	return findPage[models.Order](ctx, r.collection, liveFilter(bson.M{}, includeDeleted), oldestFirst("order_id"), page)
+}
+
+func (r *mongoOrderRepository) FindByID(ctx context.Context, orderId string) (models.Order, error) {
+	return findByKey[models.Order](ctx, r.collection, "order_id", orderId, false)
+}
+
+func (r *mongoOrderRepository) FindByIDWithDeleted(ctx context.Context, orderId string) (models.Order, error) {
+	return findByKey[models.Order](ctx, r.collection, "order_id", orderId, true)
+}
+
+func (r *mongoOrderRepository) ListByTable(ctx context.Context, tableId string) ([]models.Order, error) {
+	return findAll[models.Order](ctx, r.collection, liveFilter(bson.M{"table_id": tableId}, false))
+}
+
+func (r *mongoOrderRepository) Create(ctx context.Context, order models.Order) error {
+	_, err := r.collection.InsertOne(ctx, order)
+	return mongoError(err)
+}
+
+func (r *mongoOrderRepository) Update(ctx context.Context, orderId string, updateObj primitive.D, version int) error {
+	return updateVersioned(ctx, r.collection, "order_id", orderId, updateObj, version)
+}
+
+func (r *mongoOrderRepository) Delete(ctx context.Context, orderId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"order_id": orderId}, d, true)
+}
+
+func (r *mongoOrderRepository) Restore(ctx context.Context, orderId string) error {
+	return restore(ctx, r.collection, bson.M{"order_id": orderId}, true)
+}
+
+type memoryOrderRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryOrderRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Order], error) {
+	orders, err := r.db.orders.find(nil, includeDeleted)
+	if err != nil {
+		return Paged[models.Order]{}, err
+	}
+	return pageOf(orders, oldestFirst("order_id"), page, nil)
+}
+
+func (r *memoryOrderRepository) FindByID(ctx context.Context, orderId string) (models.Order, error) {
+	return r.db.orders.get(orderId, false)
+}
+
+func (r *memoryOrderRepository) FindByIDWithDeleted(ctx context.Context, orderId string) (models.Order, error) {
+	return r.db.orders.get(orderId, true)
+}
+
+func (r *memoryOrderRepository) ListByTable(ctx context.Context, tableId string) ([]models.Order, error) {
+	return r.db.orders.find(func(order models.Order) bool {
+		return order.Table_ID != nil && *order.Table_ID == tableId
+	}, false)
+}
+
+func (r *memoryOrderRepository) Create(ctx context.Context, order models.Order) error {
+	return r.db.orders.insert(order)
+}
+
+func (r *memoryOrderRepository) Update(ctx context.Context, orderId string, updateObj primitive.D, version int) error {
+	return r.db.orders.update(orderId, updateObj, version)
+}
+
+func (r *memoryOrderRepository) Delete(ctx context.Context, orderId string, d Deletion) error {
+	return r.db.orders.markDeleted(orderId, d)
+}
+
+func (r *memoryOrderRepository) Restore(ctx context.Context, orderId string) error {
+	return r.db.orders.unmarkDeleted(orderId)
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not handed out by a listing in the same order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page asks a listing for the Limit documents that follow Cursor. An empty Cursor starts at the beginning.
+//
+// A cursor holds the sort values of the last document of the previous page, not an offset, so documents created or
+// deleted while a client scrolls neither repeat nor get skipped.
+type Page struct {
+	Cursor string
+	Limit  int
+}
+
+// Paged is one page of a listing. Next_cursor continues after its last item and is empty on the last page.
+// Total_count counts every match of the listing, not just the page.
+type Paged[T any] struct {
+	Items       []T
+	Next_cursor string
+	Total_count int
+}
+
+// Every listing is sorted by a unique field last, so no two documents are equal in its order and a cursor always
+// points between two of them.
+
+// oldestFirst is the order of most listings: by creation, the unique key breaking ties.
+func oldestFirst(key string) bson.D {
+	return bson.D{{Key: "created_at", Value: 1}, {Key: key, Value: 1}}
+}
+
+// newestFirst is the order of the logs, whose entries are stamped with at.
+func newestFirst(key string) bson.D {
+	return bson.D{{Key: "at", Value: -1}, {Key: key, Value: -1}}
+}
+
+type pageCursor struct {
+	Order  string `bson:"o"`
+	Values bson.A `bson:"v"`
+}
+
+// orderName spells out an order, so that a cursor of one order is refused by a listing in another.
+func orderName(order bson.D) string {
+	keys := make([]string, len(order))
+	for i, key := range order {
+		keys[i] = fmt.Sprintf("%s:%v", key.Key, key.Value)
+	}
+	return strings.Join(keys, ",")
+}
+
+func encodeCursor(order bson.D, values bson.A) (string, error) {
+	data, err := bson.Marshal(pageCursor{Order: orderName(order), Values: values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort values held by cursor, or nil for an empty one.
+func decodeCursor(cursor string, order bson.D) (bson.A, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var decoded pageCursor
+	if err := bson.Unmarshal(data, &decoded); err != nil || decoded.Order != orderName(order) || len(decoded.Values) != len(order) {
+		return nil, ErrInvalidCursor
+	}
+	return decoded.Values, nil
+}
+
+// afterCursor matches the documents that come after values in order.
+//
+// Null and missing fields sort below every value, but $gt and $lt only compare values of the same type, so a null
+// sort value needs clauses of its own: after null come all values in ascending order and nothing in descending
+// order, and in descending order null comes after every value.
+func afterCursor(order bson.D, values bson.A) bson.M {
+	or := bson.A{}
+	for i, key := range order {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[order[j].Key] = values[j]
+		}
+		switch {
+		case values[i] == nil && key.Value == -1:
+			continue
+		case values[i] == nil:
+			clause[key.Key] = bson.M{"$ne": nil}
+		case key.Value == -1:
+			clause["$or"] = bson.A{bson.M{key.Key: bson.M{"$lt": values[i]}}, bson.M{key.Key: nil}}
+		default:
+			clause[key.Key] = bson.M{"$gt": values[i]}
+		}
+		or = append(or, clause)
+	}
+	if len(or) == 0 {
+		return bson.M{"$expr": false}
+	}
+	return bson.M{"$or": or}
+}
+
+// aggregatePage sorts the documents left by pipeline in order and cuts page out of them. The total and the page are
+// computed by one $facet, and neither branch holds more than a page, whatever the number of matches.
+func aggregatePage[T any](ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline, order bson.D, page Page) (Paged[T], error) {
+	paged := Paged[T]{Items: []T{}}
+	values, err := decodeCursor(page.Cursor, order)
+	if err != nil {
+		return paged, err
+	}
+
+	// One more than the page is read to tell whether there is a next page.
+	items := bson.A{}
+	if values != nil {
+		items = append(items, bson.D{{Key: "$match", Value: afterCursor(order, values)}})
+	}
+	items = append(items, bson.D{{Key: "$limit", Value: page.Limit + 1}})
+	pipeline = append(slices.Clip(pipeline),
+		bson.D{{Key: "$sort", Value: order}},
+		bson.D{{Key: "$facet", Value: bson.D{
+			{Key: "items", Value: items},
+			{Key: "total_count", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+		}}},
+	)
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return paged, err
+	}
+	var result []struct {
+		Items       []bson.Raw `bson:"items"`
+		Total_count []struct {
+			Count int `bson:"count"`
+		} `bson:"total_count"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return paged, err
+	}
+	// $count produces nothing at all when nothing matched.
+	if len(result) == 0 || len(result[0].Total_count) == 0 {
+		return paged, nil
+	}
+	paged.Total_count = result[0].Total_count[0].Count
+
+	docs := result[0].Items
+	if len(docs) > page.Limit {
+		docs = docs[:page.Limit]
+		last := bson.A{}
+		for _, key := range order {
+			var value interface{}
+			if raw, err := docs[len(docs)-1].LookupErr(key.Key); err == nil {
+				if err := raw.Unmarshal(&value); err != nil {
+					return paged, err
+				}
+			}
+			last = append(last, value)
+		}
+		if paged.Next_cursor, err = encodeCursor(order, last); err != nil {
+			return paged, err
+		}
+	}
+	for _, doc := range docs {
+		var item T
+		if err := bson.Unmarshal(doc, &item); err != nil {
+			return paged, err
+		}
+		paged.Items = append(paged.Items, item)
+	}
+	return paged, nil
+}
+
+// findPage is aggregatePage for a plain filter.
+func findPage[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, order bson.D, page Page) (Paged[T], error) {
+	return aggregatePage[T](ctx, collection, mongo.Pipeline{{{Key: "$match", Value: filter}}}, order, page)
+}
+
+// pageOf does what aggregatePage does for documents held in memory. computed adds fields that are sorted on but not
+// stored, like the text score, and may be nil.
+func pageOf[T any](docs []T, order bson.D, page Page, computed func(T) bson.M) (Paged[T], error) {
+	paged := Paged[T]{Items: []T{}, Total_count: len(docs)}
+	after, err := decodeCursor(page.Cursor, order)
+	if err != nil {
+		return paged, err
+	}
+
+	type keyed struct {
+		item T
+		key  bson.A
+	}
+	sorted := make([]keyed, len(docs))
+	for i, item := range docs {
+		doc, err := toDocument(item)
+		if err != nil {
+			return paged, err
+		}
+		if computed != nil {
+			doc = withFields(doc, computed(item))
+		}
+		// The sort values go through a cursor and back, so that they compare like the values of a decoded cursor.
+		values := bson.A{}
+		for _, key := range order {
+			values = append(values, doc[key.Key])
+		}
+		encoded, err := encodeCursor(order, values)
+		if err != nil {
+			return paged, err
+		}
+		if values, err = decodeCursor(encoded, order); err != nil {
+			return paged, err
+		}
+		sorted[i] = keyed{item: item, key: values}
+	}
+	slices.SortStableFunc(sorted, func(a, b keyed) int {
+		return compareKeys(order, a.key, b.key)
+	})
+
+	for _, doc := range sorted {
+		if after != nil && compareKeys(order, doc.key, after) <= 0 {
+			continue
+		}
+		if len(paged.Items) == page.Limit {
+			paged.Next_cursor, err = encodeCursor(order, after)
+			return paged, err
+		}
+		paged.Items = append(paged.Items, doc.item)
+		after = doc.key
+	}
+	return paged, nil
+}
+
+func compareKeys(order bson.D, a bson.A, b bson.A) int {
+	for i, key := range order {
+		c := compareValues(a[i], b[i])
+		if key.Value == -1 {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues orders values of different types the way MongoDB does: null, then numbers, then strings, then
+// dates.
+func compareValues(a interface{}, b interface{}) int {
+	rank := func(v interface{}) (int, float64, string) {
+		switch v := v.(type) {
+		case nil:
+			return 0, 0, ""
+		case int32:
+			return 1, float64(v), ""
+		case int64:
+			return 1, float64(v), ""
+		case float64:
+			return 1, v, ""
+		case string:
+			return 2, 0, v
+		case primitive.DateTime:
+			return 3, float64(v), ""
+		default:
+			return 4, 0, fmt.Sprint(v)
+		}
+	}
+	rankA, numberA, textA := rank(a)
+	rankB, numberB, textB := rank(b)
+	return cmp.Or(cmp.Compare(rankA, rankB), cmp.Compare(numberA, numberB), strings.Compare(textA, textB))
+}
@@ -0,0 +1,75 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+)
+
+// pageThroughFoods lists every food matching filter, limit at a time, and returns their food_ids in listing order.
+func pageThroughFoods(t *testing.T, s *store.Store, filter store.FoodFilter, limit int) []string {
+	t.Helper()
+	var ids []string
+	page := store.Page{Limit: limit}
+	for pages := 0; pages < 100; pages++ {
+		paged, err := s.Foods.List(context.Background(), filter, page)
+		if err != nil {
+			t.Fatalf("listing the foods: %v", err)
+		}
+		for _, food := range paged.Items {
+			ids = append(ids, food.Food_id)
+		}
+		if paged.Next_cursor == "" {
+			return ids
+		}
+		page.Cursor = paged.Next_cursor
+	}
+	t.Fatalf("the listing did not end after 100 pages")
+	return nil
+}
+
+func TestPagesContinueAcrossNullSortValues(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		menuId := "menu"
+		created := time.Now().UTC().Truncate(time.Millisecond)
+		// Foods priced only by their variants were once stored without a price.
+		prices := []*float64{nil, ptr(4.0), nil, ptr(2.0), ptr(4.0), nil, ptr(1.0)}
+		for i, price := range prices {
+			name := fmt.Sprintf("food %d", i)
+			food := models.Food{
+				Name:       &name,
+				Price:      price,
+				Food_id:    fmt.Sprintf("food%d", i),
+				Menu_id:    &menuId,
+				Created_at: created.Add(time.Duration(i) * time.Millisecond),
+				Version:    1,
+			}
+			if err := s.Foods.Create(ctx, food); err != nil {
+				t.Fatalf("creating %s: %v", food.Food_id, err)
+			}
+		}
+
+		for _, descending := range []bool{false, true} {
+			filter := store.FoodFilter{Sort: "price", Descending: descending}
+			all := pageThroughFoods(t, s, filter, len(prices))
+			for _, limit := range []int{1, 2, 3} {
+				paged := pageThroughFoods(t, s, filter, limit)
+				if fmt.Sprint(paged) != fmt.Sprint(all) {
+					t.Errorf("descending %v in pages of %d listed %v, in one page %v", descending, limit, paged, all)
+				}
+			}
+			if len(all) != len(prices) {
+				t.Errorf("descending %v listed %d of %d foods", descending, len(all), len(prices))
+			}
+		}
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RevocationRepository is the denylist the Authentication middleware checks on every request.
+type RevocationRepository interface {
+	Create(ctx context.Context, revocation models.Revocation) error
+	// IsRevoked reports whether the token with id jti, issued to userId at issuedAt, is covered by a revocation that
+	// has not expired. A revocation of the user covers the tokens issued strictly before it.
+	IsRevoked(ctx context.Context, jti string, userId string, issuedAt time.Time) (bool, error)
+}
+
+type mongoRevocationRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoRevocationRepository) Create(ctx context.Context, revocation models.Revocation) error {
+	_, err := r.collection.InsertOne(ctx, revocation)
+	return mongoError(err)
+}
+
+// The TTL index on expires_at removes expired revocations only about once a minute, so the query checks the
+// expiry itself.
+func (r *mongoRevocationRepository) IsRevoked(ctx context.Context, jti string, userId string, issuedAt time.Time) (bool, error) {
+	covers := bson.A{bson.M{"user_id": userId, "before": bson.M{"$gt": issuedAt}}}
+	if jti != "" {
+		covers = append(covers, bson.M{"jti": jti})
+	}
+	count, err := r.collection.CountDocuments(ctx, bson.M{
+		"$or":        covers,
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	return count > 0, err
+}
+
+type memoryRevocationRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryRevocationRepository) Create(ctx context.Context, revocation models.Revocation) error {
+	return r.db.revocations.insert(revocation)
+}
+
+func (r *memoryRevocationRepository) IsRevoked(ctx context.Context, jti string, userId string, issuedAt time.Time) (bool, error) {
+	now := time.Now()
+	revocations, err := r.db.revocations.find(func(revocation models.Revocation) bool {
+		if !revocation.Expires_at.After(now) {
+			return false
+		}
+		if jti != "" && revocation.Jti != nil && *revocation.Jti == jti {
+			return true
+		}
+		return revocation.User_id != nil && *revocation.User_id == userId &&
+			revocation.Before != nil && issuedAt.Before(*revocation.Before)
+	}, false)
+	return len(revocations) > 0, err
+}
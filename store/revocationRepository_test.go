@@ -0,0 +1,50 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+)
+
+func TestRevocationsCoverTokensIssuedBeforeThem(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		userId := "user"
+		jti := "logged-out"
+		before := time.Now().UTC().Truncate(time.Millisecond)
+		expires := before.Add(time.Hour)
+		for _, revocation := range []models.Revocation{
+			{Revocation_id: "sessions", User_id: &userId, Before: &before, Expires_at: expires},
+			{Revocation_id: "logout", Jti: &jti, Expires_at: expires},
+		} {
+			if err := s.Revocations.Create(ctx, revocation); err != nil {
+				t.Fatalf("creating the revocation %s: %v", revocation.Revocation_id, err)
+			}
+		}
+
+		for _, c := range []struct {
+			name     string
+			jti      string
+			userId   string
+			issuedAt time.Time
+			revoked  bool
+		}{
+			{"issued earlier", "a", userId, before.Add(-time.Millisecond), true},
+			{"issued at the revocation", "b", userId, before, false},
+			{"issued later", "c", userId, before.Add(time.Millisecond), false},
+			{"of another user", "d", "other", before.Add(-time.Millisecond), false},
+			{"logged out", jti, "other", before.Add(time.Hour), true},
+		} {
+			revoked, err := s.Revocations.IsRevoked(ctx, c.jti, c.userId, c.issuedAt)
+			if err != nil {
+				t.Fatalf("checking a token %s: %v", c.name, err)
+			}
+			if revoked != c.revoked {
+				t.Errorf("a token %s is revoked %v, want %v", c.name, revoked, c.revoked)
+			}
+		}
+	})
+}
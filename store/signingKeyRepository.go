@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SigningKeyRepository keeps the token signing keys every instance of the server shares.
+type SigningKeyRepository interface {
+	// List returns the keys that have not expired, oldest first.
+	List(ctx context.Context) ([]models.SigningKey, error)
+	Create(ctx context.Context, key models.SigningKey) error
+}
+
+type mongoSigningKeyRepository struct {
+	collection *mongo.Collection
+}
+
+// The TTL index on expires_at removes expired keys only about once a minute, so the query checks the expiry itself.
+func (r *mongoSigningKeyRepository) List(ctx context.Context) ([]models.SigningKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"expires_at": bson.M{"$gt": time.Now()}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	keys := []models.SigningKey{}
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *mongoSigningKeyRepository) Create(ctx context.Context, key models.SigningKey) error {
+	_, err := r.collection.InsertOne(ctx, key)
+	return mongoError(err)
+}
+
+type memorySigningKeyRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memorySigningKeyRepository) List(ctx context.Context) ([]models.SigningKey, error) {
+	now := time.Now()
+	return r.db.signingKeys.find(func(key models.SigningKey) bool {
+		return key.Expires_at.After(now)
+	}, false)
+}
+
+func (r *memorySigningKeyRepository) Create(ctx context.Context, key models.SigningKey) error {
+	return r.db.signingKeys.insert(key)
+}
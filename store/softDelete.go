@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Documents are never removed. Deleting one stamps it with deleted_at and deleted_by, restoring it removes the stamp
+// again, and reads leave stamped documents out unless the caller asks for them. Unique indexes still cover deleted
+// documents, so a deleted table keeps its number and a deleted user keeps their email until they are restored.
+
+// Deletion is the stamp a soft delete leaves on a document.
+type Deletion struct {
+	At time.Time
+	By string // user_id of whoever deleted the document
+}
+
+// liveFilter narrows filter to documents that have not been deleted unless includeDeleted is set. Matching
+// deleted_at against null also matches documents that never had the field.
+func liveFilter(filter bson.M, includeDeleted bool) bson.M {
+	if !includeDeleted {
+		filter["deleted_at"] = nil
+	}
+	return filter
+}
+
+func findByKey[T any](ctx context.Context, collection *mongo.Collection, key string, id string, includeDeleted bool) (T, error) {
+	var v T
+	err := collection.FindOne(ctx, liveFilter(bson.M{key: id}, includeDeleted)).Decode(&v)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return v, ErrNotFound
+	}
+	return v, err
+}
+
+func findAll[T any](ctx context.Context, collection *mongo.Collection, filter bson.M) ([]T, error) {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	all := []T{}
+	if err = cursor.All(ctx, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// softDelete stamps every live document matching filter and increments its version. With one set, it fails with
+// ErrNotFound when nothing was stamped.
+func softDelete(ctx context.Context, collection *mongo.Collection, filter bson.M, d Deletion, one bool) error {
+	update := bson.M{
+		"$set": bson.M{"deleted_at": d.At, "deleted_by": d.By},
+		"$inc": bson.M{"version": 1},
+	}
+	var result *mongo.UpdateResult
+	var err error
+	if one {
+		result, err = collection.UpdateOne(ctx, liveFilter(filter, false), update)
+	} else {
+		result, err = collection.UpdateMany(ctx, liveFilter(filter, false), update)
+	}
+	if err != nil {
+		return err
+	}
+	if one && result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// restore removes the stamp from every deleted document matching filter and increments its version. With one set,
+// it fails with ErrNotFound when no document matches filter at all. Restoring a document that is not deleted
+// changes nothing.
+func restore(ctx context.Context, collection *mongo.Collection, filter bson.M, one bool) error {
+	deleted := bson.M{"deleted_at": bson.M{"$ne": nil}}
+	for k, v := range filter {
+		deleted[k] = v
+	}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+	var result *mongo.UpdateResult
+	var err error
+	if one {
+		result, err = collection.UpdateOne(ctx, deleted, update)
+	} else {
+		result, err = collection.UpdateMany(ctx, deleted, update)
+	}
+	if err != nil {
+		return mongoError(err)
+	}
+	if one && result.MatchedCount == 0 {
+		count, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
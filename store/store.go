@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// The store package sits between the gin handlers and the database. Every controller talks to one of the repository
+// interfaces below instead of a *mongo.Collection, so the same handlers can run against MongoDB in production and
+// against the in-memory implementation in tests and demos.
+
+// ErrNotFound is returned by every repository when the requested document does not exist.
+var ErrNotFound = errors.New("document not found")
+
+// ErrDuplicate is returned when a document with the same custom identifier already exists.
+var ErrDuplicate = errors.New("document already exists")
+
+// ErrConflict is returned by Update when the document is no longer at the version the caller expected, because
+// someone else changed it in the meantime.
+var ErrConflict = errors.New("document was changed by someone else")
+
+// Store groups one repository per collection.
+type Store struct {
+	Foods          FoodRepository
+	Menus          MenuRepository
+	Tables         TableRepository
+	Orders         OrderRepository
+	OrderItems     OrderItemRepository
+	Invoices       InvoiceRepository
+	Users          UserRepository
+	Audit          AuditRepository
+	Revocations    RevocationRepository
+	ApiKeys        ApiKeyRepository
+	UserTokens     UserTokenRepository
+	LoginAttempts  LoginAttemptRepository
+	LoginThrottles LoginThrottleRepository
+	SigningKeys    SigningKeyRepository
+	Claims         ClaimRepository
+
+	client *mongo.Client   // nil for the in-memory store
+	memory *memoryDatabase // nil for the Mongo store
+}
+
+// Ping reports whether the backing database is reachable. The in-memory store is always reachable.
+func (s *Store) Ping(ctx context.Context) error {
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Ping(ctx, readpref.Primary())
+}
+
+// Transaction runs fn so that either all of its writes are applied or none are. fn must do every read and write
+// through tx and ctx, which carry the transaction; work done through s directly is not part of it.
+//
+// Against MongoDB this is a session transaction, which needs a replica set or a sharded cluster. The driver retries
+// fn on transient transaction errors, so fn must not have side effects outside the store. The in-memory store runs
+// fn on a copy of the data that replaces the original when fn returns nil, and blocks other writers until then.
+func (s *Store) Transaction(ctx context.Context, fn func(ctx context.Context, tx *Store) error) error {
+	if s.memory != nil {
+		return s.memory.transaction(func(db *memoryDatabase) error {
+			return fn(ctx, newMemoryStore(db))
+		})
+	}
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc, s)
+	})
+	return err
+}
+
+// mongoError translates driver errors into the errors of this package, so callers never import the driver to tell
+// a unique index violation apart from other failures.
+func mongoError(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %v", ErrDuplicate, err)
+	}
+	return err
+}
+
+// updateVersioned applies updateObj as a $set on the live document whose key is id and increments its version in the
+// same write. With a version other than 0 the document must still be at that version, otherwise nothing is written
+// and ErrConflict is returned. A missing or deleted document is ErrNotFound; nothing is ever upserted.
+func updateVersioned(ctx context.Context, collection *mongo.Collection, key string, id string, updateObj primitive.D, version int) error {
+	filter := liveFilter(bson.M{key: id}, false)
+	if version != 0 {
+		filter["version"] = version
+	}
+	result, err := collection.UpdateOne(ctx, filter, bson.D{
+		{Key: "$set", Value: updateObj},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	})
+	if err != nil {
+		return mongoError(err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	if version == 0 {
+		return ErrNotFound
+	}
+	// The filter did not match: find out whether the document is gone or only at another version.
+	count, err := collection.CountDocuments(ctx, liveFilter(bson.M{key: id}, false))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return ErrConflict
+}
+
+// Collection names used by the Mongo implementation. They are exported for the migrations package.
+const (
+	FoodCollection      = "food"
+	MenuCollection      = "menu"
+	TableCollection     = "table"
+	OrderCollection     = "Order"
+	OrderItemCollection = "orderItem"
+	InvoiceCollection   = "invoice"
+	UserCollection      = "user"
+	AuditCollection     = "audit"
+	// EventCollection carries domain events between processes. The store does not read it; the events package does.
+	EventCollection         = "events"
+	RevocationCollection    = "revocations"
+	ApiKeyCollection        = "api_keys"
+	UserTokenCollection     = "user_tokens"
+	LoginAttemptCollection  = "login_attempts"
+	LoginThrottleCollection = "login_throttles"
+	SigningKeyCollection    = "signing_keys"
+	ClaimCollection         = "claims"
+)
+
+// NewMongoStore builds a Store whose repositories read and write the collections of db.
+func NewMongoStore(db *mongo.Database) *Store {
+	return &Store{
+		Foods:          &mongoFoodRepository{collection: db.Collection(FoodCollection)},
+		Menus:          &mongoMenuRepository{collection: db.Collection(MenuCollection)},
+		Tables:         &mongoTableRepository{collection: db.Collection(TableCollection)},
+		Orders:         &mongoOrderRepository{collection: db.Collection(OrderCollection)},
+		OrderItems:     &mongoOrderItemRepository{collection: db.Collection(OrderItemCollection)},
+		Invoices:       &mongoInvoiceRepository{collection: db.Collection(InvoiceCollection)},
+		Users:          &mongoUserRepository{collection: db.Collection(UserCollection)},
+		Audit:          &mongoAuditRepository{collection: db.Collection(AuditCollection)},
+		Revocations:    &mongoRevocationRepository{collection: db.Collection(RevocationCollection)},
+		ApiKeys:        &mongoApiKeyRepository{collection: db.Collection(ApiKeyCollection)},
+		UserTokens:     &mongoUserTokenRepository{collection: db.Collection(UserTokenCollection)},
+		LoginAttempts:  &mongoLoginAttemptRepository{collection: db.Collection(LoginAttemptCollection)},
+		LoginThrottles: &mongoLoginThrottleRepository{collection: db.Collection(LoginThrottleCollection)},
+		SigningKeys:    &mongoSigningKeyRepository{collection: db.Collection(SigningKeyCollection)},
+		Claims:         &mongoClaimRepository{collection: db.Collection(ClaimCollection)},
+		client:         db.Client(),
+	}
+}
+
+// NewMemoryStore builds a Store that keeps every document in process memory. Nothing is persisted.
+func NewMemoryStore() *Store {
+	return newMemoryStore(newMemoryDatabase())
+}
+
+func newMemoryStore(db *memoryDatabase) *Store {
+	return &Store{
+		Foods:          &memoryFoodRepository{db: db},
+		Menus:          &memoryMenuRepository{db: db},
+		Tables:         &memoryTableRepository{db: db},
+		Orders:         &memoryOrderRepository{db: db},
+		OrderItems:     &memoryOrderItemRepository{db: db},
+		Invoices:       &memoryInvoiceRepository{db: db},
+		Users:          &memoryUserRepository{db: db},
+		Audit:          &memoryAuditRepository{db: db},
+		Revocations:    &memoryRevocationRepository{db: db},
+		ApiKeys:        &memoryApiKeyRepository{db: db},
+		UserTokens:     &memoryUserTokenRepository{db: db},
+		LoginAttempts:  &memoryLoginAttemptRepository{db: db},
+		LoginThrottles: &memoryLoginThrottleRepository{db: db},
+		SigningKeys:    &memorySigningKeyRepository{db: db},
+		Claims:         &memoryClaimRepository{db: db},
+		memory:         db,
+	}
+}
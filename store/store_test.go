@@ -0,0 +1,70 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"restaurant-management-system/migrations"
+	"restaurant-management-system/store"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The tests hold every implementation of a repository to the same contract. They always run against the in-memory
+// store, and against MongoDB as well when TEST_MONGO_URI names a server. Each Mongo test gets a database of its own,
+// migrated to the current schema and dropped again afterwards. Transactions need a replica set.
+
+// eachStore runs test once per implementation of the store.
+func eachStore(t *testing.T, test func(t *testing.T, s *store.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, store.NewMemoryStore())
+	})
+	t.Run("mongo", func(t *testing.T) {
+		test(t, mongoStore(t))
+	})
+}
+
+func mongoStore(t *testing.T) *store.Store {
+	t.Helper()
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connecting to %s: %v", uri, err)
+	}
+	db := client.Database(fmt.Sprintf("restaurant_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := db.Drop(ctx); err != nil {
+			t.Errorf("dropping %s: %v", db.Name(), err)
+		}
+		client.Disconnect(ctx)
+	})
+	if err := migrations.Run(ctx, db); err != nil {
+		t.Fatalf("migrating %s: %v", db.Name(), err)
+	}
+	return store.NewMongoStore(db)
+}
+
+func TestMissingDocumentsAreNotFound(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		if _, err := s.Users.FindByEmail(ctx, "nobody@example.com"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("finding an unknown user returned %v, want ErrNotFound", err)
+		}
+		if _, err := s.Tables.FindByID(ctx, "missing"); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("finding an unknown table returned %v, want ErrNotFound", err)
+		}
+	})
+}
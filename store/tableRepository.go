@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TableRepository interface {
+	// List returns one page of the tables that are not deleted, or of every table with includeDeleted, oldest first.
+	List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Table], error)
+	// FindByID returns ErrNotFound for a deleted table. FindByIDWithDeleted returns it.
+	FindByID(ctx context.Context, tableId string) (models.Table, error)
+	FindByIDWithDeleted(ctx context.Context, tableId string) (models.Table, error)
+	Create(ctx context.Context, table models.Table) error
+	// Update applies updateObj as a $set on the table and increments its version. With a version other than 0 it
+	// returns ErrConflict unless the table is still at that version. A missing or deleted table is ErrNotFound.
+	Update(ctx context.Context, tableId string, updateObj primitive.D, version int) error
+	// Delete soft deletes the table. It returns ErrNotFound when the table does not exist or is already deleted.
+	Delete(ctx context.Context, tableId string, d Deletion) error
+	Restore(ctx context.Context, tableId string) error
+}
+
+type mongoTableRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoTableRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Table], error) {
+	return findPage[models.Table](ctx, r.collection, liveFilter(bson.M{}, includeDeleted), oldestFirst("table_id"), page)
+}
+
+func (r *mongoTableRepository) FindByID(ctx context.Context, tableId string) (models.Table, error) {
+	return findByKey[models.Table](ctx, r.collection, "table_id", tableId, false)
+}
+
+func (r *mongoTableRepository) FindByIDWithDeleted(ctx context.Context, tableId string) (models.Table, error) {
+	return findByKey[models.Table](ctx, r.collection, "table_id", tableId, true)
+}
+
+func (r *mongoTableRepository) Create(ctx context.Context, table models.Table) error {
+	_, err := r.collection.InsertOne(ctx, table)
+	return mongoError(err)
+}
+
+func (r *mongoTableRepository) Update(ctx context.Context, tableId string, updateObj primitive.D, version int) error {
+	return updateVersioned(ctx, r.collection, "table_id", tableId, updateObj, version)
+}
+
+func (r *mongoTableRepository) Delete(ctx context.Context, tableId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"table_id": tableId}, d, true)
+}
+
+func (r *mongoTableRepository) Restore(ctx context.Context, tableId string) error {
+	return restore(ctx, r.collection, bson.M{"table_id": tableId}, true)
+}
+
+type memoryTableRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryTableRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.Table], error) {
+	tables, err := r.db.tables.find(nil, includeDeleted)
+	if err != nil {
+		return Paged[models.Table]{}, err
+	}
+	return pageOf(tables, oldestFirst("table_id"), page, nil)
+}
+
+func (r *memoryTableRepository) FindByID(ctx context.Context, tableId string) (models.Table, error) {
+	return r.db.tables.get(tableId, false)
+}
+
+func (r *memoryTableRepository) FindByIDWithDeleted(ctx context.Context, tableId string) (models.Table, error) {
+	return r.db.tables.get(tableId, true)
+}
+
+func (r *memoryTableRepository) Create(ctx context.Context, table models.Table) error {
+	return r.db.tables.insert(table)
+}
+
+func (r *memoryTableRepository) Update(ctx context.Context, tableId string, updateObj primitive.D, version int) error {
+	return r.db.tables.update(tableId, updateObj, version)
+}
+
+func (r *memoryTableRepository) Delete(ctx context.Context, tableId string, d Deletion) error {
+	return r.db.tables.markDeleted(tableId, d)
+}
+
+func (r *memoryTableRepository) Restore(ctx context.Context, tableId string) error {
+	return r.db.tables.unmarkDeleted(tableId)
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type UserRepository interface {
+	// List returns one page of the users that are not deleted, or of every user with includeDeleted, oldest first.
+	List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.User], error)
+	// FindByID and FindByEmail return ErrNotFound for a deleted user, so a deleted user can no longer log in.
+	// FindByIDWithDeleted returns them.
+	FindByID(ctx context.Context, userId string) (models.User, error)
+	FindByIDWithDeleted(ctx context.Context, userId string) (models.User, error)
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	// CountByEmail and CountByPhone count deleted users too, because the unique indexes cover them.
+	CountByEmail(ctx context.Context, email string) (int64, error)
+	CountByPhone(ctx context.Context, phone string) (int64, error)
+	// CountActiveByRole counts the users of role who are neither deleted nor deactivated.
+	CountActiveByRole(ctx context.Context, role string) (int64, error)
+	Create(ctx context.Context, user models.User) error
+	// Update applies updateObj as a $set on the user and increments its version. With a version other than 0 it
+	// returns ErrConflict unless the user is still at that version. A missing or deleted user is ErrNotFound.
+	Update(ctx context.Context, userId string, updateObj primitive.D, version int) error
+	// Delete soft deletes the user. It returns ErrNotFound when the user does not exist or is already deleted.
+	Delete(ctx context.Context, userId string, d Deletion) error
+	Restore(ctx context.Context, userId string) error
+}
+
+type mongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUserRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.User], error) {
+	return findPage[models.User](ctx, r.collection, liveFilter(bson.M{}, includeDeleted), oldestFirst("user_id"), page)
+}
+
+func (r *mongoUserRepository) FindByID(ctx context.Context, userId string) (models.User, error) {
+	return findByKey[models.User](ctx, r.collection, "user_id", userId, false)
+}
+
+func (r *mongoUserRepository) FindByIDWithDeleted(ctx context.Context, userId string) (models.User, error) {
+	return findByKey[models.User](ctx, r.collection, "user_id", userId, true)
+}
+
+func (r *mongoUserRepository) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	return findByKey[models.User](ctx, r.collection, "email", email, false)
+}
+
+func (r *mongoUserRepository) CountByEmail(ctx context.Context, email string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"email": email})
+}
+
+func (r *mongoUserRepository) CountByPhone(ctx context.Context, phone string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"phone": phone})
+}
+
+func (r *mongoUserRepository) CountActiveByRole(ctx context.Context, role string) (int64, error) {
+	return r.collection.CountDocuments(ctx, liveFilter(bson.M{"user_type": role, "deactivated_at": nil}, false))
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user models.User) error {
+	_, err := r.collection.InsertOne(ctx, user)
+	return mongoError(err)
+}
+
+func (r *mongoUserRepository) Update(ctx context.Context, userId string, updateObj primitive.D, version int) error {
+	return updateVersioned(ctx, r.collection, "user_id", userId, updateObj, version)
+}
+
+func (r *mongoUserRepository) Delete(ctx context.Context, userId string, d Deletion) error {
+	return softDelete(ctx, r.collection, bson.M{"user_id": userId}, d, true)
+}
+
+func (r *mongoUserRepository) Restore(ctx context.Context, userId string) error {
+	return restore(ctx, r.collection, bson.M{"user_id": userId}, true)
+}
+
+type memoryUserRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, includeDeleted bool, page Page) (Paged[models.User], error) {
+	users, err := r.db.users.find(nil, includeDeleted)
+	if err != nil {
+		return Paged[models.User]{}, err
+	}
+	return pageOf(users, oldestFirst("user_id"), page, nil)
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, userId string) (models.User, error) {
+	return r.db.users.get(userId, false)
+}
+
+func (r *memoryUserRepository) FindByIDWithDeleted(ctx context.Context, userId string) (models.User, error) {
+	return r.db.users.get(userId, true)
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	users, err := r.db.users.find(func(user models.User) bool {
+		return user.Email != nil && *user.Email == email
+	}, false)
+	if err != nil {
+		return models.User{}, err
+	}
+	if len(users) == 0 {
+		return models.User{}, ErrNotFound
+	}
+	return users[0], nil
+}
+
+func (r *memoryUserRepository) CountByEmail(ctx context.Context, email string) (int64, error) {
+	users, err := r.db.users.find(func(user models.User) bool {
+		return user.Email != nil && *user.Email == email
+	}, true)
+	return int64(len(users)), err
+}
+
+func (r *memoryUserRepository) CountByPhone(ctx context.Context, phone string) (int64, error) {
+	users, err := r.db.users.find(func(user models.User) bool {
+		return user.Phone != nil && *user.Phone == phone
+	}, true)
+	return int64(len(users)), err
+}
+
+func (r *memoryUserRepository) CountActiveByRole(ctx context.Context, role string) (int64, error) {
+	users, err := r.db.users.find(func(user models.User) bool {
+		return user.User_type != nil && *user.User_type == role && user.Deactivated_at == nil
+	}, false)
+	return int64(len(users)), err
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user models.User) error {
+	return r.db.users.insert(user)
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, userId string, updateObj primitive.D, version int) error {
+	return r.db.users.update(userId, updateObj, version)
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, userId string, d Deletion) error {
+	return r.db.users.markDeleted(userId, d)
+}
+
+func (r *memoryUserRepository) Restore(ctx context.Context, userId string) error {
+	return r.db.users.unmarkDeleted(userId)
+}
@@ -0,0 +1,42 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestActiveUsersAreCountedByRole(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		admin, waiter := models.RoleAdmin, models.RoleWaiter
+		for _, user := range []models.User{
+			{User_id: "admin", User_type: &admin, Version: 1},
+			{User_id: "deactivated", User_type: &admin, Version: 1},
+			{User_id: "deleted", User_type: &admin, Version: 1},
+			{User_id: "waiter", User_type: &waiter, Version: 1},
+		} {
+			if err := s.Users.Create(ctx, user); err != nil {
+				t.Fatalf("creating the user %s: %v", user.User_id, err)
+			}
+		}
+		if err := s.Users.Update(ctx, "deactivated", primitive.D{{Key: "deactivated_at", Value: time.Now()}}, 0); err != nil {
+			t.Fatalf("deactivating a user: %v", err)
+		}
+		if err := s.Users.Delete(ctx, "deleted", store.Deletion{At: time.Now(), By: "admin"}); err != nil {
+			t.Fatalf("deleting a user: %v", err)
+		}
+
+		if admins, err := s.Users.CountActiveByRole(ctx, models.RoleAdmin); err != nil || admins != 1 {
+			t.Fatalf("counted %d active admins, %v, want 1", admins, err)
+		}
+		if waiters, err := s.Users.CountActiveByRole(ctx, models.RoleWaiter); err != nil || waiters != 1 {
+			t.Fatalf("counted %d active waiters, %v, want 1", waiters, err)
+		}
+	})
+}
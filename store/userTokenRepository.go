@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"restaurant-management-system/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UserTokenRepository keeps the password reset and email verification tokens.
+type UserTokenRepository interface {
+	Create(ctx context.Context, token models.UserToken) error
+	// FindByHash returns the token with tokenHash whatever its state; the caller checks expiry and use.
+	FindByHash(ctx context.Context, tokenHash string) (models.UserToken, error)
+	// Use marks the token used at at. It returns ErrConflict when the token was already used, so of two requests
+	// racing with the same token only one succeeds.
+	Use(ctx context.Context, userTokenId string, at time.Time) error
+	// UseAll marks every unused token of the user for purpose as used, so that only a token issued afterwards works.
+	UseAll(ctx context.Context, userId string, purpose string, at time.Time) error
+}
+
+type mongoUserTokenRepository struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoUserTokenRepository) Create(ctx context.Context, token models.UserToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return mongoError(err)
+}
+
+func (r *mongoUserTokenRepository) FindByHash(ctx context.Context, tokenHash string) (models.UserToken, error) {
+	var token models.UserToken
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return token, ErrNotFound
+	}
+	return token, err
+}
+
+func (r *mongoUserTokenRepository) Use(ctx context.Context, userTokenId string, at time.Time) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"user_token_id": userTokenId, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": at}},
+	)
+	if err != nil {
+		return mongoError(err)
+	}
+	if result.MatchedCount == 0 {
+		count, err := r.collection.CountDocuments(ctx, bson.M{"user_token_id": userTokenId})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r *mongoUserTokenRepository) UseAll(ctx context.Context, userId string, purpose string, at time.Time) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"user_id": userId, "purpose": purpose, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": at}},
+	)
+	return mongoError(err)
+}
+
+type memoryUserTokenRepository struct {
+	db *memoryDatabase
+}
+
+func (r *memoryUserTokenRepository) Create(ctx context.Context, token models.UserToken) error {
+	return r.db.userTokens.insert(token)
+}
+
+func (r *memoryUserTokenRepository) FindByHash(ctx context.Context, tokenHash string) (models.UserToken, error) {
+	tokens, err := r.db.userTokens.find(func(token models.UserToken) bool {
+		return token.Token_hash == tokenHash
+	}, false)
+	if err != nil {
+		return models.UserToken{}, err
+	}
+	if len(tokens) == 0 {
+		return models.UserToken{}, ErrNotFound
+	}
+	return tokens[0], nil
+}
+
+// The handlers use tokens inside a transaction, which holds every lock of the in-memory store, so reading and
+// then marking the token cannot interleave with another request.
+func (r *memoryUserTokenRepository) Use(ctx context.Context, userTokenId string, at time.Time) error {
+	token, err := r.db.userTokens.get(userTokenId, false)
+	if err != nil {
+		return err
+	}
+	if token.Used_at != nil {
+		return ErrConflict
+	}
+	return r.db.userTokens.update(userTokenId, primitive.D{{Key: "used_at", Value: at}}, 0)
+}
+
+func (r *memoryUserTokenRepository) UseAll(ctx context.Context, userId string, purpose string, at time.Time) error {
+	tokens, err := r.db.userTokens.find(func(token models.UserToken) bool {
+		return token.User_id == userId && token.Purpose == purpose && token.Used_at == nil
+	}, false)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := r.Use(ctx, token.User_token_id, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
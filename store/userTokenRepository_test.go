@@ -0,0 +1,48 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"restaurant-management-system/models"
+	"restaurant-management-system/store"
+)
+
+func TestUserTokensAreFoundByHashAndUsedOnce(t *testing.T) {
+	eachStore(t, func(t *testing.T, s *store.Store) {
+		ctx := context.Background()
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		token := models.UserToken{
+			User_token_id: "token",
+			Purpose:       models.PurposePasswordReset,
+			User_id:       "user",
+			Email:         "user@example.com",
+			Token_hash:    "hash",
+			Expires_at:    now.Add(time.Hour),
+			Created_at:    now,
+		}
+		if err := s.UserTokens.Create(ctx, token); err != nil {
+			t.Fatalf("creating the token: %v", err)
+		}
+
+		if _, err := s.UserTokens.FindByHash(ctx, "unknown"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("finding an unknown hash returned %v, want ErrNotFound", err)
+		}
+		found, err := s.UserTokens.FindByHash(ctx, "hash")
+		if err != nil || found.User_token_id != token.User_token_id {
+			t.Fatalf("finding the token returned %+v, %v", found, err)
+		}
+
+		if err := s.UserTokens.Use(ctx, "token", now); err != nil {
+			t.Fatalf("using the token: %v", err)
+		}
+		if err := s.UserTokens.Use(ctx, "token", now); !errors.Is(err, store.ErrConflict) {
+			t.Fatalf("using the token again returned %v, want ErrConflict", err)
+		}
+		if err := s.UserTokens.Use(ctx, "unknown", now); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("using an unknown token returned %v, want ErrNotFound", err)
+		}
+	})
+}